@@ -1,11 +1,19 @@
 module github.com/lightsgoout/fintech-go
 
-go 1.15
+go 1.16
 
 require (
 	github.com/go-kit/kit v0.10.0
-	github.com/go-pg/pg/v10 v10.5.1
+	github.com/go-pg/pg/v10 v10.10.6
 	github.com/gorilla/mux v1.7.3
+	github.com/prometheus/client_golang v1.11.1
 	github.com/shopspring/decimal v1.2.0
-	github.com/stretchr/testify v1.6.1
+	github.com/stretchr/testify v1.7.0
+	go.opentelemetry.io/otel v1.3.0
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.3.0
+	go.opentelemetry.io/otel/sdk v1.3.0
+	go.opentelemetry.io/otel/trace v1.3.0
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013
+	google.golang.org/grpc v1.43.0
+	google.golang.org/protobuf v1.27.1
 )
@@ -1,29 +1,515 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	kitlog "github.com/go-kit/kit/log"
 	"github.com/lightsgoout/fintech-go/payments/api"
+	grpcapi "github.com/lightsgoout/fintech-go/payments/api/grpc"
+	"github.com/lightsgoout/fintech-go/payments/api/grpc/pb"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/migrations"
+	"github.com/lightsgoout/fintech-go/payments/outbox"
+	"github.com/lightsgoout/fintech-go/payments/scheduler"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/payments/service/audit"
+	"github.com/lightsgoout/fintech-go/payments/service/instrumenting"
+	"github.com/lightsgoout/fintech-go/payments/service/logging"
+	"github.com/lightsgoout/fintech-go/payments/service/memory"
 	"github.com/lightsgoout/fintech-go/payments/service/persistent"
+	"github.com/lightsgoout/fintech-go/payments/service/tracing"
+	"github.com/lightsgoout/fintech-go/payments/webhook"
+	"github.com/lightsgoout/fintech-go/pkg/fee"
+	"github.com/lightsgoout/fintech-go/pkg/fx"
+	"github.com/lightsgoout/fintech-go/pkg/money"
 	"github.com/lightsgoout/fintech-go/pkg/postgres"
+	"github.com/lightsgoout/fintech-go/pkg/postgres/migrate"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run runs the service or a command, returns process exit code.
+// Everything is cleaned up by deferred calls, which os.Exit would skip.
+func run() int {
 	var (
-		listen = flag.String("listen", ":8080", "HTTP listen address")
+		listen               = flag.String("listen", ":8080", "HTTP listen address")
+		grpcListen           = flag.String("grpc-listen", "", "gRPC listen address (gRPC API is disabled if not set)")
+		adminListen          = flag.String("admin-listen", "", "Admin HTTP listen address serving /metrics and admin API, which must not be exposed publicly (disabled if not set)")
+		currenciesPath       = flag.String("currencies", "", "Path to JSON file with supported currencies (USD, EUR and RUB if not set)")
+		fxRatesPath          = flag.String("fx-rates", "", "Path to JSON file with exchange rates (cross-currency transfers are disabled if not set)")
+		quoteTTL             = flag.Duration("quote-ttl", persistent.DefaultQuoteTTL, "How long quoted exchange rates stay locked")
+		idempotencyRetention = flag.Duration("idempotency-retention", persistent.DefaultIdempotencyRetention, "How long transfer idempotency keys are remembered")
+		holdTTL              = flag.Duration("hold-ttl", persistent.DefaultHoldTTL, "How long holds reserve money unless captured or voided")
+		feesPath             = flag.String("fees", "", "Path to JSON file with transfer fee rules (transfers are free if not set)")
+		feeAccounts          = flag.String("fee-accounts", "", "Accounts receiving fees by currency, e.g. USD=revenue_usd,EUR=revenue_eur")
+		eventsOut            = flag.String("events-out", "", "File to append published events to, - for stdout (events aren't published if not set)")
+		webhooks             = flag.Bool("webhooks", false, "Deliver events to webhooks")
+		eventsInterval       = flag.Duration("events-interval", outbox.DefaultInterval, "How often new events are published")
+		traceOut             = flag.String("trace-out", "", "File to append spans to, - for stdout (requests aren't traced if not set)")
+		drainTimeout         = flag.Duration("drain-timeout", 30*time.Second, "How long in-flight requests and background work may take to finish on shutdown")
+		schedulerInterval    = flag.Duration("scheduler-interval", scheduler.DefaultInterval, "How often due scheduled transfers are made (they aren't made by this instance if 0)")
+		autoMigrate          = flag.Bool("auto-migrate", false, "Apply pending schema migrations on startup")
+		inMemory             = flag.Bool("in-memory", false, "Keep all data in memory instead of Postgres (for local development)")
 	)
 	flag.Parse()
 
-	pg := postgres.NewPostgresFromEnv()
-	svc := persistent.NewPaymentsService(pg)
+	logger := kitlog.NewJSONLogger(kitlog.NewSyncWriter(os.Stderr))
+	logger = kitlog.With(logger, "ts", kitlog.DefaultTimestampUTC)
+
+	// ctx is cancelled on SIGTERM or SIGINT, which starts the shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	currencies := money.DefaultCurrencyRegistry
+	if *currenciesPath != "" {
+		var err error
+		currencies, err = money.LoadCurrencyRegistry(*currenciesPath)
+		if err != nil {
+			log.Print(fmt.Errorf("failed to load currencies: %w", err))
+			return 1
+		}
+	}
+
+	var rates fx.RateProvider
+	if *fxRatesPath != "" {
+		var err error
+		rates, err = fx.LoadStaticRateProvider(*fxRatesPath)
+		if err != nil {
+			log.Print(fmt.Errorf("failed to load exchange rates: %w", err))
+			return 1
+		}
+	}
 
-	srv := http.Server{
+	var fees service.Fees
+	if *feesPath != "" {
+		var err error
+		fees.Schedule, err = fee.LoadStaticSchedule(*feesPath)
+		if err != nil {
+			log.Print(fmt.Errorf("failed to load fee rules: %w", err))
+			return 1
+		}
+		fees.RevenueAccounts, err = parseFeeAccounts(*feeAccounts)
+		if err != nil {
+			log.Print(fmt.Errorf("failed to parse fee accounts: %w", err))
+			return 1
+		}
+	}
+
+	var tp trace.TracerProvider = trace.NewNoopTracerProvider()
+	if *traceOut != "" {
+		sdkTP, err := newTracerProvider(*traceOut)
+		if err != nil {
+			log.Print(fmt.Errorf("failed to open trace output: %w", err))
+			return 1
+		}
+		defer func() {
+			if err := sdkTP.Shutdown(context.Background()); err != nil {
+				log.Print(fmt.Errorf("failed to flush spans: %w", err))
+			}
+		}()
+		tp = sdkTP
+	}
+
+	var (
+		svc     service.PaymentsService
+		apiOpts []api.Option
+	)
+	if *inMemory {
+		if flag.Arg(0) != "" {
+			log.Printf("command %q is not available with -in-memory", flag.Arg(0))
+			return 1
+		}
+		svc = memory.NewPaymentsService(
+			memory.WithCurrencyRegistry(currencies),
+			memory.WithIdempotencyRetention(*idempotencyRetention),
+			memory.WithQuoteTTL(*quoteTTL),
+			memory.WithHoldTTL(*holdTTL),
+			memory.WithRateProvider(rates),
+			memory.WithFees(fees),
+		)
+	} else {
+		pg := postgres.NewPostgresFromEnv()
+		defer func() {
+			if err := pg.Close(); err != nil {
+				log.Print(fmt.Errorf("failed to close database: %w", err))
+			}
+		}()
+		if *traceOut != "" {
+			pg.AddQueryHook(postgres.NewTracingHook(tp))
+		}
+		if flag.Arg(0) == "migrate" {
+			return migrateSchema(context.Background(), pg, flag.Arg(1), flag.Arg(2))
+		}
+		if *autoMigrate {
+			if exitCode := migrateSchema(context.Background(), pg, "up", ""); exitCode != 0 {
+				return exitCode
+			}
+		}
+
+		persistentSvc := persistent.NewPaymentsService(pg,
+			persistent.WithCurrencyRegistry(currencies),
+			persistent.WithIdempotencyRetention(*idempotencyRetention),
+			persistent.WithQuoteTTL(*quoteTTL),
+			persistent.WithHoldTTL(*holdTTL),
+			persistent.WithRateProvider(rates),
+			persistent.WithFees(fees),
+			persistent.WithTracerProvider(tp),
+		)
+		if err := persistentSvc.SyncCurrencies(context.Background()); err != nil {
+			log.Print(fmt.Errorf("failed to sync currencies: %w", err))
+			return 1
+		}
+		if *adminListen != "" {
+			prometheus.MustRegister(postgres.NewPoolStatsCollector(pg, "payments"))
+		}
+		migrator, err := migrate.NewMigrator(pg, migrations.FS)
+		if err != nil {
+			log.Print(fmt.Errorf("failed to load migrations: %w", err))
+			return 1
+		}
+		apiOpts = append(apiOpts,
+			api.WithReadinessCheck("postgres", pg.Ping),
+			api.WithReadinessCheck("migrations", migrationsApplied(migrator)),
+		)
+
+		switch flag.Arg(0) {
+		case "":
+			// Serve API, see below
+		case "verify-balances":
+			return verifyBalances(context.Background(), persistentSvc)
+		default:
+			log.Printf("unknown command %q", flag.Arg(0))
+			return 1
+		}
+		svc = persistentSvc
+	}
+
+	// serveErr receives the first error of any server, which stops the service
+	serveErr := make(chan error, 3)
+
+	svc = audit.NewPaymentsService(svc, logger)
+	apiOpts = append(apiOpts, api.WithLogger(logger))
+	if *adminListen != "" {
+		svc = instrumenting.NewPaymentsService(svc, instrumenting.NewPrometheusMetrics(prometheus.DefaultRegisterer))
+		apiOpts = append(apiOpts, api.WithMetrics(api.NewPrometheusHTTPMetrics(prometheus.DefaultRegisterer)))
+	}
+	if *traceOut != "" {
+		svc = tracing.NewPaymentsService(svc, tp)
+		apiOpts = append(apiOpts, api.WithTracing(tp))
+	}
+	svc = logging.NewPaymentsService(svc, logger)
+
+	var adminSrv *http.Server
+	if *adminListen != "" {
+		adminSrv = serveAdmin(*adminListen, api.NewAdminServer(svc, apiOpts...), serveErr)
+	}
+
+	var grpcSrv *grpc.Server
+	if *grpcListen != "" {
+		var err error
+		grpcSrv, err = serveGRPC(*grpcListen, svc, serveErr)
+		if err != nil {
+			log.Print(fmt.Errorf("failed to listen gRPC: %w", err))
+			return 1
+		}
+	}
+
+	// Workers stop once ctx is cancelled. Work they are doing at that moment is rolled back and made again later,
+	// since every unit of it is a transaction.
+	var workers sync.WaitGroup
+	runWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(ctx)
+		}()
+	}
+
+	var publishers outbox.MultiPublisher
+	if *eventsOut != "" {
+		publisher, err := openEventsPublisher(*eventsOut)
+		if err != nil {
+			log.Print(fmt.Errorf("failed to open events output: %w", err))
+			return 1
+		}
+		publishers = append(publishers, publisher)
+	}
+	if *webhooks {
+		publishers = append(publishers, webhook.NewPublisher(svc))
+		runWorker(webhook.NewSender(svc).Run)
+	}
+	if len(publishers) > 0 {
+		runWorker(outbox.NewRelay(svc, publishers, outbox.WithInterval(*eventsInterval)).Run)
+	}
+
+	if *schedulerInterval > 0 {
+		runWorker(scheduler.NewScheduler(svc, scheduler.WithInterval(*schedulerInterval)).Run)
+	}
+
+	srv := &http.Server{
 		Addr:    *listen,
-		Handler: api.NewAPIServer(svc),
+		Handler: api.NewAPIServer(svc, apiOpts...),
+	}
+	go func() {
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			serveErr <- fmt.Errorf("failed to listen and serve: %w", err)
+		}
+	}()
+	exitCode := 0
+	select {
+	case err := <-serveErr:
+		log.Print(err)
+		exitCode = 1
+		stop()
+	case <-ctx.Done():
+		_ = logger.Log("msg", "shutting down", "drain_timeout", *drainTimeout)
+	}
+
+	// In-flight requests aren't cancelled, they have until the drain timeout to finish.
+	// Metrics are served until the very end, so the shutdown can be watched.
+	drainCtx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+	defer cancel()
+	if err := srv.Shutdown(drainCtx); err != nil {
+		log.Print(fmt.Errorf("failed to drain HTTP requests: %w", err))
+	}
+	if grpcSrv != nil {
+		stopGRPC(drainCtx, grpcSrv)
 	}
-	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
-		log.Print(fmt.Errorf("failed to listen and serve: %w", err))
+	if err := waitGroup(drainCtx, &workers); err != nil {
+		log.Print(fmt.Errorf("failed to wait for background workers: %w", err))
+	}
+	if adminSrv != nil {
+		_ = adminSrv.Shutdown(drainCtx)
+	}
+	_ = logger.Log("msg", "stopped")
+	return exitCode
+}
+
+// serveGRPC serves gRPC API until the returned server is stopped, sending a failure to serve to errs.
+func serveGRPC(addr string, svc service.PaymentsService, errs chan<- error) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv := grpc.NewServer()
+	pb.RegisterPaymentsServer(srv, grpcapi.NewServer(svc))
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			errs <- fmt.Errorf("failed to serve gRPC: %w", err)
+		}
+	}()
+	return srv, nil
+}
+
+// stopGRPC stops srv gracefully, or abruptly if in-flight calls don't finish before ctx is done.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
+// waitGroup waits for wg unless ctx is done first.
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// serveAdmin serves endpoints for operators, metrics and admin API, which must not be exposed publicly,
+// until the returned server is shut down. A failure to serve is sent to errs.
+func serveAdmin(addr string, adminAPI http.Handler, errs chan<- error) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/admin/", adminAPI)
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			errs <- fmt.Errorf("failed to serve admin endpoints: %w", err)
+		}
+	}()
+	return srv
+}
+
+// migrationsApplied is a readiness check failing while schema migrations are pending,
+// e.g. until another instance applies them.
+func migrationsApplied(migrator *migrate.Migrator) api.ReadinessCheck {
+	return func(ctx context.Context) error {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d migrations pending", len(pending))
+		}
+		return nil
+	}
+}
+
+// newTracerProvider returns a provider exporting spans as JSON to stdout if path is -, or appending them to the file otherwise.
+func newTracerProvider(path string) (*sdktrace.TracerProvider, error) {
+	out := os.Stdout
+	if path != "-" {
+		var err error
+		out, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(out))
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "fintech-go"))),
+	), nil
+}
+
+// openEventsPublisher returns a publisher writing events to stdout if path is -, or appending them to the file otherwise.
+func openEventsPublisher(path string) (outbox.Publisher, error) {
+	if path == "-" {
+		return outbox.NewWriterPublisher(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return outbox.NewWriterPublisher(f), nil
+}
+
+// parseFeeAccounts parses a comma-separated list of CUR=account pairs.
+func parseFeeAccounts(raw string) (map[money.Currency]entity.AccountID, error) {
+	accounts := make(map[money.Currency]entity.AccountID)
+	if raw == "" {
+		return accounts, nil
+	}
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("bad pair %q, expected CUR=account", pair)
+		}
+		accounts[money.NewCurrency(parts[0])] = entity.AccountID(parts[1])
+	}
+	return accounts, nil
+}
+
+// verifyBalances reports accounts whose balances can't be proven by the journal, returns process exit code.
+func verifyBalances(ctx context.Context, svc persistent.PaymentsService) int {
+	discrepancies, err := svc.VerifyBalances(ctx)
+	if err != nil {
+		log.Print(fmt.Errorf("failed to verify balances: %w", err))
+		return 2
+	}
+	for _, d := range discrepancies {
+		fmt.Printf("%s: balance %s %s, journal %s %s\n", d.Account, d.Balance, d.Currency, d.JournalBalance, d.Currency)
+	}
+	if len(discrepancies) > 0 {
+		return 1
+	}
+	fmt.Println("all balances match the journal")
+	return 0
+}
+
+// migrateSchema runs a migrate subcommand (up, down, status or baseline with version argument),
+// returns process exit code.
+func migrateSchema(ctx context.Context, db postgres.Database, command, arg string) int {
+	migrator, err := migrate.NewMigrator(db, migrations.FS)
+	if err != nil {
+		log.Print(fmt.Errorf("failed to load migrations: %w", err))
+		return 2
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			log.Printf("applied migration %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Print(fmt.Errorf("failed to apply migrations: %w", err))
+			return 1
+		}
+	case "down":
+		reverted, found, err := migrator.Down(ctx)
+		if err != nil {
+			log.Print(fmt.Errorf("failed to revert migration: %w", err))
+			return 1
+		}
+		if found {
+			log.Printf("reverted migration %d_%s", reverted.Version, reverted.Name)
+		} else {
+			log.Print("no migrations to revert")
+		}
+	case "baseline":
+		version, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			log.Printf("bad baseline version %q, expected a number, e.g. 1", arg)
+			return 2
+		}
+		recorded, err := migrator.Baseline(ctx, version)
+		for _, m := range recorded {
+			log.Printf("recorded migration %d_%s as applied", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Print(fmt.Errorf("failed to baseline migrations: %w", err))
+			return 1
+		}
+	case "status":
+		applied, err := migrator.Applied(ctx)
+		if err != nil {
+			log.Print(fmt.Errorf("failed to get applied migrations: %w", err))
+			return 1
+		}
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			log.Print(fmt.Errorf("failed to get pending migrations: %w", err))
+			return 1
+		}
+		for _, m := range applied {
+			fmt.Printf("%04d_%s applied at %s\n", m.Version, m.Name, m.AppliedAt.Format(time.RFC3339))
+		}
+		for _, m := range pending {
+			fmt.Printf("%04d_%s pending\n", m.Version, m.Name)
+		}
+	default:
+		log.Printf("unknown migrate command %q, expected up, down, status or baseline", command)
+		return 2
 	}
+	return 0
 }
@@ -0,0 +1,62 @@
+package authorize_hold
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"net/http"
+	"time"
+)
+
+type authorizeHoldRequest struct {
+	From     entity.AccountID `json:"from"`
+	To       entity.AccountID `json:"to"`
+	Amount   money.Numeric    `json:"amount"`
+	Currency string           `json:"currency"`
+}
+
+type authorizeHoldResponse struct {
+	HoldId    entity.HoldID `json:"hold_id"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+func authorizeHoldEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(authorizeHoldRequest)
+		hold, err := svc.Authorize(
+			ctx,
+			req.From,
+			req.To,
+			req.Amount,
+			money.NewCurrency(req.Currency),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return authorizeHoldResponse{
+			HoldId:    hold.Id,
+			ExpiresAt: hold.ExpiresAt,
+		}, nil
+	}
+}
+
+func decodeAuthorizeHoldRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request authorizeHoldRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		authorizeHoldEndpoint(svc),
+		decodeAuthorizeHoldRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
@@ -0,0 +1,51 @@
+package cancel_scheduled_transfer
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"net/http"
+)
+
+type cancelScheduledTransferRequest struct {
+	Id entity.ScheduledTransferID `json:"id"`
+}
+
+type cancelScheduledTransferResponse struct {
+	Id     entity.ScheduledTransferID `json:"id"`
+	Status entity.ScheduleStatus      `json:"status"`
+}
+
+func cancelScheduledTransferEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(cancelScheduledTransferRequest)
+		st, err := svc.CancelScheduledTransfer(ctx, req.Id)
+		if err != nil {
+			return nil, err
+		}
+		return cancelScheduledTransferResponse{
+			Id:     st.Id,
+			Status: st.Status,
+		}, nil
+	}
+}
+
+func decodeCancelScheduledTransferRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request cancelScheduledTransferRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		cancelScheduledTransferEndpoint(svc),
+		decodeCancelScheduledTransferRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
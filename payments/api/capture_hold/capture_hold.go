@@ -0,0 +1,51 @@
+package capture_hold
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"net/http"
+)
+
+type captureHoldRequest struct {
+	HoldId entity.HoldID `json:"hold_id"`
+
+	// Amount is a part of held money to capture, all of it if omitted
+	Amount *money.Numeric `json:"amount"`
+}
+
+type captureHoldResponse struct {
+	PaymentId entity.PaymentID `json:"payment_id"`
+}
+
+func captureHoldEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(captureHoldRequest)
+		paymentId, err := svc.Capture(ctx, req.HoldId, req.Amount)
+		if err != nil {
+			return nil, err
+		}
+		return captureHoldResponse{paymentId}, nil
+	}
+}
+
+func decodeCaptureHoldRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request captureHoldRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		captureHoldEndpoint(svc),
+		decodeCaptureHoldRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
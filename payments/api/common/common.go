@@ -3,9 +3,124 @@ package common
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lightsgoout/fintech-go/payments/service"
 	"net/http"
 )
 
+// ErrBadRequest is returned when a request can't be decoded.
+var ErrBadRequest = errors.New("bad request")
+
 func EncodeResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	return json.NewEncoder(w).Encode(response)
 }
+
+// DecodeJSON decodes JSON request body into v, failing with ErrBadRequest on malformed input.
+func DecodeJSON(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("%w: %s", ErrBadRequest, err)
+	}
+	return nil
+}
+
+// Error is a machine-readable description of a failed request.
+type Error struct {
+	// Code is a stable identifier of the error clients can rely on, e.g. insufficient_funds
+	Code string `json:"code"`
+
+	// Message is a human-readable description of the error
+	Message string `json:"message"`
+
+	// Details are machine-readable specifics of some errors, e.g. which limit was exceeded
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// detailedError is implemented by errors carrying Error.Details.
+type detailedError interface {
+	ErrorDetails() map[string]string
+}
+
+type errorResponse struct {
+	Error Error `json:"error"`
+}
+
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// errorMappings lists known errors, anything else is reported as internal error.
+var errorMappings = []errorMapping{
+	{ErrBadRequest, http.StatusBadRequest, "bad_request"},
+	{service.ErrAccountDoesNotExist, http.StatusNotFound, "account_not_found"},
+	{service.ErrQuoteNotFound, http.StatusNotFound, "quote_not_found"},
+	{service.ErrHoldNotFound, http.StatusNotFound, "hold_not_found"},
+	{service.ErrPaymentNotFound, http.StatusNotFound, "payment_not_found"},
+	{service.ErrWebhookNotFound, http.StatusNotFound, "webhook_not_found"},
+	{service.ErrDeliveryNotFound, http.StatusNotFound, "delivery_not_found"},
+	{service.ErrScheduleNotFound, http.StatusNotFound, "schedule_not_found"},
+	{service.ErrInsufficientFunds, http.StatusConflict, "insufficient_funds"},
+	{service.ErrAccountAlreadyExists, http.StatusConflict, "account_already_exists"},
+	{service.ErrIdempotencyKeyReused, http.StatusConflict, "idempotency_key_reused"},
+	{service.ErrQuoteExpired, http.StatusConflict, "quote_expired"},
+	{service.ErrHoldExpired, http.StatusConflict, "hold_expired"},
+	{service.ErrHoldNotActive, http.StatusConflict, "hold_not_active"},
+	{service.ErrRefundExceedsPayment, http.StatusConflict, "refund_exceeds_payment"},
+	{service.ErrAccountFrozen, http.StatusConflict, "account_frozen"},
+	{service.ErrAccountClosed, http.StatusConflict, "account_closed"},
+	{service.ErrAccountNotEmpty, http.StatusConflict, "account_not_empty"},
+	{service.ErrLimitExceeded, http.StatusConflict, "limit_exceeded"},
+	{service.ErrCreditLimitTooLow, http.StatusConflict, "credit_limit_too_low"},
+	{service.ErrScheduleNotActive, http.StatusConflict, "schedule_not_active"},
+	{service.ErrIncompatibleCurrency, http.StatusUnprocessableEntity, "incompatible_currency"},
+	{service.ErrBadAccountID, http.StatusUnprocessableEntity, "bad_account_id"},
+	{service.ErrBadTransferTarget, http.StatusUnprocessableEntity, "bad_transfer_target"},
+	{service.ErrInvalidAmount, http.StatusUnprocessableEntity, "invalid_amount"},
+	{service.ErrCaptureExceedsHold, http.StatusUnprocessableEntity, "capture_exceeds_hold"},
+	{service.ErrNotRefundable, http.StatusUnprocessableEntity, "not_refundable"},
+	{service.ErrRateUnavailable, http.StatusUnprocessableEntity, "rate_unavailable"},
+	{service.ErrBadQuery, http.StatusUnprocessableEntity, "bad_query"},
+	{service.ErrBadCursor, http.StatusUnprocessableEntity, "bad_cursor"},
+	{service.ErrBadBatch, http.StatusUnprocessableEntity, "bad_batch"},
+	{service.ErrBadAccountStatus, http.StatusUnprocessableEntity, "bad_account_status"},
+	{service.ErrReasonRequired, http.StatusUnprocessableEntity, "reason_required"},
+	{service.ErrBadLimits, http.StatusUnprocessableEntity, "bad_limits"},
+	{service.ErrBadCreditLimit, http.StatusUnprocessableEntity, "bad_credit_limit"},
+	{service.ErrBadPeriod, http.StatusUnprocessableEntity, "bad_period"},
+	{service.ErrBadWebhook, http.StatusUnprocessableEntity, "bad_webhook"},
+	{service.ErrBadSchedule, http.StatusUnprocessableEntity, "bad_schedule"},
+}
+
+// DescribeError returns HTTP status and machine-readable description of err.
+// Details of internal errors are never exposed to clients.
+func DescribeError(err error) (int, Error) {
+	for _, m := range errorMappings {
+		if errors.Is(err, m.err) {
+			body := Error{
+				Code:    m.code,
+				Message: err.Error(),
+			}
+			var detailed detailedError
+			if errors.As(err, &detailed) {
+				body.Details = detailed.ErrorDetails()
+			}
+			return m.status, body
+		}
+	}
+	return http.StatusInternalServerError, Error{
+		Code:    "internal_error",
+		Message: "internal error",
+	}
+}
+
+// EncodeError is a go-kit error encoder writing errors as {"error":{"code":...,"message":...}}
+// with a matching HTTP status.
+func EncodeError(_ context.Context, err error, w http.ResponseWriter) {
+	status, body := DescribeError(err)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorResponse{body})
+}
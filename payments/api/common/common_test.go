@@ -0,0 +1,51 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEncodeError(t *testing.T) {
+	for _, testcase := range []struct {
+		err    error
+		status int
+		want   string
+	}{
+		{
+			err:    service.ErrInsufficientFunds,
+			status: http.StatusConflict,
+			want:   `{"error":{"code":"insufficient_funds","message":"insufficient funds"}}`,
+		},
+		{
+			err:    service.ErrAccountDoesNotExist,
+			status: http.StatusNotFound,
+			want:   `{"error":{"code":"account_not_found","message":"account does not exist"}}`,
+		},
+		{
+			err:    fmt.Errorf("wrapped: %w", service.ErrIncompatibleCurrency),
+			status: http.StatusUnprocessableEntity,
+			want:   `{"error":{"code":"incompatible_currency","message":"wrapped: incompatible currency"}}`,
+		},
+		{
+			err:    service.ErrAccountLimit{Account: "bob", Limit: "daily_amount", Max: "100"},
+			status: http.StatusConflict,
+			want:   `{"error":{"code":"limit_exceeded","message":"limit exceeded: daily_amount of bob is 100","details":{"account":"bob","limit":"daily_amount","max":"100"}}}`,
+		},
+		{
+			err:    service.NewErrInternal(fmt.Errorf("pq: relation \"account\" does not exist")),
+			status: http.StatusInternalServerError,
+			want:   `{"error":{"code":"internal_error","message":"internal error"}}`,
+		},
+	} {
+		w := httptest.NewRecorder()
+		EncodeError(context.Background(), testcase.err, w)
+		assert.Equal(t, testcase.status, w.Code)
+		assert.Equal(t, testcase.want, strings.TrimSpace(w.Body.String()))
+	}
+}
@@ -2,7 +2,6 @@ package create_account
 
 import (
 	"context"
-	"encoding/json"
 	"github.com/go-kit/kit/endpoint"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/lightsgoout/fintech-go/payments/api/common"
@@ -12,35 +11,34 @@ import (
 	"net/http"
 )
 
-type createAccountRequest struct {
+type CreateAccountRequest struct {
 	Id       entity.AccountID `json:"id"`
-	Balance  float32          `json:"balance"`
+	Balance  money.Numeric    `json:"balance"`
 	Currency string           `json:"currency"`
 }
 
-type createAccountResponse struct {
-	Err string `json:"err,omitempty"`
-}
+type CreateAccountResponse struct{}
 
-func createAccountEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+// CreateAccountEndpoint is served both over HTTP and gRPC, see payments/api/grpc.
+func CreateAccountEndpoint(svc service.PaymentsService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		req := request.(createAccountRequest)
+		req := request.(CreateAccountRequest)
 		err := svc.CreateAccount(
 			ctx,
 			req.Id,
-			money.NewNumericFromFloat32(req.Balance),
+			req.Balance,
 			money.NewCurrency(req.Currency),
 		)
 		if err != nil {
-			return createAccountResponse{err.Error()}, nil
+			return nil, err
 		}
-		return createAccountResponse{""}, nil
+		return CreateAccountResponse{}, nil
 	}
 }
 
 func decodeCreateAccountRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	var request createAccountRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+	var request CreateAccountRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
 		return nil, err
 	}
 	return request, nil
@@ -48,8 +46,9 @@ func decodeCreateAccountRequest(_ context.Context, r *http.Request) (interface{}
 
 func Server(svc service.PaymentsService) *httptransport.Server {
 	return httptransport.NewServer(
-		createAccountEndpoint(svc),
+		CreateAccountEndpoint(svc),
 		decodeCreateAccountRequest,
 		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
 	)
 }
@@ -0,0 +1,67 @@
+package create_webhook
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"net/http"
+	"time"
+)
+
+type createWebhookRequest struct {
+	URL        string             `json:"url"`
+	Secret     string             `json:"secret"`
+	AccountId  entity.AccountID   `json:"account_id"`
+	EventTypes []entity.EventType `json:"event_types"`
+}
+
+// createWebhookResponse never contains the secret.
+type createWebhookResponse struct {
+	Id         entity.WebhookID   `json:"id"`
+	URL        string             `json:"url"`
+	AccountId  entity.AccountID   `json:"account_id,omitempty"`
+	EventTypes []entity.EventType `json:"event_types,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+func createWebhookEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(createWebhookRequest)
+		webhook, err := svc.CreateWebhook(ctx, entity.Webhook{
+			URL:        req.URL,
+			Secret:     req.Secret,
+			Account:    req.AccountId,
+			EventTypes: req.EventTypes,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return createWebhookResponse{
+			Id:         webhook.Id,
+			URL:        webhook.URL,
+			AccountId:  webhook.Account,
+			EventTypes: webhook.EventTypes,
+			CreatedAt:  webhook.CreatedAt,
+		}, nil
+	}
+}
+
+func decodeCreateWebhookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request createWebhookRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		createWebhookEndpoint(svc),
+		decodeCreateWebhookRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
@@ -0,0 +1,44 @@
+package delete_webhook
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"net/http"
+)
+
+type deleteWebhookRequest struct {
+	Id entity.WebhookID `json:"id"`
+}
+
+type deleteWebhookResponse struct{}
+
+func deleteWebhookEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(deleteWebhookRequest)
+		if err := svc.DeleteWebhook(ctx, req.Id); err != nil {
+			return nil, err
+		}
+		return deleteWebhookResponse{}, nil
+	}
+}
+
+func decodeDeleteWebhookRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request deleteWebhookRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		deleteWebhookEndpoint(svc),
+		decodeDeleteWebhookRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
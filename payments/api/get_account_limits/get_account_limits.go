@@ -0,0 +1,59 @@
+package get_account_limits
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"net/http"
+)
+
+type getAccountLimitsRequest struct {
+	AccountId entity.AccountID `json:"account_id"`
+}
+
+// getAccountLimitsResponse omits limits which are not set.
+type getAccountLimitsResponse struct {
+	MaxAmount     *money.Numeric `json:"max_amount,omitempty"`
+	DailyAmount   *money.Numeric `json:"daily_amount,omitempty"`
+	MonthlyAmount *money.Numeric `json:"monthly_amount,omitempty"`
+	DailyCount    *int           `json:"daily_count,omitempty"`
+	MonthlyCount  *int           `json:"monthly_count,omitempty"`
+}
+
+func getAccountLimitsEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getAccountLimitsRequest)
+		limits, err := svc.GetAccountLimits(ctx, req.AccountId)
+		if err != nil {
+			return nil, err
+		}
+		return getAccountLimitsResponse{
+			MaxAmount:     limits.MaxAmount,
+			DailyAmount:   limits.DailyAmount,
+			MonthlyAmount: limits.MonthlyAmount,
+			DailyCount:    limits.DailyCount,
+			MonthlyCount:  limits.MonthlyCount,
+		}, nil
+	}
+}
+
+func decodeGetAccountLimitsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request getAccountLimitsRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		getAccountLimitsEndpoint(svc),
+		decodeGetAccountLimitsRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
@@ -2,7 +2,6 @@ package get_accounts
 
 import (
 	"context"
-	"encoding/json"
 	"github.com/go-kit/kit/endpoint"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/lightsgoout/fintech-go/payments/api/common"
@@ -12,32 +11,32 @@ import (
 	"net/http"
 )
 
-type getAccountsRequest struct {
+type GetAccountsRequest struct {
 	Currency string `json:"currency"`
 }
 
-type getAccountsResponse struct {
+type GetAccountsResponse struct {
 	Accounts []entity.AccountID `json:"accounts,omitempty"`
-	Err      string             `json:"err,omitempty"`
 }
 
-func getAccountsEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+// GetAccountsEndpoint is served both over HTTP and gRPC, see payments/api/grpc.
+func GetAccountsEndpoint(svc service.PaymentsService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		req := request.(getAccountsRequest)
+		req := request.(GetAccountsRequest)
 		accs, err := svc.GetAccounts(
 			ctx,
 			money.NewCurrency(req.Currency),
 		)
 		if err != nil {
-			return getAccountsResponse{nil, err.Error()}, nil
+			return nil, err
 		}
-		return getAccountsResponse{accs, ""}, nil
+		return GetAccountsResponse{accs}, nil
 	}
 }
 
 func decodeGetAccountsRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	var request getAccountsRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+	var request GetAccountsRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
 		return nil, err
 	}
 	return request, nil
@@ -45,8 +44,9 @@ func decodeGetAccountsRequest(_ context.Context, r *http.Request) (interface{},
 
 func Server(svc service.PaymentsService) *httptransport.Server {
 	return httptransport.NewServer(
-		getAccountsEndpoint(svc),
+		GetAccountsEndpoint(svc),
 		decodeGetAccountsRequest,
 		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
 	)
 }
@@ -0,0 +1,81 @@
+package get_audit_log
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"net/http"
+	"time"
+)
+
+type getAuditLogRequest struct {
+	Actor  string    `json:"claimed_actor"`
+	Method string    `json:"method"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+	Limit  int       `json:"limit"`
+}
+
+type outEntry struct {
+	Id        entity.AuditEntryID `json:"id"`
+	Time      time.Time           `json:"time"`
+	Actor     string              `json:"claimed_actor"`
+	RequestId string              `json:"request_id"`
+	Method    string              `json:"method"`
+	Params    json.RawMessage     `json:"params"`
+	Error     string              `json:"error,omitempty"`
+}
+
+type getAuditLogResponse struct {
+	Entries []outEntry `json:"entries"`
+}
+
+func getAuditLogEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getAuditLogRequest)
+		entries, err := svc.GetAuditLog(ctx, service.AuditQuery{
+			Actor:  req.Actor,
+			Method: req.Method,
+			Since:  req.Since,
+			Until:  req.Until,
+			Limit:  req.Limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		out := make([]outEntry, 0, len(entries))
+		for _, e := range entries {
+			out = append(out, outEntry{
+				Id:        e.Id,
+				Time:      e.Time,
+				Actor:     e.Actor,
+				RequestId: e.RequestId,
+				Method:    e.Method,
+				Params:    e.Params,
+				Error:     e.Error,
+			})
+		}
+		return getAuditLogResponse{out}, nil
+	}
+}
+
+func decodeGetAuditLogRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request getAuditLogRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		getAuditLogEndpoint(svc),
+		decodeGetAuditLogRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
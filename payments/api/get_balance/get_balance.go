@@ -0,0 +1,64 @@
+package get_balance
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"net/http"
+	"time"
+)
+
+type getBalanceRequest struct {
+	AccountId entity.AccountID `json:"account_id"`
+
+	// At is a moment of the balance, now if omitted
+	At time.Time `json:"at"`
+}
+
+type getBalanceResponse struct {
+	AccountId entity.AccountID `json:"account_id"`
+	At        time.Time        `json:"at"`
+	Balance   money.Numeric    `json:"balance"`
+	Currency  string           `json:"currency"`
+}
+
+func getBalanceEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getBalanceRequest)
+		at := req.At
+		if at.IsZero() {
+			at = time.Now().UTC()
+		}
+		balance, err := svc.GetBalance(ctx, req.AccountId, at)
+		if err != nil {
+			return nil, err
+		}
+		return getBalanceResponse{
+			AccountId: balance.Account,
+			At:        balance.At,
+			Balance:   balance.Amount,
+			Currency:  string(balance.Currency),
+		}, nil
+	}
+}
+
+func decodeGetBalanceRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request getBalanceRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		getBalanceEndpoint(svc),
+		decodeGetBalanceRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
@@ -2,18 +2,26 @@ package get_payments
 
 import (
 	"context"
-	"encoding/json"
 	"github.com/go-kit/kit/endpoint"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/lightsgoout/fintech-go/payments/api/common"
 	"github.com/lightsgoout/fintech-go/payments/entity"
 	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
 	"net/http"
 	"time"
 )
 
-type getPaymentsRequest struct {
-	AccountId entity.AccountID `json:"account_id"`
+type GetPaymentsRequest struct {
+	AccountId    entity.AccountID         `json:"account_id"`
+	Cursor       string                   `json:"cursor"`
+	Limit        int                      `json:"limit"`
+	Since        time.Time                `json:"since"`
+	Until        time.Time                `json:"until"`
+	Direction    service.PaymentDirection `json:"direction"`
+	Counterparty entity.AccountID         `json:"counterparty"`
+	MinAmount    *money.Numeric           `json:"min_amount"`
+	MaxAmount    *money.Numeric           `json:"max_amount"`
 }
 
 type outPayment struct {
@@ -21,46 +29,93 @@ type outPayment struct {
 	Time     time.Time        `json:"time"`
 	From     entity.AccountID `json:"from"`
 	To       entity.AccountID `json:"to"`
-	Amount   string           `json:"amount"`
+	Amount   money.Numeric    `json:"amount"`
 	Currency string           `json:"currency"`
 	Outgoing bool             `json:"outgoing"`
+	Exchange *outExchange     `json:"exchange,omitempty"`
+
+	// RefundOf is a payment this one refunds
+	RefundOf entity.PaymentID `json:"refund_of,omitempty"`
+
+	// RefundedBy are refunds of this payment
+	RefundedBy []entity.PaymentID `json:"refunded_by,omitempty"`
+
+	// Fee is paid by the sender on top of Amount to FeeAccount
+	Fee        *money.Numeric   `json:"fee,omitempty"`
+	FeeAccount entity.AccountID `json:"fee_account,omitempty"`
+}
+
+type outExchange struct {
+	QuoteId    entity.QuoteID `json:"quote_id"`
+	Rate       money.Numeric  `json:"rate"`
+	ToAmount   money.Numeric  `json:"to_amount"`
+	ToCurrency string         `json:"to_currency"`
 }
 
-type getPaymentsResponse struct {
-	Payments []outPayment `json:"payments,omitempty"`
-	Err      string       `json:"err,omitempty"`
+type GetPaymentsResponse struct {
+	Payments   []outPayment `json:"payments,omitempty"`
+	NextCursor string       `json:"next_cursor,omitempty"`
 }
 
-func getPaymentsEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+// GetPaymentsEndpoint is served both over HTTP and gRPC, see payments/api/grpc.
+func GetPaymentsEndpoint(svc service.PaymentsService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		req := request.(getPaymentsRequest)
-		payments, err := svc.GetPayments(
+		req := request.(GetPaymentsRequest)
+		page, err := svc.GetPayments(
 			ctx,
-			req.AccountId,
+			service.PaymentsQuery{
+				Account:      req.AccountId,
+				Cursor:       req.Cursor,
+				Limit:        req.Limit,
+				Since:        req.Since,
+				Until:        req.Until,
+				Direction:    req.Direction,
+				Counterparty: req.Counterparty,
+				MinAmount:    req.MinAmount,
+				MaxAmount:    req.MaxAmount,
+			},
 		)
 		if err != nil {
-			return getPaymentsResponse{nil, err.Error()}, nil
+			return nil, err
 		}
 
-		outPayments := make([]outPayment, 0, len(payments))
-		for _, p := range payments {
+		outPayments := make([]outPayment, 0, len(page.Payments))
+		for _, p := range page.Payments {
+			var exchange *outExchange
+			if p.Value.Exchange != nil {
+				exchange = &outExchange{
+					QuoteId:    p.Value.Exchange.Quote,
+					Rate:       p.Value.Exchange.Rate,
+					ToAmount:   p.Value.Exchange.ToAmount,
+					ToCurrency: string(p.Value.Exchange.ToCurrency),
+				}
+			}
+			var fee *money.Numeric
+			if p.Value.FeeAccount != "" {
+				fee = &p.Value.Fee
+			}
 			outPayments = append(outPayments, outPayment{
-				Id:       p.Id,
-				Time:     p.Value.Time,
-				From:     p.Value.From,
-				To:       p.Value.To,
-				Amount:   p.Value.Amount.String(),
-				Currency: string(p.Value.Currency),
-				Outgoing: p.Value.Outgoing,
+				Id:         p.Id,
+				Time:       p.Value.Time,
+				From:       p.Value.From,
+				To:         p.Value.To,
+				Amount:     p.Value.Amount,
+				Currency:   string(p.Value.Currency),
+				Outgoing:   p.Value.Outgoing,
+				Exchange:   exchange,
+				RefundOf:   p.Value.RefundOf,
+				RefundedBy: p.RefundedBy,
+				Fee:        fee,
+				FeeAccount: p.Value.FeeAccount,
 			})
 		}
-		return getPaymentsResponse{outPayments, ""}, nil
+		return GetPaymentsResponse{outPayments, page.NextCursor}, nil
 	}
 }
 
 func decodeGetPaymentsRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	var request getPaymentsRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+	var request GetPaymentsRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
 		return nil, err
 	}
 	return request, nil
@@ -68,8 +123,9 @@ func decodeGetPaymentsRequest(_ context.Context, r *http.Request) (interface{},
 
 func Server(svc service.PaymentsService) *httptransport.Server {
 	return httptransport.NewServer(
-		getPaymentsEndpoint(svc),
+		GetPaymentsEndpoint(svc),
 		decodeGetPaymentsRequest,
 		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
 	)
 }
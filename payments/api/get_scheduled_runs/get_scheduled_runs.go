@@ -0,0 +1,68 @@
+package get_scheduled_runs
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"net/http"
+	"time"
+)
+
+type getScheduledRunsRequest struct {
+	Id entity.ScheduledTransferID `json:"id"`
+}
+
+type outRun struct {
+	Id          entity.ScheduledRunID `json:"id"`
+	ScheduledAt time.Time             `json:"scheduled_at"`
+	RanAt       time.Time             `json:"ran_at"`
+	Status      entity.RunStatus      `json:"status"`
+	PaymentId   entity.PaymentID      `json:"payment_id,omitempty"`
+	Error       string                `json:"error,omitempty"`
+}
+
+type getScheduledRunsResponse struct {
+	Runs []outRun `json:"runs"`
+}
+
+func getScheduledRunsEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getScheduledRunsRequest)
+		runs, err := svc.GetScheduledRuns(ctx, req.Id)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]outRun, 0, len(runs))
+		for _, run := range runs {
+			out = append(out, outRun{
+				Id:          run.Id,
+				ScheduledAt: run.ScheduledAt,
+				RanAt:       run.RanAt,
+				Status:      run.Status,
+				PaymentId:   run.Payment,
+				Error:       run.Error,
+			})
+		}
+		return getScheduledRunsResponse{out}, nil
+	}
+}
+
+func decodeGetScheduledRunsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request getScheduledRunsRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		getScheduledRunsEndpoint(svc),
+		decodeGetScheduledRunsRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
@@ -0,0 +1,89 @@
+package get_scheduled_transfers
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"net/http"
+	"time"
+)
+
+type getScheduledTransfersRequest struct {
+	From entity.AccountID `json:"from"`
+}
+
+type outScheduledTransfer struct {
+	Id         entity.ScheduledTransferID `json:"id"`
+	From       entity.AccountID           `json:"from"`
+	To         entity.AccountID           `json:"to"`
+	Amount     money.Numeric              `json:"amount"`
+	Currency   money.Currency             `json:"currency"`
+	Recurrence entity.Recurrence          `json:"recurrence"`
+	DayOfMonth int                        `json:"day_of_month,omitempty"`
+	StartAt    time.Time                  `json:"start_at"`
+	EndAt      *time.Time                 `json:"end_at,omitempty"`
+	Status     entity.ScheduleStatus      `json:"status"`
+	NextRunAt  *time.Time                 `json:"next_run_at,omitempty"`
+	CreatedAt  time.Time                  `json:"created_at"`
+}
+
+type getScheduledTransfersResponse struct {
+	ScheduledTransfers []outScheduledTransfer `json:"scheduled_transfers"`
+}
+
+func getScheduledTransfersEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getScheduledTransfersRequest)
+		schedules, err := svc.GetScheduledTransfers(ctx, req.From)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]outScheduledTransfer, 0, len(schedules))
+		for _, st := range schedules {
+			out = append(out, outScheduledTransfer{
+				Id:         st.Id,
+				From:       st.From,
+				To:         st.To,
+				Amount:     st.Amount,
+				Currency:   st.Currency,
+				Recurrence: st.Recurrence,
+				DayOfMonth: st.DayOfMonth,
+				StartAt:    st.StartAt,
+				EndAt:      optionalTime(st.EndAt),
+				Status:     st.Status,
+				NextRunAt:  optionalTime(st.NextRunAt),
+				CreatedAt:  st.CreatedAt,
+			})
+		}
+		return getScheduledTransfersResponse{out}, nil
+	}
+}
+
+// optionalTime returns nil for zero t, so it's omitted.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func decodeGetScheduledTransfersRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request getScheduledTransfersRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		getScheduledTransfersEndpoint(svc),
+		decodeGetScheduledTransfersRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
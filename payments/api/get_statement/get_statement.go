@@ -0,0 +1,125 @@
+package get_statement
+
+import (
+	"context"
+	"encoding/csv"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type getStatementRequest struct {
+	AccountId entity.AccountID `json:"account_id"`
+	Since     time.Time        `json:"since"`
+	Until     time.Time        `json:"until"`
+}
+
+type outLine struct {
+	Time         time.Time        `json:"time"`
+	PaymentId    entity.PaymentID `json:"payment_id,omitempty"`
+	Counterparty entity.AccountID `json:"counterparty,omitempty"`
+	Amount       money.Numeric    `json:"amount"`
+	Balance      money.Numeric    `json:"balance"`
+}
+
+type getStatementResponse struct {
+	AccountId      entity.AccountID `json:"account_id"`
+	Currency       string           `json:"currency"`
+	Since          time.Time        `json:"since"`
+	Until          time.Time        `json:"until"`
+	OpeningBalance money.Numeric    `json:"opening_balance"`
+	Lines          []outLine        `json:"lines"`
+	ClosingBalance money.Numeric    `json:"closing_balance"`
+}
+
+func getStatementEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getStatementRequest)
+		statement, err := svc.GetStatement(ctx, req.AccountId, req.Since, req.Until)
+		if err != nil {
+			return nil, err
+		}
+
+		lines := make([]outLine, 0, len(statement.Lines))
+		for _, l := range statement.Lines {
+			lines = append(lines, outLine{
+				Time:         l.Time,
+				PaymentId:    l.Payment,
+				Counterparty: l.Counterparty,
+				Amount:       l.Amount,
+				Balance:      l.Balance,
+			})
+		}
+		return getStatementResponse{
+			AccountId:      statement.Account,
+			Currency:       string(statement.Currency),
+			Since:          statement.Since,
+			Until:          statement.Until,
+			OpeningBalance: statement.OpeningBalance,
+			Lines:          lines,
+			ClosingBalance: statement.ClosingBalance,
+		}, nil
+	}
+}
+
+func decodeGetStatementRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request getStatementRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// encodeCSVResponse writes the statement as CSV, opening and closing balances being the first and the last rows.
+func encodeCSVResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+	statement := response.(getStatementResponse)
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+
+	cw := csv.NewWriter(w)
+	rows := [][]string{
+		{"time", "description", "payment_id", "counterparty", "amount", "balance", "currency"},
+		{formatTime(statement.Since), "opening balance", "", "", "", statement.OpeningBalance.String(), statement.Currency},
+	}
+	for _, l := range statement.Lines {
+		description, paymentId := "initial balance", ""
+		if l.PaymentId != 0 {
+			description, paymentId = "payment", strconv.FormatInt(int64(l.PaymentId), 10)
+		}
+		rows = append(rows, []string{
+			formatTime(l.Time), description, paymentId, string(l.Counterparty), l.Amount.String(), l.Balance.String(), statement.Currency,
+		})
+	}
+	rows = append(rows, []string{
+		formatTime(statement.Until), "closing balance", "", "", "", statement.ClosingBalance.String(), statement.Currency,
+	})
+	return cw.WriteAll(rows)
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		getStatementEndpoint(svc),
+		decodeGetStatementRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
+
+// CSVServer serves the same statement as Server, but as CSV. Errors are still returned as JSON.
+func CSVServer(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		getStatementEndpoint(svc),
+		decodeGetStatementRequest,
+		encodeCSVResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
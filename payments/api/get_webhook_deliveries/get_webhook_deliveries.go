@@ -0,0 +1,90 @@
+package get_webhook_deliveries
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"net/http"
+	"time"
+)
+
+type getWebhookDeliveriesRequest struct {
+	WebhookId entity.WebhookID      `json:"webhook_id"`
+	Status    entity.DeliveryStatus `json:"status"`
+	Limit     int                   `json:"limit"`
+}
+
+type outDelivery struct {
+	Id            entity.DeliveryID     `json:"id"`
+	WebhookId     entity.WebhookID      `json:"webhook_id"`
+	EventId       entity.EventID        `json:"event_id"`
+	EventType     entity.EventType      `json:"event_type"`
+	Payload       json.RawMessage       `json:"payload"`
+	Status        entity.DeliveryStatus `json:"status"`
+	Attempts      int                   `json:"attempts"`
+	NextAttemptAt *time.Time            `json:"next_attempt_at,omitempty"`
+	LastError     string                `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time            `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time             `json:"created_at"`
+}
+
+type getWebhookDeliveriesResponse struct {
+	Deliveries []outDelivery `json:"deliveries"`
+}
+
+func getWebhookDeliveriesEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(getWebhookDeliveriesRequest)
+		deliveries, err := svc.GetWebhookDeliveries(ctx, service.DeliveriesQuery{
+			Webhook: req.WebhookId,
+			Status:  req.Status,
+			Limit:   req.Limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		out := make([]outDelivery, 0, len(deliveries))
+		for _, d := range deliveries {
+			o := outDelivery{
+				Id:        d.Id,
+				WebhookId: d.Webhook,
+				EventId:   d.Event.Id,
+				EventType: d.Event.Type,
+				Payload:   d.Event.Payload,
+				Status:    d.Status,
+				Attempts:  d.Attempts,
+				LastError: d.LastError,
+				CreatedAt: d.CreatedAt,
+			}
+			if d.Status == entity.DeliveryPending {
+				o.NextAttemptAt = &d.NextAttemptAt
+			}
+			if !d.DeliveredAt.IsZero() {
+				o.DeliveredAt = &d.DeliveredAt
+			}
+			out = append(out, o)
+		}
+		return getWebhookDeliveriesResponse{out}, nil
+	}
+}
+
+func decodeGetWebhookDeliveriesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request getWebhookDeliveriesRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		getWebhookDeliveriesEndpoint(svc),
+		decodeGetWebhookDeliveriesRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
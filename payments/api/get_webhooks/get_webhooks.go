@@ -0,0 +1,64 @@
+package get_webhooks
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"net/http"
+	"time"
+)
+
+type getWebhooksRequest struct{}
+
+// outWebhook never contains the secret.
+type outWebhook struct {
+	Id         entity.WebhookID   `json:"id"`
+	URL        string             `json:"url"`
+	AccountId  entity.AccountID   `json:"account_id,omitempty"`
+	EventTypes []entity.EventType `json:"event_types,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+type getWebhooksResponse struct {
+	Webhooks []outWebhook `json:"webhooks"`
+}
+
+func getWebhooksEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		webhooks, err := svc.GetWebhooks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]outWebhook, 0, len(webhooks))
+		for _, w := range webhooks {
+			out = append(out, outWebhook{
+				Id:         w.Id,
+				URL:        w.URL,
+				AccountId:  w.Account,
+				EventTypes: w.EventTypes,
+				CreatedAt:  w.CreatedAt,
+			})
+		}
+		return getWebhooksResponse{out}, nil
+	}
+}
+
+func decodeGetWebhooksRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request getWebhooksRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		getWebhooksEndpoint(svc),
+		decodeGetWebhooksRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
@@ -0,0 +1,271 @@
+// Package grpc exposes a subset of HTTP API endpoints over gRPC, see pb/payments.proto.
+package grpc
+
+import (
+	"context"
+	"fmt"
+	grpctransport "github.com/go-kit/kit/transport/grpc"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/api/create_account"
+	"github.com/lightsgoout/fintech-go/payments/api/get_accounts"
+	"github.com/lightsgoout/fintech-go/payments/api/get_payments"
+	"github.com/lightsgoout/fintech-go/payments/api/grpc/pb"
+	"github.com/lightsgoout/fintech-go/payments/api/transfer"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net/http"
+	"time"
+)
+
+// errorDomain is ErrorInfo.Domain of errors returned by the server.
+const errorDomain = "payments"
+
+type server struct {
+	pb.UnimplementedPaymentsServer
+
+	createAccount grpctransport.Handler
+	transfer      grpctransport.Handler
+	getAccounts   grpctransport.Handler
+	getPayments   grpctransport.Handler
+}
+
+// NewServer serves the same go-kit endpoints as HTTP API does.
+func NewServer(svc service.PaymentsService) pb.PaymentsServer {
+	before := grpctransport.ServerBefore(requestContext)
+	return &server{
+		createAccount: grpctransport.NewServer(
+			create_account.CreateAccountEndpoint(svc),
+			decodeCreateAccountRequest,
+			encodeCreateAccountResponse,
+			before,
+		),
+		transfer: grpctransport.NewServer(
+			transfer.TransferEndpoint(svc),
+			decodeTransferRequest,
+			encodeTransferResponse,
+			before,
+		),
+		getAccounts: grpctransport.NewServer(
+			get_accounts.GetAccountsEndpoint(svc),
+			decodeGetAccountsRequest,
+			encodeGetAccountsResponse,
+			before,
+		),
+		getPayments: grpctransport.NewServer(
+			get_payments.GetPaymentsEndpoint(svc),
+			decodeGetPaymentsRequest,
+			encodeGetPaymentsResponse,
+			before,
+		),
+	}
+}
+
+func (s *server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	_, resp, err := s.createAccount.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeError(err)
+	}
+	return resp.(*pb.CreateAccountResponse), nil
+}
+
+func (s *server) Transfer(ctx context.Context, req *pb.TransferRequest) (*pb.TransferResponse, error) {
+	_, resp, err := s.transfer.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeError(err)
+	}
+	return resp.(*pb.TransferResponse), nil
+}
+
+func (s *server) GetAccounts(ctx context.Context, req *pb.GetAccountsRequest) (*pb.GetAccountsResponse, error) {
+	_, resp, err := s.getAccounts.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeError(err)
+	}
+	return resp.(*pb.GetAccountsResponse), nil
+}
+
+func (s *server) GetPayments(ctx context.Context, req *pb.GetPaymentsRequest) (*pb.GetPaymentsResponse, error) {
+	_, resp, err := s.getPayments.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeError(err)
+	}
+	return resp.(*pb.GetPaymentsResponse), nil
+}
+
+// requestContext puts the request id and the actor claimed in metadata into the context of a request,
+// the same way HTTP API takes them from X-Request-Id and X-Actor headers. The request id is empty if not sent.
+func requestContext(ctx context.Context, md metadata.MD) context.Context {
+	if ids := md.Get("x-request-id"); len(ids) > 0 {
+		ctx = service.ContextWithRequestId(ctx, ids[0])
+	}
+	if actors := md.Get("x-actor"); len(actors) > 0 {
+		ctx = service.ContextWithActor(ctx, actors[0])
+	}
+	return ctx
+}
+
+func decodeCreateAccountRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.CreateAccountRequest)
+	balance, err := decodeAmount(req.Balance)
+	if err != nil {
+		return nil, err
+	}
+	return create_account.CreateAccountRequest{
+		Id:       entity.AccountID(req.Id),
+		Balance:  balance,
+		Currency: req.Currency,
+	}, nil
+}
+
+func encodeCreateAccountResponse(_ context.Context, _ interface{}) (interface{}, error) {
+	return &pb.CreateAccountResponse{}, nil
+}
+
+func decodeTransferRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.TransferRequest)
+	amount, err := decodeAmount(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return transfer.TransferRequest{
+		From:           entity.AccountID(req.From),
+		To:             entity.AccountID(req.To),
+		Amount:         amount,
+		Currency:       req.Currency,
+		IdempotencyKey: entity.IdempotencyKey(req.IdempotencyKey),
+	}, nil
+}
+
+func encodeTransferResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(transfer.TransferResponse)
+	return &pb.TransferResponse{PaymentId: int64(resp.PaymentId)}, nil
+}
+
+func decodeGetAccountsRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.GetAccountsRequest)
+	return get_accounts.GetAccountsRequest{Currency: req.Currency}, nil
+}
+
+func encodeGetAccountsResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(get_accounts.GetAccountsResponse)
+	accounts := make([]string, 0, len(resp.Accounts))
+	for _, id := range resp.Accounts {
+		accounts = append(accounts, string(id))
+	}
+	return &pb.GetAccountsResponse{Accounts: accounts}, nil
+}
+
+func decodeGetPaymentsRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.GetPaymentsRequest)
+	r := get_payments.GetPaymentsRequest{
+		AccountId:    entity.AccountID(req.AccountId),
+		Cursor:       req.Cursor,
+		Limit:        int(req.Limit),
+		Since:        decodeTime(req.Since),
+		Until:        decodeTime(req.Until),
+		Direction:    service.PaymentDirection(req.Direction),
+		Counterparty: entity.AccountID(req.Counterparty),
+	}
+	if req.MinAmount != "" {
+		minAmount, err := decodeAmount(req.MinAmount)
+		if err != nil {
+			return nil, err
+		}
+		r.MinAmount = &minAmount
+	}
+	if req.MaxAmount != "" {
+		maxAmount, err := decodeAmount(req.MaxAmount)
+		if err != nil {
+			return nil, err
+		}
+		r.MaxAmount = &maxAmount
+	}
+	return r, nil
+}
+
+func encodeGetPaymentsResponse(_ context.Context, response interface{}) (interface{}, error) {
+	resp := response.(get_payments.GetPaymentsResponse)
+	payments := make([]*pb.Payment, 0, len(resp.Payments))
+	for _, p := range resp.Payments {
+		payment := &pb.Payment{
+			Id:         int64(p.Id),
+			Time:       timestamppb.New(p.Time),
+			From:       string(p.From),
+			To:         string(p.To),
+			Amount:     p.Amount.String(),
+			Currency:   p.Currency,
+			Outgoing:   p.Outgoing,
+			RefundOf:   int64(p.RefundOf),
+			FeeAccount: string(p.FeeAccount),
+		}
+		if p.Exchange != nil {
+			payment.Exchange = &pb.Exchange{
+				QuoteId:    int64(p.Exchange.QuoteId),
+				Rate:       p.Exchange.Rate.String(),
+				ToAmount:   p.Exchange.ToAmount.String(),
+				ToCurrency: p.Exchange.ToCurrency,
+			}
+		}
+		for _, id := range p.RefundedBy {
+			payment.RefundedBy = append(payment.RefundedBy, int64(id))
+		}
+		if p.Fee != nil {
+			payment.Fee = p.Fee.String()
+		}
+		payments = append(payments, payment)
+	}
+	return &pb.GetPaymentsResponse{Payments: payments, NextCursor: resp.NextCursor}, nil
+}
+
+// decodeAmount parses a decimal string, empty string being zero like an omitted JSON field.
+func decodeAmount(raw string) (money.Numeric, error) {
+	if raw == "" {
+		return money.NewNumericFromInt64(0), nil
+	}
+	amount, err := money.NewNumericFromString(raw)
+	if err != nil {
+		return money.Numeric{}, fmt.Errorf("%w: %s", common.ErrBadRequest, err)
+	}
+	return amount, nil
+}
+
+// decodeTime converts unset timestamps to zero time, which service treats as no bound.
+func decodeTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// grpcCodes map HTTP statuses of common.DescribeError to gRPC codes.
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.FailedPrecondition,
+	http.StatusUnprocessableEntity: codes.InvalidArgument,
+}
+
+// encodeError converts err to gRPC status with the same code and details as HTTP API returns
+// carried by ErrorInfo.
+func encodeError(err error) error {
+	httpStatus, body := common.DescribeError(err)
+	code, ok := grpcCodes[httpStatus]
+	if !ok {
+		code = codes.Internal
+	}
+	st, detailsErr := status.New(code, body.Message).WithDetails(&errdetails.ErrorInfo{
+		Reason:   body.Code,
+		Domain:   errorDomain,
+		Metadata: body.Details,
+	})
+	if detailsErr != nil {
+		return status.Error(code, body.Message)
+	}
+	return st.Err()
+}
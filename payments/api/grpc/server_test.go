@@ -0,0 +1,124 @@
+package grpc
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"github.com/lightsgoout/fintech-go/payments/api/grpc/pb"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/payments/service/audit"
+	"github.com/lightsgoout/fintech-go/payments/service/memory"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+func newTestClient(t *testing.T, svc service.PaymentsService) pb.PaymentsClient {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pb.RegisterPaymentsServer(srv, NewServer(svc))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewPaymentsClient(conn)
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, memory.NewPaymentsService())
+
+	for _, id := range [...]string{"bob", "alice"} {
+		_, err := client.CreateAccount(ctx, &pb.CreateAccountRequest{Id: id, Balance: "100", Currency: "USD"})
+		assert.NoError(t, err)
+	}
+
+	transferResp, err := client.Transfer(ctx, &pb.TransferRequest{From: "bob", To: "alice", Amount: "10.5", Currency: "USD", IdempotencyKey: "k1"})
+	if assert.NoError(t, err) {
+		assert.NotZero(t, transferResp.PaymentId)
+	}
+
+	replayResp, err := client.Transfer(ctx, &pb.TransferRequest{From: "bob", To: "alice", Amount: "10.5", Currency: "USD", IdempotencyKey: "k1"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, transferResp.PaymentId, replayResp.PaymentId)
+	}
+
+	accountsResp, err := client.GetAccounts(ctx, &pb.GetAccountsRequest{Currency: "USD"})
+	if assert.NoError(t, err) {
+		assert.ElementsMatch(t, accountsResp.Accounts, []string{"bob", "alice"})
+	}
+
+	paymentsResp, err := client.GetPayments(ctx, &pb.GetPaymentsRequest{AccountId: "bob", Direction: "outgoing", MinAmount: "10"})
+	if assert.NoError(t, err) && assert.Len(t, paymentsResp.Payments, 1) {
+		p := paymentsResp.Payments[0]
+		assert.Equal(t, p.Id, transferResp.PaymentId)
+		assert.Equal(t, p.From, "bob")
+		assert.Equal(t, p.To, "alice")
+		assert.Equal(t, p.Amount, "10.5")
+		assert.Equal(t, p.Currency, "USD")
+		assert.True(t, p.Outgoing)
+		assert.NotNil(t, p.Time)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	ctx := context.Background()
+	client := newTestClient(t, memory.NewPaymentsService())
+
+	_, err := client.CreateAccount(ctx, &pb.CreateAccountRequest{Id: "bob", Balance: "5", Currency: "USD"})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		req    *pb.TransferRequest
+		code   codes.Code
+		reason string
+	}{
+		{"malformed amount", &pb.TransferRequest{From: "bob", To: "alice", Amount: "ten", Currency: "USD"}, codes.InvalidArgument, "bad_request"},
+		{"unknown account", &pb.TransferRequest{From: "bob", To: "alice", Amount: "1", Currency: "USD"}, codes.NotFound, "account_not_found"},
+		{"insufficient funds", &pb.TransferRequest{From: "bob", To: "bob2", Amount: "10", Currency: "USD"}, codes.FailedPrecondition, "insufficient_funds"},
+	}
+	_, err = client.CreateAccount(ctx, &pb.CreateAccountRequest{Id: "bob2", Currency: "USD"})
+	assert.NoError(t, err)
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := client.Transfer(ctx, c.req)
+			st := status.Convert(err)
+			assert.Equal(t, st.Code(), c.code)
+			if assert.Len(t, st.Details(), 1) {
+				info := st.Details()[0].(*errdetails.ErrorInfo)
+				assert.Equal(t, info.Reason, c.reason)
+				assert.Equal(t, info.Domain, errorDomain)
+			}
+		})
+	}
+}
+
+func TestServer_Metadata(t *testing.T) {
+	svc := audit.NewPaymentsService(memory.NewPaymentsService(), log.NewNopLogger())
+	client := newTestClient(t, svc)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-42", "x-actor", "backoffice")
+	_, err := client.CreateAccount(ctx, &pb.CreateAccountRequest{Id: "bob", Balance: "100", Currency: "USD"})
+	assert.NoError(t, err)
+
+	entries, err := svc.GetAuditLog(context.Background(), service.AuditQuery{})
+	if assert.NoError(t, err) && assert.Len(t, entries, 1) {
+		assert.Equal(t, entries[0].RequestId, "req-42")
+		assert.Equal(t, entries[0].Actor, "backoffice")
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"github.com/go-kit/kit/log/level"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"net/http"
+	"time"
+)
+
+// readinessTimeout bounds how long readiness checks take altogether, so probes get an answer in time.
+const readinessTimeout = 2 * time.Second
+
+// ReadinessCheck returns an error unless a dependency of the service is ready, e.g. the database is reachable.
+type ReadinessCheck func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+
+	// Failed are names of failed readiness checks, their errors are logged but never returned
+	Failed []string `json:"failed,omitempty"`
+}
+
+// healthz answers liveness probes: the process is up and serving requests.
+func healthz(w http.ResponseWriter, r *http.Request) {
+	_ = common.EncodeResponse(r.Context(), w, healthResponse{Status: "ok"})
+}
+
+// readyz answers readiness probes, failing with 503 Service Unavailable unless every check passes.
+func (o options) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	resp := healthResponse{Status: "ok"}
+	for _, c := range o.readiness {
+		if err := c.check(ctx); err != nil {
+			resp.Failed = append(resp.Failed, c.name)
+			if o.logger != nil {
+				_ = level.Warn(o.logger.logger).Log("msg", "readiness check failed", "check", c.name, "err", err)
+			}
+		}
+	}
+	if len(resp.Failed) > 0 {
+		resp.Status = "unavailable"
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = common.EncodeResponse(r.Context(), w, resp)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"net/http"
+	"time"
+)
+
+const (
+	// RequestIdHeader carries the id of a request, which is generated unless the caller sends one.
+	// Responses echo it, so a caller can find log records and audit log entries of its request.
+	RequestIdHeader = "X-Request-Id"
+
+	// ActorHeader tells who makes a request, e.g. set by a gateway authenticating callers.
+	// It's sent by the caller and never verified, so it's recorded in the audit log as the claimed actor.
+	ActorHeader = "X-Actor"
+
+	// maxRequestIdLength caps ids sent by callers, longer ones are replaced.
+	maxRequestIdLength = 128
+)
+
+// requestContext is a mux middleware putting the request id and the actor into the context of a request,
+// so service calls made by its endpoint are logged and audited with them.
+func requestContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIdHeader)
+		if id == "" || len(id) > maxRequestIdLength {
+			id = newRequestId()
+		}
+		w.Header().Set(RequestIdHeader, id)
+
+		ctx := service.ContextWithRequestId(r.Context(), id)
+		if actor := r.Header.Get(ActorHeader); actor != "" {
+			ctx = service.ContextWithActor(ctx, actor)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestId() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// httpLogger logs every request matching a route of the API.
+type httpLogger struct {
+	logger log.Logger
+}
+
+// log is a mux middleware logging a request once it's served. Server errors are logged at error level.
+func (l httpLogger) log(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		begin := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		logger := level.Info(l.logger)
+		if rec.status >= http.StatusInternalServerError {
+			logger = level.Error(l.logger)
+		}
+		_ = logger.Log(
+			"request_id", service.RequestIdFromContext(r.Context()),
+			"http_method", r.Method,
+			"route", routeTemplate(r),
+			"code", rec.status,
+			"took", time.Since(begin),
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
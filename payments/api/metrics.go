@@ -0,0 +1,78 @@
+package api
+
+import (
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPMetrics are collected for every request matching a route of the API.
+type HTTPMetrics struct {
+	// Requests counts requests, labeled by "route", "method" and "code", the HTTP status
+	Requests metrics.Counter
+
+	// Duration observes how long requests take in seconds, labeled by "route" and "method"
+	Duration metrics.Histogram
+}
+
+// NewPrometheusHTTPMetrics returns HTTPMetrics registered with reg.
+func NewPrometheusHTTPMetrics(reg prometheus.Registerer) HTTPMetrics {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "payments",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests.",
+	}, []string{"route", "method", "code"})
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "payments",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+	reg.MustRegister(requests, duration)
+
+	return HTTPMetrics{
+		Requests: kitprometheus.NewCounter(requests),
+		Duration: kitprometheus.NewHistogram(duration),
+	}
+}
+
+// statusRecorder remembers the status of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// instrument is a mux middleware collecting m. Routes are labeled by their path templates,
+// so the number of label values stays bounded whatever the requests are.
+func (m HTTPMetrics) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		begin := time.Now()
+		route := routeTemplate(r)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		m.Requests.With("route", route, "method", r.Method, "code", strconv.Itoa(rec.status)).Add(1)
+		m.Duration.With("route", route, "method", r.Method).Observe(time.Since(begin).Seconds())
+	})
+}
+
+// routeTemplate returns the path template of the route r matched, or its path if there is none.
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
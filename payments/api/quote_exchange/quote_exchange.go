@@ -0,0 +1,64 @@
+package quote_exchange
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"net/http"
+	"time"
+)
+
+type quoteExchangeRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type quoteExchangeResponse struct {
+	QuoteId   entity.QuoteID `json:"quote_id"`
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	Rate      money.Numeric  `json:"rate"`
+	ExpiresAt time.Time      `json:"expires_at"`
+}
+
+func quoteExchangeEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(quoteExchangeRequest)
+		quote, err := svc.QuoteExchange(
+			ctx,
+			money.NewCurrency(req.From),
+			money.NewCurrency(req.To),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return quoteExchangeResponse{
+			QuoteId:   quote.Id,
+			From:      string(quote.From),
+			To:        string(quote.To),
+			Rate:      quote.Rate,
+			ExpiresAt: quote.ExpiresAt,
+		}, nil
+	}
+}
+
+func decodeQuoteExchangeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request quoteExchangeRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		quoteExchangeEndpoint(svc),
+		decodeQuoteExchangeRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
@@ -0,0 +1,51 @@
+package refund_payment
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"net/http"
+)
+
+type refundPaymentRequest struct {
+	PaymentId entity.PaymentID `json:"payment_id"`
+
+	// Amount is a part of the payment to refund, all of it not refunded yet if omitted
+	Amount *money.Numeric `json:"amount"`
+}
+
+type refundPaymentResponse struct {
+	PaymentId entity.PaymentID `json:"payment_id"`
+}
+
+func refundPaymentEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(refundPaymentRequest)
+		refundId, err := svc.Refund(ctx, req.PaymentId, req.Amount)
+		if err != nil {
+			return nil, err
+		}
+		return refundPaymentResponse{refundId}, nil
+	}
+}
+
+func decodeRefundPaymentRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request refundPaymentRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		refundPaymentEndpoint(svc),
+		decodeRefundPaymentRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
@@ -0,0 +1,54 @@
+package replay_webhook_delivery
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"net/http"
+	"time"
+)
+
+type replayWebhookDeliveryRequest struct {
+	DeliveryId entity.DeliveryID `json:"delivery_id"`
+}
+
+type replayWebhookDeliveryResponse struct {
+	Id            entity.DeliveryID     `json:"id"`
+	Status        entity.DeliveryStatus `json:"status"`
+	NextAttemptAt time.Time             `json:"next_attempt_at"`
+}
+
+func replayWebhookDeliveryEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(replayWebhookDeliveryRequest)
+		delivery, err := svc.ReplayWebhookDelivery(ctx, req.DeliveryId, time.Now().UTC())
+		if err != nil {
+			return nil, err
+		}
+		return replayWebhookDeliveryResponse{
+			Id:            delivery.Id,
+			Status:        delivery.Status,
+			NextAttemptAt: delivery.NextAttemptAt,
+		}, nil
+	}
+}
+
+func decodeReplayWebhookDeliveryRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request replayWebhookDeliveryRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		replayWebhookDeliveryEndpoint(svc),
+		decodeReplayWebhookDeliveryRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
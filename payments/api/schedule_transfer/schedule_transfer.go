@@ -0,0 +1,97 @@
+package schedule_transfer
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"net/http"
+	"time"
+)
+
+type scheduleTransferRequest struct {
+	From       entity.AccountID  `json:"from"`
+	To         entity.AccountID  `json:"to"`
+	Amount     money.Numeric     `json:"amount"`
+	Currency   string            `json:"currency"`
+	Recurrence entity.Recurrence `json:"recurrence"`
+	DayOfMonth int               `json:"day_of_month"`
+	StartAt    time.Time         `json:"start_at"`
+	EndAt      time.Time         `json:"end_at"`
+}
+
+type scheduleTransferResponse struct {
+	Id         entity.ScheduledTransferID `json:"id"`
+	From       entity.AccountID           `json:"from"`
+	To         entity.AccountID           `json:"to"`
+	Amount     money.Numeric              `json:"amount"`
+	Currency   money.Currency             `json:"currency"`
+	Recurrence entity.Recurrence          `json:"recurrence"`
+	DayOfMonth int                        `json:"day_of_month,omitempty"`
+	StartAt    time.Time                  `json:"start_at"`
+	EndAt      *time.Time                 `json:"end_at,omitempty"`
+	Status     entity.ScheduleStatus      `json:"status"`
+	NextRunAt  *time.Time                 `json:"next_run_at,omitempty"`
+	CreatedAt  time.Time                  `json:"created_at"`
+}
+
+func scheduleTransferEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(scheduleTransferRequest)
+		st, err := svc.ScheduleTransfer(ctx, entity.ScheduledTransfer{
+			From:       req.From,
+			To:         req.To,
+			Amount:     req.Amount,
+			Currency:   money.NewCurrency(req.Currency),
+			Recurrence: req.Recurrence,
+			DayOfMonth: req.DayOfMonth,
+			StartAt:    req.StartAt,
+			EndAt:      req.EndAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return scheduleTransferResponse{
+			Id:         st.Id,
+			From:       st.From,
+			To:         st.To,
+			Amount:     st.Amount,
+			Currency:   st.Currency,
+			Recurrence: st.Recurrence,
+			DayOfMonth: st.DayOfMonth,
+			StartAt:    st.StartAt,
+			EndAt:      optionalTime(st.EndAt),
+			Status:     st.Status,
+			NextRunAt:  optionalTime(st.NextRunAt),
+			CreatedAt:  st.CreatedAt,
+		}, nil
+	}
+}
+
+// optionalTime returns nil for zero t, so it's omitted.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func decodeScheduleTransferRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request scheduleTransferRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		scheduleTransferEndpoint(svc),
+		decodeScheduleTransferRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
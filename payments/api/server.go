@@ -1,20 +1,158 @@
 package api
 
 import (
+	"github.com/go-kit/kit/log"
 	"github.com/gorilla/mux"
+	"github.com/lightsgoout/fintech-go/payments/api/authorize_hold"
+	"github.com/lightsgoout/fintech-go/payments/api/cancel_scheduled_transfer"
+	"github.com/lightsgoout/fintech-go/payments/api/capture_hold"
 	"github.com/lightsgoout/fintech-go/payments/api/create_account"
+	"github.com/lightsgoout/fintech-go/payments/api/create_webhook"
+	"github.com/lightsgoout/fintech-go/payments/api/delete_webhook"
+	"github.com/lightsgoout/fintech-go/payments/api/get_account_limits"
 	"github.com/lightsgoout/fintech-go/payments/api/get_accounts"
+	"github.com/lightsgoout/fintech-go/payments/api/get_audit_log"
+	"github.com/lightsgoout/fintech-go/payments/api/get_balance"
 	"github.com/lightsgoout/fintech-go/payments/api/get_payments"
+	"github.com/lightsgoout/fintech-go/payments/api/get_scheduled_runs"
+	"github.com/lightsgoout/fintech-go/payments/api/get_scheduled_transfers"
+	"github.com/lightsgoout/fintech-go/payments/api/get_statement"
+	"github.com/lightsgoout/fintech-go/payments/api/get_webhook_deliveries"
+	"github.com/lightsgoout/fintech-go/payments/api/get_webhooks"
+	"github.com/lightsgoout/fintech-go/payments/api/quote_exchange"
+	"github.com/lightsgoout/fintech-go/payments/api/refund_payment"
+	"github.com/lightsgoout/fintech-go/payments/api/replay_webhook_delivery"
+	"github.com/lightsgoout/fintech-go/payments/api/schedule_transfer"
+	"github.com/lightsgoout/fintech-go/payments/api/set_account_limits"
+	"github.com/lightsgoout/fintech-go/payments/api/set_account_status"
+	"github.com/lightsgoout/fintech-go/payments/api/set_credit_limit"
 	"github.com/lightsgoout/fintech-go/payments/api/transfer"
+	"github.com/lightsgoout/fintech-go/payments/api/transfer_batch"
+	"github.com/lightsgoout/fintech-go/payments/api/transfer_with_quote"
+	"github.com/lightsgoout/fintech-go/payments/api/void_hold"
 	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/payments/service/tracing"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"net/http"
 )
 
-func NewAPIServer(svc service.PaymentsService) http.Handler {
-	router := mux.NewRouter()
+// Option configures optional settings of the API server.
+type Option func(o *options)
+
+type options struct {
+	metrics *HTTPMetrics
+	tracer  *httpTracer
+	logger  *httpLogger
+
+	// readiness are checks made by /readyz
+	readiness []namedCheck
+}
+
+// WithMetrics enables collecting metrics of requests.
+func WithMetrics(m HTTPMetrics) Option {
+	return func(o *options) {
+		o.metrics = &m
+	}
+}
+
+// WithTracing enables tracing of requests by a tracer of tp, continuing traces of callers sending W3C traceparent header.
+func WithTracing(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracer = &httpTracer{
+			tracer:     tp.Tracer(tracing.InstrumentationName),
+			propagator: propagation.TraceContext{},
+		}
+	}
+}
+
+// WithLogger enables logging of requests to logger.
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) {
+		o.logger = &httpLogger{logger: logger}
+	}
+}
+
+// WithReadinessCheck makes /readyz fail unless check passes, name tells which one failed.
+func WithReadinessCheck(name string, check ReadinessCheck) Option {
+	return func(o *options) {
+		o.readiness = append(o.readiness, namedCheck{name: name, check: check})
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// subrouter returns a router of root whose requests are logged, traced and measured as configured.
+func (o options) subrouter(root *mux.Router) *mux.Router {
+	router := root.NewRoute().Subrouter()
+	router.Use(requestContext)
+	if o.tracer != nil {
+		router.Use(o.tracer.trace)
+	}
+	if o.logger != nil {
+		router.Use(o.logger.log)
+	}
+	if o.metrics != nil {
+		router.Use(o.metrics.instrument)
+	}
+	return router
+}
+
+// NewAPIServer returns a handler of HTTP API, and of /healthz and /readyz probes.
+// Probes are neither logged, traced nor measured, since they come every few seconds.
+// Admin endpoints are served by NewAdminServer.
+func NewAPIServer(svc service.PaymentsService, opts ...Option) http.Handler {
+	o := newOptions(opts)
+
+	root := mux.NewRouter()
+	root.Methods("GET").Path("/healthz").HandlerFunc(healthz)
+	root.Methods("GET").Path("/readyz").HandlerFunc(o.readyz)
+
+	router := o.subrouter(root)
 	router.Methods("POST").Path("/account/create").Handler(create_account.Server(svc))
 	router.Methods("POST").Path("/transfer").Handler(transfer.Server(svc))
+	router.Methods("POST").Path("/transfer/batch").Handler(transfer_batch.Server(svc))
+	router.Methods("POST").Path("/transfer/schedule").Handler(schedule_transfer.Server(svc))
+	router.Methods("POST").Path("/transfer/schedule/list").Handler(get_scheduled_transfers.Server(svc))
+	router.Methods("POST").Path("/transfer/schedule/cancel").Handler(cancel_scheduled_transfer.Server(svc))
+	router.Methods("POST").Path("/transfer/schedule/runs").Handler(get_scheduled_runs.Server(svc))
+	router.Methods("POST").Path("/exchange/quote").Handler(quote_exchange.Server(svc))
+	router.Methods("POST").Path("/exchange/transfer").Handler(transfer_with_quote.Server(svc))
+	router.Methods("POST").Path("/hold/authorize").Handler(authorize_hold.Server(svc))
+	router.Methods("POST").Path("/hold/capture").Handler(capture_hold.Server(svc))
+	router.Methods("POST").Path("/hold/void").Handler(void_hold.Server(svc))
+	router.Methods("POST").Path("/payment/refund").Handler(refund_payment.Server(svc))
 	router.Methods("POST").Path("/account/list").Handler(get_accounts.Server(svc))
 	router.Methods("POST").Path("/payment/list").Handler(get_payments.Server(svc))
-	return router
+	router.Methods("POST").Path("/account/balance").Handler(get_balance.Server(svc))
+	router.Methods("POST").Path("/account/statement").Handler(get_statement.Server(svc))
+	router.Methods("POST").Path("/account/statement.csv").Handler(get_statement.CSVServer(svc))
+	return root
+}
+
+// NewAdminServer returns a handler of admin HTTP API, which changes settings of accounts, manages webhooks
+// and lists the audit log.
+// Admin endpoints aren't authenticated, so they must be served on a listener which isn't exposed publicly.
+func NewAdminServer(svc service.PaymentsService, opts ...Option) http.Handler {
+	o := newOptions(opts)
+
+	root := mux.NewRouter()
+	router := o.subrouter(root)
+	router.Methods("POST").Path("/admin/account/status").Handler(set_account_status.Server(svc))
+	router.Methods("POST").Path("/admin/account/credit_limit").Handler(set_credit_limit.Server(svc))
+	router.Methods("POST").Path("/admin/account/limits/set").Handler(set_account_limits.Server(svc))
+	router.Methods("POST").Path("/admin/account/limits/get").Handler(get_account_limits.Server(svc))
+	router.Methods("POST").Path("/admin/webhook/create").Handler(create_webhook.Server(svc))
+	router.Methods("POST").Path("/admin/webhook/list").Handler(get_webhooks.Server(svc))
+	router.Methods("POST").Path("/admin/webhook/delete").Handler(delete_webhook.Server(svc))
+	router.Methods("POST").Path("/admin/webhook/deliveries").Handler(get_webhook_deliveries.Server(svc))
+	router.Methods("POST").Path("/admin/webhook/deliveries/replay").Handler(replay_webhook_delivery.Server(svc))
+	router.Methods("POST").Path("/admin/audit_log").Handler(get_audit_log.Server(svc))
+	return root
 }
@@ -1,15 +1,32 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"github.com/go-kit/kit/log"
 	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/payments/service/audit"
+	"github.com/lightsgoout/fintech-go/payments/service/memory"
 	"github.com/lightsgoout/fintech-go/payments/service/persistent"
+	"github.com/lightsgoout/fintech-go/payments/service/tracing"
+	"github.com/lightsgoout/fintech-go/pkg/fee"
+	"github.com/lightsgoout/fintech-go/pkg/fx"
 	"github.com/lightsgoout/fintech-go/pkg/money"
 	"github.com/lightsgoout/fintech-go/pkg/testing/isolation"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -65,7 +82,7 @@ func TestServer_CreateAccount(t *testing.T) {
 		}{
 			{
 				body: `{"id":"bob","currency":"UAH","balance":100.50}`,
-				want: `{"err":"incompatible currency"}`,
+				want: `{"error":{"code":"incompatible_currency","message":"incompatible currency"}}`,
 			},
 		} {
 			req, _ := http.NewRequest("POST", srv.URL+"/account/create", strings.NewReader(testcase.body))
@@ -77,13 +94,43 @@ func TestServer_CreateAccount(t *testing.T) {
 		}
 	}))
 
+	t.Run("balance as decimal string", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		for _, testcase := range []struct {
+			body, want string
+		}{
+			{
+				body: `{"id":"bob","currency":"USD","balance":"100.10"}`,
+				want: `{}`,
+			},
+			{
+				body: `{"id":"alice","currency":"USD","balance":"100.105"}`,
+				want: `{"error":{"code":"invalid_amount","message":"invalid amount"}}`,
+			},
+		} {
+			req, _ := http.NewRequest("POST", srv.URL+"/account/create", strings.NewReader(testcase.body))
+			resp, _ := http.DefaultClient.Do(req)
+			body, _ := ioutil.ReadAll(resp.Body)
+			if want, have := testcase.want, strings.TrimSpace(string(body)); want != have {
+				t.Errorf("%s : want %q, have %q", testcase.body, want, have)
+			}
+		}
+	}))
+
+	t.Run("malformed json", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/account/create", strings.NewReader(`{"id":`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+		assert.Contains(t, string(body), `"code":"bad_request"`)
+	}))
+
 	t.Run("negative balance not allowed", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
 		for _, testcase := range []struct {
 			body, want string
 		}{
 			{
 				body: `{"id":"bob","currency":"UAH","balance":-600}`,
-				want: `{"err":"insufficient funds"}`,
+				want: `{"error":{"code":"insufficient_funds","message":"insufficient funds"}}`,
 			},
 		} {
 			req, _ := http.NewRequest("POST", srv.URL+"/account/create", strings.NewReader(testcase.body))
@@ -139,6 +186,66 @@ func TestServer_Transfer(t *testing.T) {
 			assert.Greater(t, r.PaymentId, int64(0))
 		}
 	}))
+
+	t.Run("transfer exact amounts", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		for _, testcase := range []struct {
+			body, want string
+		}{
+			{
+				body: `{"from":"bob","to":"alice","currency":"USD","amount":0.1}`,
+			},
+			{
+				body: `{"from":"bob","to":"alice","currency":"USD","amount":"0.2"}`,
+			},
+			{
+				body: `{"from":"bob","to":"alice","currency":"USD","amount":"0.001"}`,
+				want: `{"error":{"code":"invalid_amount","message":"invalid amount"}}`,
+			},
+		} {
+			req, _ := http.NewRequest("POST", srv.URL+"/transfer", strings.NewReader(testcase.body))
+			resp, _ := http.DefaultClient.Do(req)
+			body, _ := ioutil.ReadAll(resp.Body)
+			if testcase.want != "" {
+				assert.Equal(t, testcase.want, strings.TrimSpace(string(body)))
+			}
+		}
+
+		page, err := svc.GetPayments(env.Ctx, service.PaymentsQuery{Account: bob})
+		if err != nil {
+			t.Error(err)
+		}
+		payments := page.Payments
+		assert.Equal(t, len(payments), 2)
+		assert.Equal(t, payments[0].Value.Amount.String(), "0.2")
+		assert.Equal(t, payments[1].Value.Amount.String(), "0.1")
+	}))
+
+	t.Run("transfer replay with idempotency key", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		var paymentIds []int64
+		for _, key := range [...]string{"abc", "abc"} {
+			req, _ := http.NewRequest("POST", srv.URL+"/transfer", strings.NewReader(`{"from":"bob","to":"alice","currency":"USD","amount":30}`))
+			req.Header.Set("Idempotency-Key", key)
+			resp, _ := http.DefaultClient.Do(req)
+			body, _ := ioutil.ReadAll(resp.Body)
+
+			var r struct {
+				PaymentId int64 `json:"payment_id"`
+			}
+			err := json.Unmarshal(body, &r)
+			if err != nil {
+				t.Error(err)
+			}
+			paymentIds = append(paymentIds, r.PaymentId)
+		}
+		assert.Greater(t, paymentIds[0], int64(0))
+		assert.Equal(t, paymentIds[0], paymentIds[1])
+
+		req, _ := http.NewRequest("POST", srv.URL+"/transfer", strings.NewReader(`{"from":"bob","to":"alice","currency":"USD","amount":31,"idempotency_key":"abc"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusConflict)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"idempotency_key_reused","message":"idempotency key reused with different parameters"}}`)
+	}))
 }
 
 func TestServer_GetAccounts(t *testing.T) {
@@ -193,7 +300,7 @@ func TestServer_GetAccounts(t *testing.T) {
 		}{
 			{
 				body: `{"currency":"UAH"}`,
-				want: `{"err":"incompatible currency"}`,
+				want: `{"error":{"code":"incompatible_currency","message":"incompatible currency"}}`,
 			},
 		} {
 			req, _ := http.NewRequest("POST", srv.URL+"/account/list", strings.NewReader(testcase.body))
@@ -224,11 +331,11 @@ func TestServer_GetPayments(t *testing.T) {
 		}
 	}
 
-	paymentFromBob, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(30), money.NewCurrency("USD"))
+	paymentFromBob, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(30), money.NewCurrency("USD"), "")
 	if err != nil {
 		t.Error(err)
 	}
-	paymentFromAlice, err := svc.Transfer(env.Ctx, alice, bob, money.NewNumericFromInt64(25), money.NewCurrency("USD"))
+	paymentFromAlice, err := svc.Transfer(env.Ctx, alice, bob, money.NewNumericFromInt64(25), money.NewCurrency("USD"), "")
 	if err != nil {
 		t.Error(err)
 	}
@@ -293,10 +400,945 @@ func TestServer_GetPayments(t *testing.T) {
 		assert.Equal(t, r.Payments[1].Amount, "30")
 	}))
 
+	t.Run("get payments page by page", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		var page struct {
+			Payments []struct {
+				Id entity.PaymentID `json:"id"`
+			} `json:"payments"`
+			NextCursor string `json:"next_cursor"`
+		}
+
+		req, _ := http.NewRequest("POST", srv.URL+"/payment/list", strings.NewReader(`{"account_id":"bob","limit":1}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		if err := json.Unmarshal(body, &page); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, len(page.Payments), 1)
+		assert.Equal(t, page.Payments[0].Id, paymentFromAlice)
+		assert.NotEmpty(t, page.NextCursor)
+
+		req, _ = http.NewRequest("POST", srv.URL+"/payment/list", strings.NewReader(`{"account_id":"bob","limit":1,"cursor":"`+page.NextCursor+`"}`))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ = ioutil.ReadAll(resp.Body)
+		page.NextCursor = ""
+		if err := json.Unmarshal(body, &page); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, len(page.Payments), 1)
+		assert.Equal(t, page.Payments[0].Id, paymentFromBob)
+		assert.Empty(t, page.NextCursor)
+	}))
+
+	t.Run("filter by direction", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/payment/list", strings.NewReader(`{"account_id":"bob","direction":"outgoing","min_amount":"10"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+
+		err := json.Unmarshal(body, &r)
+		if err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, len(r.Payments), 1)
+		assert.Equal(t, r.Payments[0].Id, paymentFromBob)
+	}))
+
+	t.Run("bad cursor", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/payment/list", strings.NewReader(`{"account_id":"bob","cursor":"garbage"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusUnprocessableEntity)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"bad_cursor","message":"bad cursor"}}`)
+	}))
+
 	t.Run("incorrect account id", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
 		req, _ := http.NewRequest("POST", srv.URL+"/payment/list", strings.NewReader(`{"account_id":""}`))
 		resp, _ := http.DefaultClient.Do(req)
 		body, _ := ioutil.ReadAll(resp.Body)
-		assert.Equal(t, strings.TrimSpace(string(body)), `{"err":"bad account id"}`)
+		assert.Equal(t, resp.StatusCode, http.StatusUnprocessableEntity)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"bad_account_id","message":"bad account id"}}`)
+	}))
+}
+
+func TestServer_TransferWithQuote(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	rates, err := fx.NewStaticRateProvider(fx.Rate{From: "USD", To: "EUR", Rate: money.NewNumericFromStringMust("0.5")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := persistent.NewPaymentsService(env.Tx, persistent.WithRateProvider(rates))
+	srv := httptest.NewServer(NewAPIServer(svc))
+	defer srv.Close()
+
+	err = svc.CreateAccount(env.Ctx, "bob", money.NewNumericFromInt64(100), "USD")
+	if err != nil {
+		t.Error(err)
+	}
+	err = svc.CreateAccount(env.Ctx, "alice", money.NewNumericFromInt64(100), "EUR")
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("quote and transfer ok", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/exchange/quote", strings.NewReader(`{"from":"usd","to":"eur"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+
+		var quote struct {
+			QuoteId int64  `json:"quote_id"`
+			Rate    string `json:"rate"`
+		}
+		if err := json.Unmarshal(body, &quote); err != nil {
+			t.Fatal(err)
+		}
+		assert.Greater(t, quote.QuoteId, int64(0))
+		assert.Equal(t, quote.Rate, "0.5")
+
+		req, _ = http.NewRequest("POST", srv.URL+"/exchange/transfer", strings.NewReader(
+			`{"from":"bob","to":"alice","amount":"20","quote_id":`+strconv.FormatInt(quote.QuoteId, 10)+`}`,
+		))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ = ioutil.ReadAll(resp.Body)
+
+		var r struct {
+			PaymentId int64 `json:"payment_id"`
+		}
+		if err := json.Unmarshal(body, &r); err != nil {
+			t.Fatal(err)
+		}
+		assert.Greater(t, r.PaymentId, int64(0))
+
+		req, _ = http.NewRequest("POST", srv.URL+"/payment/list", strings.NewReader(`{"account_id":"alice"}`))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ = ioutil.ReadAll(resp.Body)
+		assert.Contains(t, string(body), `"exchange":{"quote_id":`+strconv.FormatInt(quote.QuoteId, 10)+`,"rate":"0.5","to_amount":"10","to_currency":"EUR"}`)
+	}))
+
+	t.Run("unknown quote", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/exchange/transfer", strings.NewReader(`{"from":"bob","to":"alice","amount":"20","quote_id":999999}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"quote_not_found","message":"quote not found"}}`)
+	}))
+}
+
+func TestServer_InMemory(t *testing.T) {
+	srv := httptest.NewServer(NewAPIServer(memory.NewPaymentsService()))
+	defer srv.Close()
+
+	for _, testcase := range []struct {
+		path, body, want string
+		status           int
+	}{
+		{
+			path:   "/account/create",
+			body:   `{"id":"bob","currency":"USD","balance":"100"}`,
+			want:   `{}`,
+			status: http.StatusOK,
+		},
+		{
+			path:   "/account/create",
+			body:   `{"id":"alice","currency":"USD","balance":"0"}`,
+			want:   `{}`,
+			status: http.StatusOK,
+		},
+		{
+			path:   "/transfer",
+			body:   `{"from":"bob","to":"alice","currency":"USD","amount":"30"}`,
+			want:   `{"payment_id":1}`,
+			status: http.StatusOK,
+		},
+		{
+			path:   "/transfer",
+			body:   `{"from":"bob","to":"alice","currency":"USD","amount":"71"}`,
+			want:   `{"error":{"code":"insufficient_funds","message":"insufficient funds"}}`,
+			status: http.StatusConflict,
+		},
+		{
+			path:   "/account/list",
+			body:   `{"currency":"USD"}`,
+			want:   `{"accounts":["alice","bob"]}`,
+			status: http.StatusOK,
+		},
+	} {
+		req, _ := http.NewRequest("POST", srv.URL+testcase.path, strings.NewReader(testcase.body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, testcase.status, resp.StatusCode, testcase.body)
+		assert.Equal(t, testcase.want, strings.TrimSpace(string(body)), testcase.body)
+	}
+}
+
+func TestServer_Holds(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	svc := persistent.NewPaymentsService(env.Tx)
+	srv := httptest.NewServer(NewAPIServer(svc))
+	defer srv.Close()
+
+	for _, id := range [...]entity.AccountID{"bob", "alice"} {
+		err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	authorize := func(t *testing.T) string {
+		req, _ := http.NewRequest("POST", srv.URL+"/hold/authorize", strings.NewReader(`{"from":"bob","to":"alice","currency":"USD","amount":"30"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+		var r struct {
+			HoldId int64 `json:"hold_id"`
+		}
+		if err := json.Unmarshal(body, &r); err != nil {
+			t.Fatal(err)
+		}
+		assert.Greater(t, r.HoldId, int64(0))
+		return strconv.FormatInt(r.HoldId, 10)
+	}
+
+	t.Run("authorize and capture", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		holdId := authorize(t)
+
+		req, _ := http.NewRequest("POST", srv.URL+"/hold/capture", strings.NewReader(`{"hold_id":`+holdId+`,"amount":"25"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Contains(t, string(body), `"payment_id":`)
+
+		req, _ = http.NewRequest("POST", srv.URL+"/hold/capture", strings.NewReader(`{"hold_id":`+holdId+`}`))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ = ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusConflict)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"hold_not_active","message":"hold is already captured or voided"}}`)
+	}))
+
+	t.Run("authorize and void", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		holdId := authorize(t)
+
+		req, _ := http.NewRequest("POST", srv.URL+"/hold/void", strings.NewReader(`{"hold_id":`+holdId+`}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{}`)
+	}))
+
+	t.Run("unknown hold", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/hold/void", strings.NewReader(`{"hold_id":999999}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"hold_not_found","message":"hold not found"}}`)
+	}))
+}
+
+func TestServer_Refund(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	svc := persistent.NewPaymentsService(env.Tx)
+	srv := httptest.NewServer(NewAPIServer(svc))
+	defer srv.Close()
+
+	for _, id := range [...]entity.AccountID{"bob", "alice"} {
+		err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	t.Run("refund is linked to the payment", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		paymentId, err := svc.Transfer(env.Ctx, "bob", "alice", money.NewNumericFromInt64(30), "USD", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := strconv.FormatInt(int64(paymentId), 10)
+
+		req, _ := http.NewRequest("POST", srv.URL+"/payment/refund", strings.NewReader(`{"payment_id":`+id+`,"amount":"10"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+		var r struct {
+			PaymentId int64 `json:"payment_id"`
+		}
+		if err := json.Unmarshal(body, &r); err != nil {
+			t.Fatal(err)
+		}
+		refundId := strconv.FormatInt(r.PaymentId, 10)
+
+		req, _ = http.NewRequest("POST", srv.URL+"/payment/list", strings.NewReader(`{"account_id":"bob"}`))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ = ioutil.ReadAll(resp.Body)
+		assert.Contains(t, string(body), `"refund_of":`+id+`}`)
+		assert.Contains(t, string(body), `"refunded_by":[`+refundId+`]}`)
+
+		req, _ = http.NewRequest("POST", srv.URL+"/payment/refund", strings.NewReader(`{"payment_id":`+id+`,"amount":"21"}`))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ = ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusConflict)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"refund_exceeds_payment","message":"refund exceeds payment amount"}}`)
+	}))
+}
+
+func TestServer_TransferBatch(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	svc := persistent.NewPaymentsService(env.Tx)
+	srv := httptest.NewServer(NewAPIServer(svc))
+	defer srv.Close()
+
+	for _, id := range [...]entity.AccountID{"bob", "alice"} {
+		err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	for _, testcase := range []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			"batch OK",
+			`{"legs":[{"from":"bob","to":"alice","amount":"100","currency":"USD"},{"from":"alice","to":"bob","amount":"150","currency":"USD"}]}`,
+			http.StatusOK,
+			`"payment_ids":[`,
+		},
+		{
+			"failed leg",
+			`{"legs":[{"from":"bob","to":"alice","amount":"10","currency":"USD"},{"from":"alice","to":"bob","amount":"111","currency":"USD"}]}`,
+			http.StatusConflict,
+			`{"error":{"code":"insufficient_funds","message":"leg 1: insufficient funds"}}`,
+		},
+		{
+			"empty batch",
+			`{"legs":[]}`,
+			http.StatusUnprocessableEntity,
+			`{"error":{"code":"bad_batch","message":"bad batch"}}`,
+		},
+	} {
+		testcase := testcase
+		t.Run(testcase.name, isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", srv.URL+"/transfer/batch", strings.NewReader(testcase.body))
+			resp, _ := http.DefaultClient.Do(req)
+			body, _ := ioutil.ReadAll(resp.Body)
+			assert.Equal(t, resp.StatusCode, testcase.wantStatus)
+			assert.Contains(t, string(body), testcase.wantBody)
+		}))
+	}
+}
+
+func TestServer_AccountStatus(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	svc := persistent.NewPaymentsService(env.Tx)
+	srv := httptest.NewServer(NewAPIServer(svc))
+	defer srv.Close()
+	admin := httptest.NewServer(NewAdminServer(svc))
+	defer admin.Close()
+
+	for _, id := range [...]entity.AccountID{"bob", "alice"} {
+		err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	t.Run("frozen account can't send money", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", admin.URL+"/admin/account/status", strings.NewReader(`{"account_id":"bob","status":"frozen_debit","reason":"suspicious activity"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"account_id":"bob","status":"frozen_debit","reason":"suspicious activity"}`)
+
+		req, _ = http.NewRequest("POST", srv.URL+"/transfer", strings.NewReader(`{"from":"bob","to":"alice","amount":"10","currency":"USD"}`))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ = ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusConflict)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"account_frozen","message":"account is frozen"}}`)
+	}))
+
+	t.Run("account with money can't be closed", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", admin.URL+"/admin/account/status", strings.NewReader(`{"account_id":"alice","status":"closed","reason":"customer request"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusConflict)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"account_not_empty","message":"account balance is not zero"}}`)
+	}))
+
+	t.Run("not served publicly", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/admin/account/status", strings.NewReader(`{"account_id":"alice","status":"frozen_all","reason":"test"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+	})
+}
+
+func TestServer_Fees(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	schedule, err := fee.NewStaticSchedule(fee.Rule{Currency: "USD", Flat: money.NewNumericFromStringMust("0.3")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := persistent.NewPaymentsService(env.Tx, persistent.WithFees(service.Fees{
+		Schedule:        schedule,
+		RevenueAccounts: map[money.Currency]entity.AccountID{"USD": "revenue"},
+	}))
+	srv := httptest.NewServer(NewAPIServer(svc))
+	defer srv.Close()
+
+	for _, id := range [...]entity.AccountID{"bob", "alice", "revenue"} {
+		err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	t.Run("fee is shown on payment", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/transfer", strings.NewReader(`{"from":"bob","to":"alice","amount":"10","currency":"USD"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+		req, _ = http.NewRequest("POST", srv.URL+"/payment/list", strings.NewReader(`{"account_id":"bob"}`))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Contains(t, string(body), `"amount":"10","currency":"USD","outgoing":true,"fee":"0.3","fee_account":"revenue"}`)
+	}))
+}
+
+func TestServer_AccountLimits(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	svc := persistent.NewPaymentsService(env.Tx)
+	srv := httptest.NewServer(NewAPIServer(svc))
+	defer srv.Close()
+	admin := httptest.NewServer(NewAdminServer(svc))
+	defer admin.Close()
+
+	for _, id := range [...]entity.AccountID{"bob", "alice"} {
+		err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	t.Run("payment exceeding limit", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", admin.URL+"/admin/account/limits/set", strings.NewReader(`{"account_id":"bob","max_amount":"50","daily_count":10}`))
+		resp, _ := http.DefaultClient.Do(req)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+		req, _ = http.NewRequest("POST", admin.URL+"/admin/account/limits/get", strings.NewReader(`{"account_id":"bob"}`))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"max_amount":"50","daily_count":10}`)
+
+		req, _ = http.NewRequest("POST", srv.URL+"/transfer", strings.NewReader(`{"from":"bob","to":"alice","amount":"60","currency":"USD"}`))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ = ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusConflict)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"limit_exceeded","message":"limit exceeded: max_amount of bob is 50","details":{"account":"bob","limit":"max_amount","max":"50"}}}`)
+	}))
+
+	t.Run("not served publicly", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/admin/account/limits/set", strings.NewReader(`{"account_id":"bob"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+	})
+}
+
+func TestServer_CreditLimit(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	svc := persistent.NewPaymentsService(env.Tx)
+	srv := httptest.NewServer(NewAPIServer(svc))
+	defer srv.Close()
+	admin := httptest.NewServer(NewAdminServer(svc))
+	defer admin.Close()
+
+	for _, id := range [...]entity.AccountID{"bob", "alice"} {
+		err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	t.Run("account goes into debt", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", admin.URL+"/admin/account/credit_limit", strings.NewReader(`{"account_id":"bob","credit_limit":"50"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"account_id":"bob","credit_limit":"50","unbounded":false}`)
+
+		req, _ = http.NewRequest("POST", srv.URL+"/transfer", strings.NewReader(`{"from":"bob","to":"alice","amount":"150","currency":"USD"}`))
+		resp, _ = http.DefaultClient.Do(req)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+		req, _ = http.NewRequest("POST", admin.URL+"/admin/account/credit_limit", strings.NewReader(`{"account_id":"bob","credit_limit":"0"}`))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ = ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusConflict)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"credit_limit_too_low","message":"credit limit is below account debt"}}`)
+	}))
+
+	t.Run("not served publicly", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/admin/account/credit_limit", strings.NewReader(`{"account_id":"bob","unbounded":true}`))
+		resp, _ := http.DefaultClient.Do(req)
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+	})
+}
+
+func TestServer_Statement(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	svc := persistent.NewPaymentsService(env.Tx)
+	srv := httptest.NewServer(NewAPIServer(svc))
+	defer srv.Close()
+
+	since := time.Now().UTC().Add(-time.Minute)
+	for _, id := range [...]entity.AccountID{"bob", "alice"} {
+		err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	paymentId, err := svc.Transfer(env.Ctx, "bob", "alice", money.NewNumericFromInt64(10), "USD", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	until := time.Now().UTC().Add(time.Minute)
+
+	t.Run("balance", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/account/balance", strings.NewReader(`{"account_id":"bob"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		var balance struct {
+			Balance  string `json:"balance"`
+			Currency string `json:"currency"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&balance)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, balance.Balance, "90")
+		assert.Equal(t, balance.Currency, "USD")
+	}))
+
+	t.Run("json", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		data, _ := json.Marshal(map[string]interface{}{"account_id": "bob", "since": since, "until": until})
+		req, _ := http.NewRequest("POST", srv.URL+"/account/statement", bytes.NewReader(data))
+		resp, _ := http.DefaultClient.Do(req)
+		var statement struct {
+			OpeningBalance string `json:"opening_balance"`
+			Lines          []struct {
+				PaymentId    entity.PaymentID `json:"payment_id"`
+				Counterparty string           `json:"counterparty"`
+				Amount       string           `json:"amount"`
+				Balance      string           `json:"balance"`
+			} `json:"lines"`
+			ClosingBalance string `json:"closing_balance"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&statement)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, statement.OpeningBalance, "0")
+		assert.Equal(t, statement.ClosingBalance, "90")
+		if assert.Len(t, statement.Lines, 2) {
+			assert.Equal(t, statement.Lines[0].Amount, "100")
+			assert.Equal(t, statement.Lines[1].PaymentId, paymentId)
+			assert.Equal(t, statement.Lines[1].Counterparty, "alice")
+			assert.Equal(t, statement.Lines[1].Amount, "-10")
+			assert.Equal(t, statement.Lines[1].Balance, "90")
+		}
+	}))
+
+	t.Run("csv", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		data, _ := json.Marshal(map[string]interface{}{"account_id": "alice", "since": since, "until": until})
+		req, _ := http.NewRequest("POST", srv.URL+"/account/statement.csv", bytes.NewReader(data))
+		resp, _ := http.DefaultClient.Do(req)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, resp.Header.Get("Content-Type"), "text/csv; charset=utf-8")
+
+		rows, err := csv.NewReader(resp.Body).ReadAll()
+		if assert.NoError(t, err) && assert.Len(t, rows, 5) {
+			assert.Equal(t, rows[0], []string{"time", "description", "payment_id", "counterparty", "amount", "balance", "currency"})
+			assert.Equal(t, rows[1][1:], []string{"opening balance", "", "", "", "0", "USD"})
+			assert.Equal(t, rows[2][1:], []string{"initial balance", "", "", "100", "100", "USD"})
+			assert.Equal(t, rows[3][1:], []string{"payment", strconv.FormatInt(int64(paymentId), 10), "bob", "10", "110", "USD"})
+			assert.Equal(t, rows[4][1:], []string{"closing balance", "", "", "", "110", "USD"})
+		}
 	}))
+
+	t.Run("bad period", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		data, _ := json.Marshal(map[string]interface{}{"account_id": "bob", "since": until, "until": since})
+		req, _ := http.NewRequest("POST", srv.URL+"/account/statement", bytes.NewReader(data))
+		resp, _ := http.DefaultClient.Do(req)
+		assert.Equal(t, resp.StatusCode, http.StatusUnprocessableEntity)
+	}))
+}
+
+func TestServer_Webhooks(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	svc := persistent.NewPaymentsService(env.Tx)
+	srv := httptest.NewServer(NewAPIServer(svc))
+	defer srv.Close()
+	admin := httptest.NewServer(NewAdminServer(svc))
+	defer admin.Close()
+
+	for _, id := range [...]entity.AccountID{"bob", "alice"} {
+		err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	t.Run("bad webhook", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", admin.URL+"/admin/webhook/create", strings.NewReader(`{"url":"partner.example","secret":"s3cret"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusUnprocessableEntity)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"bad_webhook","message":"bad webhook"}}`)
+	}))
+
+	t.Run("webhook lifecycle", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", admin.URL+"/admin/webhook/create", strings.NewReader(`{"url":"https://alice.example/hooks","secret":"s3cret","account_id":"alice","event_types":["PaymentCreated"]}`))
+		resp, _ := http.DefaultClient.Do(req)
+		var webhook struct {
+			Id         entity.WebhookID `json:"id"`
+			URL        string           `json:"url"`
+			Secret     string           `json:"secret"`
+			EventTypes []string         `json:"event_types"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&webhook)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.NotZero(t, webhook.Id)
+		assert.Equal(t, webhook.URL, "https://alice.example/hooks")
+		assert.Empty(t, webhook.Secret)
+		assert.Equal(t, webhook.EventTypes, []string{"PaymentCreated"})
+
+		req, _ = http.NewRequest("POST", admin.URL+"/admin/webhook/list", strings.NewReader(`{}`))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Contains(t, string(body), `"url":"https://alice.example/hooks"`)
+
+		paymentId, err := svc.Transfer(env.Ctx, "bob", "alice", money.NewNumericFromInt64(10), "USD", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = svc.RelayEvents(env.Ctx, 100, svc.EnqueueWebhookDeliveries)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, _ := json.Marshal(map[string]interface{}{"webhook_id": webhook.Id})
+		req, _ = http.NewRequest("POST", admin.URL+"/admin/webhook/deliveries", bytes.NewReader(data))
+		resp, _ = http.DefaultClient.Do(req)
+		var deliveries struct {
+			Deliveries []struct {
+				Id        entity.DeliveryID `json:"id"`
+				EventType string            `json:"event_type"`
+				Status    string            `json:"status"`
+				Payload   struct {
+					PaymentId entity.PaymentID `json:"payment_id"`
+				} `json:"payload"`
+			} `json:"deliveries"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&deliveries)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		if !assert.Len(t, deliveries.Deliveries, 1) {
+			return
+		}
+		delivery := deliveries.Deliveries[0]
+		assert.Equal(t, delivery.EventType, "PaymentCreated")
+		assert.Equal(t, delivery.Status, "pending")
+		assert.Equal(t, delivery.Payload.PaymentId, paymentId)
+
+		data, _ = json.Marshal(map[string]interface{}{"delivery_id": delivery.Id})
+		req, _ = http.NewRequest("POST", admin.URL+"/admin/webhook/deliveries/replay", bytes.NewReader(data))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ = ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Contains(t, string(body), `"status":"pending"`)
+
+		data, _ = json.Marshal(map[string]interface{}{"id": webhook.Id})
+		req, _ = http.NewRequest("POST", admin.URL+"/admin/webhook/delete", bytes.NewReader(data))
+		resp, _ = http.DefaultClient.Do(req)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+		data, _ = json.Marshal(map[string]interface{}{"delivery_id": delivery.Id})
+		req, _ = http.NewRequest("POST", admin.URL+"/admin/webhook/deliveries/replay", bytes.NewReader(data))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ = ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"delivery_not_found","message":"webhook delivery not found"}}`)
+	}))
+
+	t.Run("not served publicly", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/admin/webhook/create", strings.NewReader(`{"url":"https://attacker.example/hooks","secret":"s3cret"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+	})
+}
+
+func TestServer_ScheduledTransfers(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	svc := persistent.NewPaymentsService(env.Tx)
+	srv := httptest.NewServer(NewAPIServer(svc))
+	defer srv.Close()
+
+	for _, id := range [...]entity.AccountID{"bob", "alice"} {
+		err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	startAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+	t.Run("bad schedule", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/transfer/schedule", strings.NewReader(`{"from":"bob","to":"alice","amount":"10","currency":"USD","recurrence":"monthly"}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusUnprocessableEntity)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"bad_schedule","message":"bad schedule"}}`)
+	}))
+
+	t.Run("scheduled transfer lifecycle", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		data, _ := json.Marshal(map[string]interface{}{
+			"from":       "bob",
+			"to":         "alice",
+			"amount":     "10",
+			"currency":   "USD",
+			"recurrence": "daily",
+			"start_at":   startAt,
+			"end_at":     startAt.Add(24 * time.Hour),
+		})
+		req, _ := http.NewRequest("POST", srv.URL+"/transfer/schedule", bytes.NewReader(data))
+		resp, _ := http.DefaultClient.Do(req)
+		var st struct {
+			Id        entity.ScheduledTransferID `json:"id"`
+			Status    string                     `json:"status"`
+			NextRunAt time.Time                  `json:"next_run_at"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&st)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.NotZero(t, st.Id)
+		assert.Equal(t, st.Status, "active")
+		assert.True(t, st.NextRunAt.Equal(startAt))
+
+		n, err := svc.RunScheduledTransfers(env.Ctx, startAt.Add(48*time.Hour), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, n, 2)
+
+		data, _ = json.Marshal(map[string]interface{}{"id": st.Id})
+		req, _ = http.NewRequest("POST", srv.URL+"/transfer/schedule/runs", bytes.NewReader(data))
+		resp, _ = http.DefaultClient.Do(req)
+		var runs struct {
+			Runs []struct {
+				Status    string           `json:"status"`
+				PaymentId entity.PaymentID `json:"payment_id"`
+			} `json:"runs"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&runs)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		if assert.Len(t, runs.Runs, 2) {
+			for _, run := range runs.Runs {
+				assert.Equal(t, run.Status, "succeeded")
+				assert.NotZero(t, run.PaymentId)
+			}
+		}
+
+		req, _ = http.NewRequest("POST", srv.URL+"/transfer/schedule/list", strings.NewReader(`{"from":"bob"}`))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Contains(t, string(body), `"status":"completed"`)
+
+		req, _ = http.NewRequest("POST", srv.URL+"/transfer/schedule/cancel", bytes.NewReader(data))
+		resp, _ = http.DefaultClient.Do(req)
+		body, _ = ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusConflict)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"schedule_not_active","message":"scheduled transfer is already completed or cancelled"}}`)
+	}))
+
+	t.Run("unknown scheduled transfer", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/transfer/schedule/cancel", strings.NewReader(`{"id":1000000}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"schedule_not_found","message":"scheduled transfer not found"}}`)
+	}))
+}
+
+func TestServer_AuditLog(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	svc := audit.NewPaymentsService(persistent.NewPaymentsService(env.Tx), log.NewNopLogger())
+	srv := httptest.NewServer(NewAPIServer(svc))
+	defer srv.Close()
+	admin := httptest.NewServer(NewAdminServer(svc))
+	defer admin.Close()
+
+	t.Run("state-changing calls are audited", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/account/create", strings.NewReader(`{"id":"bob","currency":"USD","balance":"100"}`))
+		req.Header.Set(ActorHeader, "backoffice")
+		req.Header.Set(RequestIdHeader, "req-42")
+		resp, _ := http.DefaultClient.Do(req)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Equal(t, resp.Header.Get(RequestIdHeader), "req-42")
+
+		req, _ = http.NewRequest("POST", srv.URL+"/account/balance", strings.NewReader(`{"account_id":"bob"}`))
+		resp, _ = http.DefaultClient.Do(req)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		assert.Len(t, resp.Header.Get(RequestIdHeader), 32, "a request id is generated unless sent")
+
+		req, _ = http.NewRequest("POST", admin.URL+"/admin/audit_log", strings.NewReader(`{"claimed_actor":"backoffice"}`))
+		resp, _ = http.DefaultClient.Do(req)
+		var auditLog struct {
+			Entries []struct {
+				Actor     string          `json:"claimed_actor"`
+				RequestId string          `json:"request_id"`
+				Method    string          `json:"method"`
+				Params    json.RawMessage `json:"params"`
+				Error     string          `json:"error"`
+			} `json:"entries"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&auditLog)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		if assert.Len(t, auditLog.Entries, 1, "reads aren't audited") {
+			e := auditLog.Entries[0]
+			assert.Equal(t, e.Actor, "backoffice")
+			assert.Equal(t, e.RequestId, "req-42")
+			assert.Equal(t, e.Method, "CreateAccount")
+			assert.JSONEq(t, `{"id":"bob","balance":"100","currency":"USD"}`, string(e.Params))
+			assert.Empty(t, e.Error)
+		}
+	}))
+
+	t.Run("bad query", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		req, _ := http.NewRequest("POST", admin.URL+"/admin/audit_log", strings.NewReader(`{"limit":-1}`))
+		resp, _ := http.DefaultClient.Do(req)
+		body, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, resp.StatusCode, http.StatusUnprocessableEntity)
+		assert.Equal(t, strings.TrimSpace(string(body)), `{"error":{"code":"bad_query","message":"bad query"}}`)
+	}))
+
+	t.Run("not served publicly", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", srv.URL+"/admin/audit_log", strings.NewReader(`{}`))
+		resp, _ := http.DefaultClient.Do(req)
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+	})
+}
+
+func TestServer_Metrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	srv := httptest.NewServer(NewAPIServer(memory.NewPaymentsService(), WithMetrics(NewPrometheusHTTPMetrics(reg))))
+	defer srv.Close()
+
+	for _, body := range []string{`{"id":"bob","currency":"USD","balance":"100"}`, `{"id":"bob","currency":"USD","balance":"100"}`} {
+		req, _ := http.NewRequest("POST", srv.URL+"/account/create", strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	expected := `
+# HELP payments_http_requests_total Number of HTTP requests.
+# TYPE payments_http_requests_total counter
+payments_http_requests_total{code="200",method="POST",route="/account/create"} 1
+payments_http_requests_total{code="409",method="POST",route="/account/create"} 1
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "payments_http_requests_total")
+	assert.NoError(t, err)
+}
+
+func TestServer_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	srv := httptest.NewServer(NewAPIServer(tracing.NewPaymentsService(memory.NewPaymentsService(), tp), WithTracing(tp)))
+	defer srv.Close()
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req, _ := http.NewRequest("POST", srv.URL+"/account/create", strings.NewReader(`{"id":"bob","currency":"USD","balance":"100"}`))
+	req.Header.Set("traceparent", traceparent)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+	svcSpan, httpSpan := spans[0], spans[1]
+	assert.Equal(t, "PaymentsService.CreateAccount", svcSpan.Name())
+	assert.Equal(t, "POST /account/create", httpSpan.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", httpSpan.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", httpSpan.Parent().SpanID().String(), "the caller's span is the parent")
+	assert.True(t, httpSpan.Parent().IsRemote())
+	assert.Equal(t, httpSpan.SpanContext().SpanID(), svcSpan.Parent().SpanID(), "service calls are children of requests")
+	assert.Contains(t, httpSpan.Attributes(), attribute.Int("http.status_code", http.StatusOK))
+}
+
+func TestServer_Health(t *testing.T) {
+	var dbErr error
+	var logs bytes.Buffer
+	srv := httptest.NewServer(NewAPIServer(memory.NewPaymentsService(),
+		WithLogger(log.NewLogfmtLogger(&logs)),
+		WithReadinessCheck("postgres", func(ctx context.Context) error { return dbErr }),
+		WithReadinessCheck("migrations", func(ctx context.Context) error { return nil }),
+	))
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(body))
+	}
+
+	code, body := get("/healthz")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, body, `{"status":"ok"}`)
+
+	code, body = get("/readyz")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, body, `{"status":"ok"}`)
+
+	dbErr = errors.New("dial tcp: connection refused")
+	code, body = get("/readyz")
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	assert.Equal(t, body, `{"status":"unavailable","failed":["postgres"]}`, "errors aren't exposed")
+	assert.Contains(t, logs.String(), `check=postgres err="dial tcp: connection refused"`)
+	assert.NotContains(t, logs.String(), "/healthz", "probes aren't logged")
+
+	code, _ = get("/account/create")
+	assert.Equal(t, code, http.StatusMethodNotAllowed, "API routes are matched as usual")
 }
@@ -0,0 +1,59 @@
+package set_account_limits
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"net/http"
+)
+
+// setAccountLimitsRequest replaces all the limits of an account, omitted ones are removed.
+type setAccountLimitsRequest struct {
+	AccountId     entity.AccountID `json:"account_id"`
+	MaxAmount     *money.Numeric   `json:"max_amount"`
+	DailyAmount   *money.Numeric   `json:"daily_amount"`
+	MonthlyAmount *money.Numeric   `json:"monthly_amount"`
+	DailyCount    *int             `json:"daily_count"`
+	MonthlyCount  *int             `json:"monthly_count"`
+}
+
+type setAccountLimitsResponse struct {
+}
+
+func setAccountLimitsEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(setAccountLimitsRequest)
+		err := svc.SetAccountLimits(ctx, req.AccountId, entity.AccountLimits{
+			MaxAmount:     req.MaxAmount,
+			DailyAmount:   req.DailyAmount,
+			MonthlyAmount: req.MonthlyAmount,
+			DailyCount:    req.DailyCount,
+			MonthlyCount:  req.MonthlyCount,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return setAccountLimitsResponse{}, nil
+	}
+}
+
+func decodeSetAccountLimitsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request setAccountLimitsRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		setAccountLimitsEndpoint(svc),
+		decodeSetAccountLimitsRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
@@ -0,0 +1,55 @@
+package set_account_status
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"net/http"
+)
+
+type setAccountStatusRequest struct {
+	AccountId entity.AccountID     `json:"account_id"`
+	Status    entity.AccountStatus `json:"status"`
+	Reason    string               `json:"reason"`
+}
+
+type setAccountStatusResponse struct {
+	AccountId entity.AccountID     `json:"account_id"`
+	Status    entity.AccountStatus `json:"status"`
+	Reason    string               `json:"reason"`
+}
+
+func setAccountStatusEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(setAccountStatusRequest)
+		account, err := svc.SetAccountStatus(ctx, req.AccountId, req.Status, req.Reason)
+		if err != nil {
+			return nil, err
+		}
+		return setAccountStatusResponse{
+			AccountId: account.Id,
+			Status:    account.Status,
+			Reason:    account.StatusReason,
+		}, nil
+	}
+}
+
+func decodeSetAccountStatusRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request setAccountStatusRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		setAccountStatusEndpoint(svc),
+		decodeSetAccountStatusRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
@@ -0,0 +1,56 @@
+package set_credit_limit
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"net/http"
+)
+
+type setCreditLimitRequest struct {
+	AccountId   entity.AccountID `json:"account_id"`
+	CreditLimit money.Numeric    `json:"credit_limit"`
+	Unbounded   bool             `json:"unbounded"`
+}
+
+type setCreditLimitResponse struct {
+	AccountId   entity.AccountID `json:"account_id"`
+	CreditLimit money.Numeric    `json:"credit_limit"`
+	Unbounded   bool             `json:"unbounded"`
+}
+
+func setCreditLimitEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(setCreditLimitRequest)
+		account, err := svc.SetCreditLimit(ctx, req.AccountId, req.CreditLimit, req.Unbounded)
+		if err != nil {
+			return nil, err
+		}
+		return setCreditLimitResponse{
+			AccountId:   account.Id,
+			CreditLimit: account.CreditLimit,
+			Unbounded:   account.Unbounded,
+		}, nil
+	}
+}
+
+func decodeSetCreditLimitRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request setCreditLimitRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		setCreditLimitEndpoint(svc),
+		decodeSetCreditLimitRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
@@ -2,7 +2,6 @@ package transfer
 
 import (
 	"context"
-	"encoding/json"
 	"github.com/go-kit/kit/endpoint"
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/lightsgoout/fintech-go/payments/api/common"
@@ -12,47 +11,56 @@ import (
 	"net/http"
 )
 
-type transferRequest struct {
-	From     entity.AccountID `json:"from"`
-	To       entity.AccountID `json:"to"`
-	Amount   float32          `json:"amount"`
-	Currency string           `json:"currency"`
+// idempotencyKeyHeader may carry the idempotency key instead of the request body.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+type TransferRequest struct {
+	From           entity.AccountID      `json:"from"`
+	To             entity.AccountID      `json:"to"`
+	Amount         money.Numeric         `json:"amount"`
+	Currency       string                `json:"currency"`
+	IdempotencyKey entity.IdempotencyKey `json:"idempotency_key"`
 }
 
-type transferResponse struct {
+type TransferResponse struct {
 	PaymentId entity.PaymentID `json:"payment_id,omitempty"`
-	Err       string           `json:"err,omitempty"`
 }
 
-func transferEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+// TransferEndpoint is served both over HTTP and gRPC, see payments/api/grpc.
+func TransferEndpoint(svc service.PaymentsService) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		req := request.(transferRequest)
+		req := request.(TransferRequest)
 		paymentId, err := svc.Transfer(
 			ctx,
 			req.From,
 			req.To,
-			money.NewNumericFromFloat32(req.Amount),
+			req.Amount,
 			money.NewCurrency(req.Currency),
+			req.IdempotencyKey,
 		)
 		if err != nil {
-			return transferResponse{0, err.Error()}, nil
+			return nil, err
 		}
-		return transferResponse{paymentId, ""}, nil
+		return TransferResponse{paymentId}, nil
 	}
 }
 
 func decodeTransferRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	var request transferRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+	var request TransferRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
 		return nil, err
 	}
+	if request.IdempotencyKey == "" {
+		request.IdempotencyKey = entity.IdempotencyKey(r.Header.Get(idempotencyKeyHeader))
+	}
 	return request, nil
 }
 
 func Server(svc service.PaymentsService) *httptransport.Server {
 	return httptransport.NewServer(
-		transferEndpoint(svc),
+		TransferEndpoint(svc),
 		decodeTransferRequest,
 		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
 	)
 }
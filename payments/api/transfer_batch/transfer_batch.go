@@ -0,0 +1,65 @@
+package transfer_batch
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"net/http"
+)
+
+type inLeg struct {
+	From     entity.AccountID `json:"from"`
+	To       entity.AccountID `json:"to"`
+	Amount   money.Numeric    `json:"amount"`
+	Currency money.Currency   `json:"currency"`
+}
+
+type transferBatchRequest struct {
+	Legs []inLeg `json:"legs"`
+}
+
+type transferBatchResponse struct {
+	// PaymentIds are ids of payments made by the legs, in the same order
+	PaymentIds []entity.PaymentID `json:"payment_ids"`
+}
+
+func transferBatchEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(transferBatchRequest)
+		legs := make([]service.TransferLeg, 0, len(req.Legs))
+		for _, l := range req.Legs {
+			legs = append(legs, service.TransferLeg{
+				From:     l.From,
+				To:       l.To,
+				Amount:   l.Amount,
+				Currency: l.Currency,
+			})
+		}
+		paymentIds, err := svc.TransferBatch(ctx, legs)
+		if err != nil {
+			return nil, err
+		}
+		return transferBatchResponse{paymentIds}, nil
+	}
+}
+
+func decodeTransferBatchRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request transferBatchRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		transferBatchEndpoint(svc),
+		decodeTransferBatchRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
@@ -0,0 +1,65 @@
+package transfer_with_quote
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"net/http"
+)
+
+// idempotencyKeyHeader may carry the idempotency key instead of the request body.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+type transferWithQuoteRequest struct {
+	From           entity.AccountID      `json:"from"`
+	To             entity.AccountID      `json:"to"`
+	Amount         money.Numeric         `json:"amount"`
+	QuoteId        entity.QuoteID        `json:"quote_id"`
+	IdempotencyKey entity.IdempotencyKey `json:"idempotency_key"`
+}
+
+type transferWithQuoteResponse struct {
+	PaymentId entity.PaymentID `json:"payment_id,omitempty"`
+}
+
+func transferWithQuoteEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(transferWithQuoteRequest)
+		paymentId, err := svc.TransferWithQuote(
+			ctx,
+			req.From,
+			req.To,
+			req.Amount,
+			req.QuoteId,
+			req.IdempotencyKey,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return transferWithQuoteResponse{paymentId}, nil
+	}
+}
+
+func decodeTransferWithQuoteRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request transferWithQuoteRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	if request.IdempotencyKey == "" {
+		request.IdempotencyKey = entity.IdempotencyKey(r.Header.Get(idempotencyKeyHeader))
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		transferWithQuoteEndpoint(svc),
+		decodeTransferWithQuoteRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
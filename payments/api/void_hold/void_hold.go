@@ -0,0 +1,44 @@
+package void_hold
+
+import (
+	"context"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/lightsgoout/fintech-go/payments/api/common"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"net/http"
+)
+
+type voidHoldRequest struct {
+	HoldId entity.HoldID `json:"hold_id"`
+}
+
+type voidHoldResponse struct{}
+
+func voidHoldEndpoint(svc service.PaymentsService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(voidHoldRequest)
+		if err := svc.Void(ctx, req.HoldId); err != nil {
+			return nil, err
+		}
+		return voidHoldResponse{}, nil
+	}
+}
+
+func decodeVoidHoldRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	var request voidHoldRequest
+	if err := common.DecodeJSON(r, &request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func Server(svc service.PaymentsService) *httptransport.Server {
+	return httptransport.NewServer(
+		voidHoldEndpoint(svc),
+		decodeVoidHoldRequest,
+		common.EncodeResponse,
+		httptransport.ServerErrorEncoder(common.EncodeError),
+	)
+}
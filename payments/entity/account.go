@@ -6,8 +6,54 @@ import (
 
 type AccountID string
 
+// AccountStatus tells which operations an account may take part in.
+type AccountStatus string
+
+const (
+	// AccountActive account can both send and receive money
+	AccountActive AccountStatus = "active"
+
+	// AccountFrozenDebit account can receive money, but can't send it
+	AccountFrozenDebit AccountStatus = "frozen_debit"
+
+	// AccountFrozenAll account can neither send nor receive money
+	AccountFrozenAll AccountStatus = "frozen_all"
+
+	// AccountClosed account can neither send nor receive money, ever again
+	AccountClosed AccountStatus = "closed"
+)
+
+// IsValid reports whether s is one of the known statuses.
+func (s AccountStatus) IsValid() bool {
+	switch s {
+	case AccountActive, AccountFrozenDebit, AccountFrozenAll, AccountClosed:
+		return true
+	}
+	return false
+}
+
+// CanDebit reports whether money may be taken from an account having this status.
+func (s AccountStatus) CanDebit() bool {
+	return s == AccountActive
+}
+
+// CanCredit reports whether money may be put to an account having this status.
+func (s AccountStatus) CanCredit() bool {
+	return s == AccountActive || s == AccountFrozenDebit
+}
+
 type Account struct {
 	Id       AccountID
 	Balance  money.Numeric
 	Currency money.Currency
+	Status   AccountStatus
+
+	// StatusReason explains why the account got its current status, empty for new accounts
+	StatusReason string
+
+	// CreditLimit is how far below zero the balance may go, zero for debit-only accounts
+	CreditLimit money.Numeric
+
+	// Unbounded accounts, e.g. system or treasury ones, may have any negative balance regardless of CreditLimit
+	Unbounded bool
 }
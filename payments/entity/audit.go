@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditEntryID int64
+
+// AuditEntry records a call which changes the state of the service, whether it succeeded or not.
+// Entries are never changed once appended.
+type AuditEntry struct {
+	// Id grows in order entries were appended
+	Id AuditEntryID
+
+	Time time.Time
+
+	// Actor is who made the call as claimed by the caller, it's never verified. Empty if unknown
+	Actor string
+
+	// RequestId ties the entry to log records of the request which made the call
+	RequestId string
+
+	// Method is the name of the called method of PaymentsService, e.g. Transfer
+	Method string
+
+	// Params is JSON object with parameters of the call
+	Params json.RawMessage
+
+	// Error describes why the call failed, empty if it succeeded
+	Error string
+}
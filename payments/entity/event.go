@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type EventID int64
+
+// EventType tells what happened and which payload an Event carries.
+type EventType string
+
+const (
+	// EventAccountCreated is emitted by CreateAccount
+	EventAccountCreated EventType = "AccountCreated"
+
+	// EventPaymentCreated is emitted by every operation moving money: transfers, captures and refunds
+	EventPaymentCreated EventType = "PaymentCreated"
+)
+
+// Event notifies downstream systems about a change. Events are recorded in the same transaction
+// as the change itself and published later, at least once, so consumers should deduplicate them by Id.
+type Event struct {
+	// Id grows in order events were recorded
+	Id EventID
+
+	Time time.Time
+
+	Type EventType
+
+	// Payload is JSON describing the change, its schema depends on Type
+	Payload json.RawMessage
+}
@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+type HoldID int64
+
+// HoldStatus is a stage of Hold's lifecycle.
+type HoldStatus string
+
+const (
+	// HoldActive means money is reserved and can be captured
+	HoldActive HoldStatus = "active"
+
+	// HoldCaptured means (a part of) reserved money was transferred, the rest is released
+	HoldCaptured HoldStatus = "captured"
+
+	// HoldVoided means reserved money was released without a transfer
+	HoldVoided HoldStatus = "voided"
+
+	// HoldExpired means the hold wasn't captured in time and reserved money was released
+	HoldExpired HoldStatus = "expired"
+)
+
+// Hold reserves money on an Account for a future transfer to another one,
+// like a card authorization does.
+type Hold struct {
+	Id HoldID
+
+	// From is AccountID money is reserved on
+	From AccountID
+
+	// To is AccountID money is going to be transferred to
+	To AccountID
+
+	// Amount is amount of money reserved
+	Amount money.Numeric
+
+	Currency money.Currency
+
+	Status HoldStatus
+
+	CreatedAt time.Time
+
+	// ExpiresAt is a moment reserved money is released at unless captured
+	ExpiresAt time.Time
+
+	// Payment is the payment made by capturing the hold, zero unless the hold is captured
+	Payment PaymentID
+}
+
+// StatusAt returns status of the hold at the given moment, taking expiration into account.
+func (h Hold) StatusAt(t time.Time) HoldStatus {
+	if h.Status == HoldActive && !t.Before(h.ExpiresAt) {
+		return HoldExpired
+	}
+	return h.Status
+}
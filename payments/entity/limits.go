@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"github.com/lightsgoout/fintech-go/pkg/money"
+)
+
+// Limit names a restriction of outgoing payments of an account.
+type Limit string
+
+const (
+	LimitMaxAmount     Limit = "max_amount"
+	LimitDailyAmount   Limit = "daily_amount"
+	LimitMonthlyAmount Limit = "monthly_amount"
+	LimitDailyCount    Limit = "daily_count"
+	LimitMonthlyCount  Limit = "monthly_count"
+)
+
+// AccountLimits restrict outgoing payments of an account, nil limits are not enforced.
+// Daily and monthly windows are calendar days and months in UTC.
+type AccountLimits struct {
+	// MaxAmount is the largest amount of a single payment
+	MaxAmount *money.Numeric
+
+	// DailyAmount and MonthlyAmount limit total amount of payments within a window
+	DailyAmount   *money.Numeric
+	MonthlyAmount *money.Numeric
+
+	// DailyCount and MonthlyCount limit number of payments within a window
+	DailyCount   *int
+	MonthlyCount *int
+}
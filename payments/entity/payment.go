@@ -7,12 +7,19 @@ import (
 
 type PaymentID int64
 
+// IdempotencyKey is a client-supplied token which makes retried transfers safe:
+// a replay carrying the same key results in the same Payment instead of a new one.
+type IdempotencyKey string
+
 // Payment describes a transfer of money between one Account and another
 type Payment struct {
 	// Id is a unique ID of this payment
 	Id PaymentID
 
 	Value PaymentValue
+
+	// RefundedBy are refunds of this payment, in order they were made
+	RefundedBy []PaymentID
 }
 
 type PaymentValue struct {
@@ -31,4 +38,46 @@ type PaymentValue struct {
 	Currency money.Currency
 
 	Outgoing bool
+
+	// exchange describes currency conversion of a cross-currency payment, nil otherwise
+	Exchange *Exchange
+
+	// refundOf is a payment this one (partially) refunds, zero otherwise
+	RefundOf PaymentID
+
+	// fee is charged from the sender on top of amount, in the same currency, zero if the payment was free
+	Fee money.Numeric
+
+	// feeAccount is AccountID to which the fee was transferred
+	FeeAccount AccountID
+}
+
+// Exchange describes how a payment was converted from the sender's currency to the receiver's one
+type Exchange struct {
+	// quote is the Quote the rate was taken from
+	Quote QuoteID
+
+	// rate is how many units of ToCurrency one unit of payment currency buys
+	Rate money.Numeric
+
+	// toAmount is amount of money received
+	ToAmount money.Numeric
+
+	ToCurrency money.Currency
+}
+
+// CreditAmount returns amount of money received by the To account.
+func (v PaymentValue) CreditAmount() money.Numeric {
+	if v.Exchange != nil {
+		return v.Exchange.ToAmount
+	}
+	return v.Amount
+}
+
+// CreditCurrency returns currency of money received by the To account.
+func (v PaymentValue) CreditCurrency() money.Currency {
+	if v.Exchange != nil {
+		return v.Exchange.ToCurrency
+	}
+	return v.Currency
 }
@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+type QuoteID int64
+
+// Quote is a currency exchange rate locked for a short period of time
+type Quote struct {
+	Id QuoteID
+
+	// From is a currency being sold
+	From money.Currency
+
+	// To is a currency being bought
+	To money.Currency
+
+	// Rate is how many units of To one unit of From buys
+	Rate money.Numeric
+
+	// ExpiresAt is a moment after which the quote can't be used anymore
+	ExpiresAt time.Time
+}
@@ -0,0 +1,110 @@
+package entity
+
+import (
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+type ScheduledTransferID int64
+
+// Recurrence tells how often a ScheduledTransfer is made.
+type Recurrence string
+
+const (
+	// RecurrenceOnce is a transfer made once at ScheduledTransfer.StartAt
+	RecurrenceOnce Recurrence = "once"
+
+	// RecurrenceDaily is a transfer made every day at the time of ScheduledTransfer.StartAt
+	RecurrenceDaily Recurrence = "daily"
+
+	// RecurrenceWeekly is a transfer made every week on the weekday and at the time of ScheduledTransfer.StartAt
+	RecurrenceWeekly Recurrence = "weekly"
+
+	// RecurrenceMonthly is a transfer made every month on ScheduledTransfer.DayOfMonth,
+	// or on the last day of shorter months, at the time of ScheduledTransfer.StartAt
+	RecurrenceMonthly Recurrence = "monthly"
+)
+
+func (r Recurrence) IsValid() bool {
+	switch r {
+	case RecurrenceOnce, RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly:
+		return true
+	}
+	return false
+}
+
+// ScheduleStatus tells whether a ScheduledTransfer is going to be made again.
+type ScheduleStatus string
+
+const (
+	// ScheduleActive is a transfer waiting for its next run
+	ScheduleActive ScheduleStatus = "active"
+
+	// ScheduleCompleted is a transfer which had its last run
+	ScheduleCompleted ScheduleStatus = "completed"
+
+	// ScheduleCancelled is a transfer cancelled before its last run
+	ScheduleCancelled ScheduleStatus = "cancelled"
+)
+
+// ScheduledTransfer is a transfer made at a future time, once or repeatedly.
+type ScheduledTransfer struct {
+	Id ScheduledTransferID
+
+	From AccountID
+
+	To AccountID
+
+	Amount money.Numeric
+
+	Currency money.Currency
+
+	Recurrence Recurrence
+
+	// DayOfMonth is the day monthly transfers are made on, from 1 to 31, zero for other recurrences
+	DayOfMonth int
+
+	// StartAt is the earliest time the transfer is made at
+	StartAt time.Time
+
+	// EndAt is the latest time the transfer is made at, zero if it recurs forever
+	EndAt time.Time
+
+	Status ScheduleStatus
+
+	// NextRunAt is when an active transfer is made next time
+	NextRunAt time.Time
+
+	CreatedAt time.Time
+}
+
+type ScheduledRunID int64
+
+// RunStatus is an outcome of a ScheduledRun.
+type RunStatus string
+
+const (
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+)
+
+// ScheduledRun is an attempt to make a ScheduledTransfer.
+type ScheduledRun struct {
+	Id ScheduledRunID
+
+	Schedule ScheduledTransferID
+
+	// ScheduledAt is the occurrence of the transfer the run was made for
+	ScheduledAt time.Time
+
+	// RanAt is when the run was actually made
+	RanAt time.Time
+
+	Status RunStatus
+
+	// Payment is the payment made by a succeeded run
+	Payment PaymentID
+
+	// Error describes why a run failed
+	Error string
+}
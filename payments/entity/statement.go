@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+// Balance is the balance of an account at some moment.
+type Balance struct {
+	Account AccountID
+
+	// At is the moment of the balance, payments made at At are included
+	At time.Time
+
+	Amount money.Numeric
+
+	Currency money.Currency
+}
+
+// Statement lists all balance changes of an account over a period.
+type Statement struct {
+	Account AccountID
+
+	Currency money.Currency
+
+	// Since is an inclusive start of the period
+	Since time.Time
+
+	// Until is an exclusive end of the period
+	Until time.Time
+
+	// OpeningBalance is the balance right before Since
+	OpeningBalance money.Numeric
+
+	// Lines are in chronological order
+	Lines []StatementLine
+
+	// ClosingBalance is the balance right before Until
+	ClosingBalance money.Numeric
+}
+
+// StatementLine is a single balance change of an account.
+type StatementLine struct {
+	Time time.Time
+
+	// Payment is a payment which changed the balance, zero for initial balance of the account
+	Payment PaymentID
+
+	// Counterparty is the other side of the payment, empty for initial balance
+	Counterparty AccountID
+
+	// Amount is positive for credits and negative for debits, fees included
+	Amount money.Numeric
+
+	// Balance is the balance right after the change
+	Balance money.Numeric
+}
@@ -0,0 +1,73 @@
+package entity
+
+import "time"
+
+type WebhookID int64
+
+// Webhook is a subscription of an HTTP endpoint to events.
+type Webhook struct {
+	Id WebhookID
+
+	// URL receives events as signed POST requests
+	URL string
+
+	// Secret signs requests, see pkg/signature
+	Secret string
+
+	// Account limits events to ones concerning the account, e.g. payments it sends or receives, all events if empty
+	Account AccountID
+
+	// EventTypes limits events to ones of the listed types, all events if empty
+	EventTypes []EventType
+
+	CreatedAt time.Time
+}
+
+type DeliveryID int64
+
+// DeliveryStatus tells whether an event has reached a Webhook.
+type DeliveryStatus string
+
+const (
+	// DeliveryPending is an event waiting for the first or the next attempt
+	DeliveryPending DeliveryStatus = "pending"
+
+	// DeliveryDelivered is an event the receiver has acknowledged
+	DeliveryDelivered DeliveryStatus = "delivered"
+
+	// DeliveryDead is an event all attempts to deliver failed, it may be replayed manually
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+func (s DeliveryStatus) IsValid() bool {
+	switch s {
+	case DeliveryPending, DeliveryDelivered, DeliveryDead:
+		return true
+	}
+	return false
+}
+
+// WebhookDelivery is an event to be sent to a Webhook.
+type WebhookDelivery struct {
+	Id DeliveryID
+
+	Webhook WebhookID
+
+	Event Event
+
+	Status DeliveryStatus
+
+	// Attempts is how many times the event was sent, unsuccessfully unless it's delivered
+	Attempts int
+
+	// NextAttemptAt is when a pending event is sent next time
+	NextAttemptAt time.Time
+
+	// LastError describes why the latest attempt failed
+	LastError string
+
+	// DeliveredAt is zero unless the event is delivered
+	DeliveredAt time.Time
+
+	CreatedAt time.Time
+}
@@ -0,0 +1,6 @@
+drop table journal_entry;
+drop function journal_entry_immutable();
+drop table payment;
+drop table fx_quote;
+drop table account;
+drop table currency;
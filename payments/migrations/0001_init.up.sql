@@ -0,0 +1,87 @@
+create table currency
+(
+    code         text PRIMARY KEY,
+    numeric_code smallint not null,
+    minor_units  smallint not null,
+    symbol       text     not null,
+    CHECK (code ~ '^[A-Z]{3}$'),
+    CHECK (minor_units >= 0)
+);
+
+insert into currency (code, numeric_code, minor_units, symbol)
+values ('USD', 840, 2, '$'),
+       ('EUR', 978, 2, '€'),
+       ('RUB', 643, 2, '₽');
+
+create table account
+(
+    id       text PRIMARY KEY,
+    currency text     not null references currency (code) on delete restrict,
+    balance  numeric,
+    CHECK (balance >= 0),
+    CHECK (id <> '')
+);
+
+create table fx_quote
+(
+    id            bigserial PRIMARY KEY,
+    from_currency text                     not null references currency (code) on delete restrict,
+    to_currency   text                     not null references currency (code) on delete restrict,
+    rate          numeric                  not null,
+    expires_at    timestamp with time zone not null,
+    CHECK (rate > 0),
+    CHECK (from_currency <> to_currency)
+);
+
+create table payment
+(
+    id              bigserial PRIMARY KEY,
+    time            timestamp with time zone,
+    from_account_id text     not null references account (id) on delete restrict deferrable,
+    to_account_id   text     not null references account (id) on delete restrict deferrable,
+    currency        text     not null references currency (code) on delete restrict,
+    amount          numeric  not null,
+    idempotency_key text,
+    fx_quote_id     bigint references fx_quote (id) on delete restrict,
+    fx_rate         numeric,
+    to_amount       numeric,
+    to_currency     text references currency (code) on delete restrict,
+    CHECK (amount > 0),
+    CHECK (idempotency_key <> ''),
+    CHECK ((fx_quote_id IS NULL) = (fx_rate IS NULL) AND
+           (fx_quote_id IS NULL) = (to_amount IS NULL) AND
+           (fx_quote_id IS NULL) = (to_currency IS NULL)),
+    CHECK (to_amount > 0)
+);
+
+create table journal_entry
+(
+    id         bigserial PRIMARY KEY,
+    time       timestamp with time zone not null,
+    account_id text                     not null references account (id) on delete restrict,
+    payment_id bigint references payment (id) on delete restrict,
+    amount     numeric                  not null,
+    currency   text                     not null references currency (code) on delete restrict,
+    CHECK (amount <> 0)
+);
+
+-- Journal is append-only: balances must always be provable from it
+create function journal_entry_immutable() returns trigger as
+$$
+begin
+    raise exception 'journal entries are immutable';
+end;
+$$ language plpgsql;
+
+create trigger journal_entry_immutable
+    before update or delete
+    on journal_entry
+    for each row
+execute function journal_entry_immutable();
+
+create index on payment using btree (from_account_id, time desc);
+create index on payment using btree (to_account_id, time desc);
+create unique index on payment using btree (idempotency_key);
+create index on account using hash (currency);
+create index on journal_entry using btree (account_id, id);
+create index on journal_entry using btree (payment_id);
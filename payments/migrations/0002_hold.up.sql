@@ -0,0 +1,19 @@
+create table hold
+(
+    id              bigserial PRIMARY KEY,
+    from_account_id text                     not null references account (id) on delete restrict,
+    to_account_id   text                     not null references account (id) on delete restrict,
+    amount          numeric                  not null,
+    currency        text                     not null references currency (code) on delete restrict,
+    -- Expired holds stay 'active', expires_at tells them apart
+    status          text                     not null,
+    created_at      timestamp with time zone not null,
+    expires_at      timestamp with time zone not null,
+    payment_id      bigint references payment (id) on delete restrict,
+    CHECK (amount > 0),
+    CHECK (from_account_id <> to_account_id),
+    CHECK (status in ('active', 'captured', 'voided')),
+    CHECK ((status = 'captured') = (payment_id IS NOT NULL))
+);
+
+create index on hold using btree (from_account_id, expires_at) where status = 'active';
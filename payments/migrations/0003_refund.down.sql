@@ -0,0 +1,2 @@
+alter table payment
+    drop column refund_of;
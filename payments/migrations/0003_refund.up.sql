@@ -0,0 +1,5 @@
+alter table payment
+    add column refund_of bigint references payment (id) on delete restrict,
+    add CHECK (refund_of <> id);
+
+create index on payment using btree (refund_of) where refund_of IS NOT NULL;
@@ -0,0 +1,3 @@
+alter table account
+    drop column status_reason,
+    drop column status;
@@ -0,0 +1,5 @@
+alter table account
+    add column status        text not null default 'active',
+    add column status_reason text not null default '',
+    add CHECK (status in ('active', 'frozen_debit', 'frozen_all', 'closed')),
+    add CHECK (status <> 'closed' OR balance = 0);
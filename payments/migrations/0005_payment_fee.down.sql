@@ -0,0 +1,3 @@
+alter table payment
+    drop column fee_account_id,
+    drop column fee;
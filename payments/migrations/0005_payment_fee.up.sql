@@ -0,0 +1,5 @@
+alter table payment
+    add column fee            numeric,
+    add column fee_account_id text references account (id) on delete restrict,
+    add CHECK (fee > 0),
+    add CHECK ((fee IS NULL) = (fee_account_id IS NULL));
@@ -0,0 +1,12 @@
+-- Limits of outgoing payments of an account, NULL means no limit
+create table account_limit
+(
+    account_id     text PRIMARY KEY references account (id) on delete restrict,
+    max_amount     numeric,
+    daily_amount   numeric,
+    monthly_amount numeric,
+    daily_count    integer,
+    monthly_count  integer,
+    CHECK (max_amount >= 0 AND daily_amount >= 0 AND monthly_amount >= 0),
+    CHECK (daily_count >= 0 AND monthly_count >= 0)
+);
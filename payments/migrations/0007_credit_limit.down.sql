@@ -0,0 +1,5 @@
+alter table account
+    drop constraint account_balance_check,
+    add constraint account_balance_check CHECK (balance >= 0),
+    drop column unbounded,
+    drop column credit_limit;
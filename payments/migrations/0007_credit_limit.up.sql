@@ -0,0 +1,6 @@
+alter table account
+    add column credit_limit numeric not null default 0,
+    add column unbounded    boolean not null default false,
+    add CHECK (credit_limit >= 0),
+    drop constraint account_balance_check,
+    add constraint account_balance_check CHECK (unbounded OR balance >= -credit_limit);
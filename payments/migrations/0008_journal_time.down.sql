@@ -0,0 +1 @@
+drop index journal_entry_account_id_time_idx;
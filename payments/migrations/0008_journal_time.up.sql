@@ -0,0 +1,2 @@
+-- Historical balances and statements select entries of an account by time
+create index journal_entry_account_id_time_idx on journal_entry using btree (account_id, time);
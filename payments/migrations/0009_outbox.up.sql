@@ -0,0 +1,11 @@
+-- Events recorded in the same transaction as changes they describe, see PaymentsService.RelayEvents
+create table outbox
+(
+    id           bigserial PRIMARY KEY,
+    time         timestamptz NOT NULL,
+    type         text        NOT NULL,
+    payload      jsonb       NOT NULL,
+    published_at timestamptz
+);
+
+create index outbox_pending_idx on outbox (id) where published_at is null;
@@ -0,0 +1,31 @@
+-- Subscriptions of HTTP endpoints to events, empty account_id and event_types match everything
+create table webhook
+(
+    id          bigserial PRIMARY KEY,
+    url         text        NOT NULL,
+    secret      text        NOT NULL,
+    account_id  text references account (id) on delete restrict,
+    event_types text[]      NOT NULL DEFAULT '{}',
+    created_at  timestamptz NOT NULL
+);
+
+-- Events to be sent to webhooks, with the state of retries
+create table webhook_delivery
+(
+    id              bigserial PRIMARY KEY,
+    webhook_id      bigint      NOT NULL references webhook (id) on delete cascade,
+    event_id        bigint      NOT NULL,
+    event_time      timestamptz NOT NULL,
+    event_type      text        NOT NULL,
+    payload         jsonb       NOT NULL,
+    status          text        NOT NULL,
+    attempts        integer     NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    last_error      text        NOT NULL DEFAULT '',
+    delivered_at    timestamptz,
+    created_at      timestamptz NOT NULL,
+    UNIQUE (webhook_id, event_id),
+    CONSTRAINT webhook_delivery_status_check CHECK (status IN ('pending', 'delivered', 'dead'))
+);
+
+create index webhook_delivery_due_idx on webhook_delivery (next_attempt_at) where status = 'pending';
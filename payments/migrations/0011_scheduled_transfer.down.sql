@@ -0,0 +1,2 @@
+drop table scheduled_transfer_run;
+drop table scheduled_transfer;
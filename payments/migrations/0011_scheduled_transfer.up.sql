@@ -0,0 +1,39 @@
+-- Transfers made later, once or repeatedly, by the scheduler
+create table scheduled_transfer
+(
+    id              bigserial PRIMARY KEY,
+    from_account_id text        NOT NULL references account (id) on delete restrict,
+    to_account_id   text        NOT NULL references account (id) on delete restrict,
+    amount          numeric     NOT NULL,
+    currency        text        NOT NULL references currency (code) on delete restrict,
+    recurrence      text        NOT NULL,
+    day_of_month    smallint    NOT NULL DEFAULT 0,
+    start_at        timestamptz NOT NULL,
+    end_at          timestamptz,
+    status          text        NOT NULL,
+    next_run_at     timestamptz,
+    created_at      timestamptz NOT NULL,
+    CHECK (amount > 0),
+    CHECK (from_account_id <> to_account_id),
+    CONSTRAINT scheduled_transfer_recurrence_check CHECK (recurrence IN ('once', 'daily', 'weekly', 'monthly')),
+    CONSTRAINT scheduled_transfer_status_check CHECK (status IN ('active', 'completed', 'cancelled')),
+    CHECK ((status = 'active') = (next_run_at IS NOT NULL))
+);
+
+create index scheduled_transfer_due_idx on scheduled_transfer (next_run_at) where status = 'active';
+create index scheduled_transfer_from_account_idx on scheduled_transfer (from_account_id);
+
+-- Outcomes of scheduled transfers, one per occurrence
+create table scheduled_transfer_run
+(
+    id                    bigserial PRIMARY KEY,
+    scheduled_transfer_id bigint      NOT NULL references scheduled_transfer (id) on delete cascade,
+    scheduled_at          timestamptz NOT NULL,
+    ran_at                timestamptz NOT NULL,
+    status                text        NOT NULL,
+    payment_id            bigint references payment (id) on delete restrict,
+    error                 text        NOT NULL DEFAULT '',
+    UNIQUE (scheduled_transfer_id, scheduled_at),
+    CONSTRAINT scheduled_transfer_run_status_check CHECK (status IN ('succeeded', 'failed')),
+    CHECK ((status = 'succeeded') = (payment_id IS NOT NULL))
+);
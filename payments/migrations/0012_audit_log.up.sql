@@ -0,0 +1,28 @@
+-- Calls changing the state of the service, see entity.AuditEntry
+create table audit_log
+(
+    id         bigserial PRIMARY KEY,
+    time       timestamptz NOT NULL,
+    actor      text        NOT NULL,
+    request_id text        NOT NULL,
+    method     text        NOT NULL,
+    params     jsonb       NOT NULL,
+    error      text        NOT NULL DEFAULT ''
+);
+
+create index audit_log_actor_idx on audit_log (actor, id);
+
+-- The log is append-only, so entries can't be rewritten to cover tracks
+create function audit_log_append_only() returns trigger
+    language plpgsql as
+$$
+begin
+    raise exception 'audit_log is append-only';
+end;
+$$;
+
+create trigger audit_log_append_only
+    before update or delete or truncate
+    on audit_log
+    for each statement
+execute procedure audit_log_append_only();
@@ -0,0 +1,9 @@
+// Package migrations contains schema migrations of the payments service, see pkg/postgres/migrate.
+package migrations
+
+import "embed"
+
+// FS holds migration files, new migrations go next to them with the next version number.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,98 @@
+package migrations
+
+import (
+	"github.com/lightsgoout/fintech-go/pkg/postgres/migrate"
+	"github.com/lightsgoout/fintech-go/pkg/testing/isolation"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := migrate.Load(FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range migrations {
+		assert.Equal(t, int64(i+1), m.Version, "migration versions must be sequential")
+		assert.NotEmpty(t, m.Down, "migration %d_%s must be reversible", m.Version, m.Name)
+	}
+}
+
+func TestMigrations_DownUp(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	migrator, err := migrate.NewMigrator(env.Tx, FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the schema is up to date, then revert everything and apply it again.
+	_, err = migrator.Up(env.Ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		_, found, err := migrator.Down(env.Ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found {
+			break
+		}
+	}
+	pending, err := migrator.Pending(env.Ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(pending), len(migrator.Migrations()))
+
+	applied, err := migrator.Up(env.Ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, applied, migrator.Migrations())
+	pending, err = migrator.Pending(env.Ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, pending)
+}
+
+func TestMigrations_Baseline(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	migrator, err := migrate.NewMigrator(env.Tx, FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A database created by init.sql, which 0001_init replaced, has the initial schema with no migrations recorded.
+	_, err = migrator.Up(env.Ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		_, found, err := migrator.Down(env.Ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found {
+			break
+		}
+	}
+	if _, err := env.Tx.ExecContext(env.Ctx, migrator.Migrations()[0].Up); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded, err := migrator.Baseline(env.Ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, recorded, migrator.Migrations()[:1])
+
+	applied, err := migrator.Up(env.Ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, applied, migrator.Migrations()[1:])
+}
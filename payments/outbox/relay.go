@@ -0,0 +1,96 @@
+// Package outbox publishes events recorded by service.PaymentsService to downstream systems.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"log"
+	"time"
+)
+
+// DefaultInterval is how often Relay checks for new events unless configured otherwise.
+const DefaultInterval = time.Second
+
+// DefaultBatchSize is how many events Relay publishes at once unless configured otherwise.
+const DefaultBatchSize = 100
+
+// Publisher delivers events to downstream systems, e.g. a message broker.
+// Events are passed in order they were recorded, and may be passed again if Publish fails,
+// so the same event may be published more than once.
+type Publisher interface {
+	Publish(ctx context.Context, events []entity.Event) error
+}
+
+// Relay is a worker moving events from the outbox of a service to a Publisher.
+type Relay struct {
+	svc       service.PaymentsService
+	publisher Publisher
+
+	// interval is how long Relay waits after there's nothing to publish or publishing fails.
+	interval time.Duration
+
+	// batchSize is how many events are passed to the publisher at once.
+	batchSize int
+}
+
+// Option configures optional settings of Relay.
+type Option func(r *Relay)
+
+// WithInterval sets how often Relay checks for new events.
+func WithInterval(interval time.Duration) Option {
+	return func(r *Relay) {
+		r.interval = interval
+	}
+}
+
+// WithBatchSize sets how many events are published at once.
+func WithBatchSize(size int) Option {
+	return func(r *Relay) {
+		r.batchSize = size
+	}
+}
+
+// NewRelay returns Relay publishing events of svc with publisher.
+func NewRelay(svc service.PaymentsService, publisher Publisher, opts ...Option) *Relay {
+	r := &Relay{
+		svc:       svc,
+		publisher: publisher,
+		interval:  DefaultInterval,
+		batchSize: DefaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run publishes events until ctx is done. Failures are logged and retried after the interval.
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		if _, err := r.RelayPending(ctx); err != nil && ctx.Err() == nil {
+			log.Print(fmt.Errorf("failed to relay events: %w", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+// RelayPending publishes events until there are no unpublished ones left and returns their number.
+func (r *Relay) RelayPending(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := r.svc.RelayEvents(ctx, r.batchSize, r.publisher.Publish)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < r.batchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package outbox
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service/memory"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyPublisher fails every other call.
+type flakyPublisher struct {
+	mu     sync.Mutex
+	calls  int
+	events []entity.Event
+}
+
+func (p *flakyPublisher) Publish(_ context.Context, events []entity.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.calls%2 == 1 {
+		return errors.New("broker is down")
+	}
+	p.events = append(p.events, events...)
+	return nil
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	svc := memory.NewPaymentsService()
+	for _, id := range [...]entity.AccountID{"bob", "alice", "clyde"} {
+		if err := svc.CreateAccount(ctx, id, money.NewNumericFromInt64(100), "USD"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("publishes everything in batches", func(t *testing.T) {
+		var buf bytes.Buffer
+		relay := NewRelay(svc, NewWriterPublisher(&buf), WithBatchSize(2))
+		n, err := relay.RelayPending(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, n, 3)
+
+		var ids []entity.EventID
+		scanner := bufio.NewScanner(&buf)
+		for scanner.Scan() {
+			var event struct {
+				Id      entity.EventID   `json:"id"`
+				Type    entity.EventType `json:"type"`
+				Payload json.RawMessage  `json:"payload"`
+			}
+			if assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event)) {
+				assert.Equal(t, event.Type, entity.EventAccountCreated)
+				assert.NotEmpty(t, event.Payload)
+				ids = append(ids, event.Id)
+			}
+		}
+		assert.Equal(t, ids, []entity.EventID{1, 2, 3})
+	})
+
+	t.Run("retries failed publishing", func(t *testing.T) {
+		if _, err := svc.Transfer(ctx, "bob", "alice", money.NewNumericFromInt64(10), "USD", ""); err != nil {
+			t.Fatal(err)
+		}
+		publisher := &flakyPublisher{}
+		relay := NewRelay(svc, publisher, WithInterval(time.Millisecond))
+
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		done := make(chan struct{})
+		go func() {
+			relay.Run(ctx)
+			close(done)
+		}()
+		assert.Eventually(t, func() bool {
+			publisher.mu.Lock()
+			defer publisher.mu.Unlock()
+			return publisher.calls >= 2
+		}, time.Second, time.Millisecond)
+		cancel()
+		<-done
+
+		if assert.Len(t, publisher.events, 1) {
+			assert.Equal(t, publisher.events[0].Type, entity.EventPaymentCreated)
+		}
+	})
+}
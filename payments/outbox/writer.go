@@ -0,0 +1,60 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"io"
+	"sync"
+	"time"
+)
+
+// WriterPublisher writes events to an io.Writer as JSON, one per line. It's meant for local development,
+// e.g. publishing events to stdout or a file.
+type WriterPublisher struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: w}
+}
+
+type writtenEvent struct {
+	Id      entity.EventID   `json:"id"`
+	Time    time.Time        `json:"time"`
+	Type    entity.EventType `json:"type"`
+	Payload json.RawMessage  `json:"payload"`
+}
+
+func (p *WriterPublisher) Publish(_ context.Context, events []entity.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	enc := json.NewEncoder(p.w)
+	for _, e := range events {
+		err := enc.Encode(writtenEvent{
+			Id:      e.Id,
+			Time:    e.Time,
+			Type:    e.Type,
+			Payload: e.Payload,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MultiPublisher publishes events with every publisher in order, failing as soon as any of them fails.
+// Events are passed again to all the publishers then, including ones which succeeded.
+type MultiPublisher []Publisher
+
+func (m MultiPublisher) Publish(ctx context.Context, events []entity.Event) error {
+	for _, p := range m {
+		if err := p.Publish(ctx, events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
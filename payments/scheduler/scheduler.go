@@ -0,0 +1,90 @@
+// Package scheduler makes scheduled transfers when they're due, see entity.ScheduledTransfer.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"log"
+	"time"
+)
+
+const (
+	// DefaultInterval is how often Scheduler checks for due transfers unless configured otherwise.
+	DefaultInterval = 10 * time.Second
+
+	// DefaultBatchSize is how many transfers Scheduler makes at once unless configured otherwise.
+	DefaultBatchSize = 100
+)
+
+// Scheduler is a worker making due scheduled transfers.
+// Several schedulers may run against the same service, every occurrence is made by only one of them.
+type Scheduler struct {
+	svc service.PaymentsService
+
+	// interval is how long Scheduler waits after there's nothing to run.
+	interval time.Duration
+
+	// batchSize is how many transfers are made at once.
+	batchSize int
+
+	// now returns current time, replaced in tests.
+	now func() time.Time
+}
+
+// Option configures optional settings of Scheduler.
+type Option func(s *Scheduler)
+
+// WithInterval sets how often Scheduler checks for due transfers.
+func WithInterval(interval time.Duration) Option {
+	return func(s *Scheduler) {
+		s.interval = interval
+	}
+}
+
+// WithBatchSize sets how many transfers are made at once.
+func WithBatchSize(size int) Option {
+	return func(s *Scheduler) {
+		s.batchSize = size
+	}
+}
+
+// NewScheduler returns Scheduler making scheduled transfers of svc.
+func NewScheduler(svc service.PaymentsService, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		svc:       svc,
+		interval:  DefaultInterval,
+		batchSize: DefaultBatchSize,
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run makes transfers until ctx is done. Failures are logged and retried after the interval.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		n, err := s.RunDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Print(fmt.Errorf("failed to run scheduled transfers: %w", err))
+		}
+		if n == s.batchSize {
+			// There may be more due transfers.
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+// RunDue makes every transfer due now, up to the batch size, and returns the number of runs.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	return s.svc.RunScheduledTransfers(ctx, s.now(), s.batchSize)
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service/memory"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+	svc := memory.NewPaymentsService()
+	for _, id := range [...]entity.AccountID{"bob", "alice"} {
+		if err := svc.CreateAccount(ctx, id, money.NewNumericFromInt64(100), "USD"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	startAt := time.Now().UTC().Add(time.Minute).Truncate(time.Second)
+	weekly, err := svc.ScheduleTransfer(ctx, entity.ScheduledTransfer{
+		From:       "bob",
+		To:         "alice",
+		Amount:     money.NewNumericFromInt64(10),
+		Currency:   "USD",
+		Recurrence: entity.RecurrenceWeekly,
+		StartAt:    startAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := startAt.Add(-time.Second)
+	scheduler := NewScheduler(svc, WithBatchSize(1))
+	scheduler.now = func() time.Time { return now }
+
+	t.Run("transfer isn't made before it's due", func(t *testing.T) {
+		n, err := scheduler.RunDue(ctx)
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("due transfers are made in batches", func(t *testing.T) {
+		now = startAt.AddDate(0, 0, 14)
+		n, err := scheduler.RunDue(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, n, 1)
+
+		// Run goes on while there may be more due transfers.
+		runCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		scheduler.Run(runCtx)
+
+		runs, err := svc.GetScheduledRuns(ctx, weekly.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assert.Len(t, runs, 3) {
+			for i, run := range runs {
+				assert.Equal(t, run.Status, entity.RunSucceeded)
+				assert.Equal(t, run.ScheduledAt, startAt.AddDate(0, 0, 7*i))
+			}
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"strings"
+)
+
+// CheckCanDebit returns an error unless money may be taken from the account.
+func CheckCanDebit(account entity.Account) error {
+	if account.Status.CanDebit() {
+		return nil
+	}
+	if account.Status == entity.AccountClosed {
+		return ErrAccountClosed
+	}
+	return ErrAccountFrozen
+}
+
+// CheckCanCredit returns an error unless money may be put to the account.
+func CheckCanCredit(account entity.Account) error {
+	if account.Status.CanCredit() {
+		return nil
+	}
+	if account.Status == entity.AccountClosed {
+		return ErrAccountClosed
+	}
+	return ErrAccountFrozen
+}
+
+// CheckStatusChange returns an error unless the account may get the given status.
+// Closed accounts can't be reopened, and only accounts having no money can be closed.
+func CheckStatusChange(account entity.Account, status entity.AccountStatus, reason string) error {
+	if !status.IsValid() {
+		return ErrBadAccountStatus
+	}
+	if strings.TrimSpace(reason) == "" {
+		return ErrReasonRequired
+	}
+	if account.Status == entity.AccountClosed {
+		return ErrAccountClosed
+	}
+	if status == entity.AccountClosed && !account.Balance.Equal(money.NewNumericFromInt64(0)) {
+		return ErrAccountNotEmpty
+	}
+	return nil
+}
+
+// CheckSufficientFunds returns ErrInsufficientFunds unless the account may be left with the given amount
+// of available money, i.e. its balance after a debit minus money reserved by holds.
+func CheckSufficientFunds(account entity.Account, available money.Numeric) error {
+	if account.Unbounded {
+		return nil
+	}
+	if available.Add(account.CreditLimit).LessThan(money.NewNumericFromInt64(0)) {
+		return ErrInsufficientFunds
+	}
+	return nil
+}
+
+// CheckCreditLimitChange returns an error unless the account having the given amount of available money
+// may get the credit limit. Limits can't be lowered below current debt, unless the account becomes unbounded.
+func CheckCreditLimitChange(account entity.Account, available money.Numeric, limit money.Numeric, unbounded bool, currency money.CurrencyInfo) error {
+	if limit.LessThan(money.NewNumericFromInt64(0)) || !limit.FitsDecimalPlaces(currency.MinorUnits) {
+		return ErrBadCreditLimit
+	}
+	account.CreditLimit, account.Unbounded = limit, unbounded
+	if err := CheckSufficientFunds(account, available); err != nil {
+		return ErrCreditLimitTooLow
+	}
+	return nil
+}
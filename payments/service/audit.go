@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"time"
+)
+
+const (
+	// DefaultAuditLimit is how many entries are returned when AuditQuery.Limit is not set.
+	DefaultAuditLimit = 100
+
+	// MaxAuditLimit is the largest number of entries returned at once, larger limits are capped.
+	MaxAuditLimit = 1000
+)
+
+// AuditQuery selects entries of the audit log for GetAuditLog. Zero values mean "no filter".
+type AuditQuery struct {
+	Actor string
+
+	Method string
+
+	// Since is an inclusive lower bound of entry time
+	Since time.Time
+
+	// Until is an exclusive upper bound of entry time
+	Until time.Time
+
+	// Limit is the largest number of entries to return, DefaultAuditLimit if zero
+	Limit int
+}
+
+// Validate returns ErrBadQuery if the query is invalid, and the effective limit otherwise.
+func (q AuditQuery) Validate() (int, error) {
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return 0, ErrBadQuery
+	}
+	switch {
+	case q.Limit < 0:
+		return 0, ErrBadQuery
+	case q.Limit == 0:
+		return DefaultAuditLimit, nil
+	case q.Limit > MaxAuditLimit:
+		return MaxAuditLimit, nil
+	}
+	return q.Limit, nil
+}
+
+// Matches tells whether entry satisfies filters of the query.
+func (q AuditQuery) Matches(entry entity.AuditEntry) bool {
+	if q.Actor != "" && entry.Actor != q.Actor {
+		return false
+	}
+	if q.Method != "" && entry.Method != q.Method {
+		return false
+	}
+	if !q.Since.IsZero() && entry.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !entry.Time.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+type contextKey int
+
+const (
+	actorKey contextKey = iota
+	requestIdKey
+)
+
+// ContextWithActor returns ctx telling who makes calls with it, see entity.AuditEntry.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFromContext returns the actor set by ContextWithActor, empty if there is none.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey).(string)
+	return actor
+}
+
+// ContextWithRequestId returns ctx of calls made by a request, so they can be told apart in logs and the audit log.
+func ContextWithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey, id)
+}
+
+// RequestIdFromContext returns the request id set by ContextWithRequestId, empty if there is none.
+func RequestIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey).(string)
+	return id
+}
@@ -0,0 +1,321 @@
+// Package audit provides service.PaymentsService middleware appending every call changing the state of the service
+// to the audit log, see entity.AuditEntry.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+// params are parameters of a call, encoded into entity.AuditEntry.Params.
+type params map[string]interface{}
+
+// PaymentsService is service.PaymentsService appending calls changing the state of the next service
+// to its audit log, with the actor and the request id from the context of a call.
+//
+// If the next service is service.Transactional, an entry of a successful call is appended in the same transaction
+// as the change, so there are no changes missing from the log: failing to append the entry fails the call,
+// and the change is rolled back. Otherwise, and for failed calls, which change nothing, an entry is appended
+// once the call returns, even if the caller has gone meanwhile, and failures to append it are logged.
+//
+// Calls made by background workers, e.g. RunScheduledTransfers, aren't audited:
+// they have no actor, and what they do is recorded elsewhere, e.g. by scheduled runs.
+type PaymentsService struct {
+	next   service.PaymentsService
+	logger log.Logger
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewPaymentsService returns next audited, errors of the audit log are logged to logger.
+func NewPaymentsService(next service.PaymentsService, logger log.Logger) *PaymentsService {
+	return &PaymentsService{
+		next:   next,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// audit makes a call of method with p by passing the next service to call, and appends it to the audit log.
+func (s PaymentsService) audit(ctx context.Context, method string, p params, call func(next service.PaymentsService) error) error {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		_ = level.Error(s.logger).Log("msg", "failed to encode audit entry", "method", method, "err", err)
+		raw = []byte("{}")
+	}
+	entry := entity.AuditEntry{
+		Time:      s.now().UTC(),
+		Actor:     service.ActorFromContext(ctx),
+		RequestId: service.RequestIdFromContext(ctx),
+		Method:    method,
+		Params:    raw,
+	}
+
+	var callErr error
+	if tx, ok := s.next.(service.Transactional); ok {
+		err = tx.InTransaction(ctx, func(next service.PaymentsService) error {
+			if callErr = call(next); callErr != nil {
+				return callErr
+			}
+			return next.AppendAuditEntry(ctx, entry)
+		})
+		if callErr == nil {
+			if err != nil {
+				_ = level.Error(s.logger).Log(
+					"msg", "failed to append audit entry, the call is rolled back", "method", method,
+					"request_id", entry.RequestId, "err", err,
+				)
+			}
+			return err
+		}
+	} else {
+		callErr = call(s.next)
+	}
+
+	if callErr != nil {
+		entry.Error = callErr.Error()
+	}
+	if err := s.next.AppendAuditEntry(detached{ctx}, entry); err != nil {
+		_ = level.Error(s.logger).Log(
+			"msg", "failed to append audit entry", "method", method, "request_id", entry.RequestId, "err", err,
+		)
+	}
+	return callErr
+}
+
+// detached is a context having values of its parent, but never done, so the audit log is appended to
+// even if the caller has gone.
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
+
+func batchLegs(legs []service.TransferLeg) []params {
+	out := make([]params, 0, len(legs))
+	for _, leg := range legs {
+		out = append(out, params{"from": leg.From, "to": leg.To, "amount": leg.Amount, "currency": leg.Currency})
+	}
+	return out
+}
+
+func (s PaymentsService) CreateAccount(ctx context.Context, id entity.AccountID, balance money.Numeric, cur money.Currency) error {
+	return s.audit(ctx, "CreateAccount", params{"id": id, "balance": balance, "currency": cur}, func(next service.PaymentsService) error {
+		return next.CreateAccount(ctx, id, balance, cur)
+	})
+}
+
+func (s PaymentsService) Transfer(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency, idempotencyKey entity.IdempotencyKey) (res entity.PaymentID, err error) {
+	err = s.audit(ctx, "Transfer", params{"from": from, "to": to, "amount": amount, "currency": cur, "idempotency_key": idempotencyKey}, func(next service.PaymentsService) error {
+		res, err = next.Transfer(ctx, from, to, amount, cur, idempotencyKey)
+		return err
+	})
+	return res, err
+}
+
+func (s PaymentsService) TransferBatch(ctx context.Context, legs []service.TransferLeg) (res []entity.PaymentID, err error) {
+	err = s.audit(ctx, "TransferBatch", params{"legs": batchLegs(legs)}, func(next service.PaymentsService) error {
+		res, err = next.TransferBatch(ctx, legs)
+		return err
+	})
+	return res, err
+}
+
+func (s PaymentsService) QuoteExchange(ctx context.Context, from, to money.Currency) (res entity.Quote, err error) {
+	err = s.audit(ctx, "QuoteExchange", params{"from": from, "to": to}, func(next service.PaymentsService) error {
+		res, err = next.QuoteExchange(ctx, from, to)
+		return err
+	})
+	return res, err
+}
+
+func (s PaymentsService) TransferWithQuote(ctx context.Context, from, to entity.AccountID, amount money.Numeric, quoteId entity.QuoteID, idempotencyKey entity.IdempotencyKey) (res entity.PaymentID, err error) {
+	err = s.audit(ctx, "TransferWithQuote", params{"from": from, "to": to, "amount": amount, "quote_id": quoteId, "idempotency_key": idempotencyKey}, func(next service.PaymentsService) error {
+		res, err = next.TransferWithQuote(ctx, from, to, amount, quoteId, idempotencyKey)
+		return err
+	})
+	return res, err
+}
+
+func (s PaymentsService) Authorize(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency) (res entity.Hold, err error) {
+	err = s.audit(ctx, "Authorize", params{"from": from, "to": to, "amount": amount, "currency": cur}, func(next service.PaymentsService) error {
+		res, err = next.Authorize(ctx, from, to, amount, cur)
+		return err
+	})
+	return res, err
+}
+
+func (s PaymentsService) Capture(ctx context.Context, id entity.HoldID, amount *money.Numeric) (res entity.PaymentID, err error) {
+	err = s.audit(ctx, "Capture", params{"id": id, "amount": amount}, func(next service.PaymentsService) error {
+		res, err = next.Capture(ctx, id, amount)
+		return err
+	})
+	return res, err
+}
+
+func (s PaymentsService) Void(ctx context.Context, id entity.HoldID) error {
+	return s.audit(ctx, "Void", params{"id": id}, func(next service.PaymentsService) error {
+		return next.Void(ctx, id)
+	})
+}
+
+func (s PaymentsService) Refund(ctx context.Context, id entity.PaymentID, amount *money.Numeric) (res entity.PaymentID, err error) {
+	err = s.audit(ctx, "Refund", params{"id": id, "amount": amount}, func(next service.PaymentsService) error {
+		res, err = next.Refund(ctx, id, amount)
+		return err
+	})
+	return res, err
+}
+
+func (s PaymentsService) SetAccountStatus(ctx context.Context, id entity.AccountID, status entity.AccountStatus, reason string) (res entity.Account, err error) {
+	err = s.audit(ctx, "SetAccountStatus", params{"id": id, "status": status, "reason": reason}, func(next service.PaymentsService) error {
+		res, err = next.SetAccountStatus(ctx, id, status, reason)
+		return err
+	})
+	return res, err
+}
+
+func (s PaymentsService) SetCreditLimit(ctx context.Context, id entity.AccountID, limit money.Numeric, unbounded bool) (res entity.Account, err error) {
+	err = s.audit(ctx, "SetCreditLimit", params{"id": id, "limit": limit, "unbounded": unbounded}, func(next service.PaymentsService) error {
+		res, err = next.SetCreditLimit(ctx, id, limit, unbounded)
+		return err
+	})
+	return res, err
+}
+
+func (s PaymentsService) SetAccountLimits(ctx context.Context, id entity.AccountID, limits entity.AccountLimits) error {
+	return s.audit(ctx, "SetAccountLimits", params{
+		"id":             id,
+		"max_amount":     limits.MaxAmount,
+		"daily_amount":   limits.DailyAmount,
+		"monthly_amount": limits.MonthlyAmount,
+		"daily_count":    limits.DailyCount,
+		"monthly_count":  limits.MonthlyCount,
+	}, func(next service.PaymentsService) error {
+		return next.SetAccountLimits(ctx, id, limits)
+	})
+}
+
+func (s PaymentsService) GetAccountLimits(ctx context.Context, id entity.AccountID) (entity.AccountLimits, error) {
+	return s.next.GetAccountLimits(ctx, id)
+}
+
+func (s PaymentsService) GetBalance(ctx context.Context, id entity.AccountID, at time.Time) (entity.Balance, error) {
+	return s.next.GetBalance(ctx, id, at)
+}
+
+func (s PaymentsService) GetStatement(ctx context.Context, id entity.AccountID, since, until time.Time) (entity.Statement, error) {
+	return s.next.GetStatement(ctx, id, since, until)
+}
+
+func (s PaymentsService) GetPayments(ctx context.Context, query service.PaymentsQuery) (service.PaymentsPage, error) {
+	return s.next.GetPayments(ctx, query)
+}
+
+func (s PaymentsService) GetAccounts(ctx context.Context, cur money.Currency) ([]entity.AccountID, error) {
+	return s.next.GetAccounts(ctx, cur)
+}
+
+func (s PaymentsService) RelayEvents(ctx context.Context, limit int, publish service.PublishFunc) (int, error) {
+	return s.next.RelayEvents(ctx, limit, publish)
+}
+
+func (s PaymentsService) CreateWebhook(ctx context.Context, webhook entity.Webhook) (res entity.Webhook, err error) {
+	err = s.audit(ctx, "CreateWebhook", params{"url": webhook.URL, "account": webhook.Account, "event_types": webhook.EventTypes}, func(next service.PaymentsService) error {
+		res, err = next.CreateWebhook(ctx, webhook)
+		return err
+	})
+	return res, err
+}
+
+func (s PaymentsService) GetWebhooks(ctx context.Context) ([]entity.Webhook, error) {
+	return s.next.GetWebhooks(ctx)
+}
+
+func (s PaymentsService) DeleteWebhook(ctx context.Context, id entity.WebhookID) error {
+	return s.audit(ctx, "DeleteWebhook", params{"id": id}, func(next service.PaymentsService) error {
+		return next.DeleteWebhook(ctx, id)
+	})
+}
+
+func (s PaymentsService) EnqueueWebhookDeliveries(ctx context.Context, events []entity.Event) error {
+	return s.next.EnqueueWebhookDeliveries(ctx, events)
+}
+
+func (s PaymentsService) DeliverWebhooks(ctx context.Context, now time.Time, limit int, deliver service.DeliverFunc) (int, error) {
+	return s.next.DeliverWebhooks(ctx, now, limit, deliver)
+}
+
+func (s PaymentsService) GetWebhookDeliveries(ctx context.Context, query service.DeliveriesQuery) ([]entity.WebhookDelivery, error) {
+	return s.next.GetWebhookDeliveries(ctx, query)
+}
+
+func (s PaymentsService) ReplayWebhookDelivery(ctx context.Context, id entity.DeliveryID, now time.Time) (res entity.WebhookDelivery, err error) {
+	err = s.audit(ctx, "ReplayWebhookDelivery", params{"id": id}, func(next service.PaymentsService) error {
+		res, err = next.ReplayWebhookDelivery(ctx, id, now)
+		return err
+	})
+	return res, err
+}
+
+func (s PaymentsService) ScheduleTransfer(ctx context.Context, st entity.ScheduledTransfer) (res entity.ScheduledTransfer, err error) {
+	err = s.audit(ctx, "ScheduleTransfer", params{
+		"from":         st.From,
+		"to":           st.To,
+		"amount":       st.Amount,
+		"currency":     st.Currency,
+		"recurrence":   st.Recurrence,
+		"day_of_month": st.DayOfMonth,
+		"start_at":     st.StartAt,
+		"end_at":       st.EndAt,
+	}, func(next service.PaymentsService) error {
+		res, err = next.ScheduleTransfer(ctx, st)
+		return err
+	})
+	return res, err
+}
+
+func (s PaymentsService) GetScheduledTransfers(ctx context.Context, from entity.AccountID) ([]entity.ScheduledTransfer, error) {
+	return s.next.GetScheduledTransfers(ctx, from)
+}
+
+func (s PaymentsService) CancelScheduledTransfer(ctx context.Context, id entity.ScheduledTransferID) (res entity.ScheduledTransfer, err error) {
+	err = s.audit(ctx, "CancelScheduledTransfer", params{"id": id}, func(next service.PaymentsService) error {
+		res, err = next.CancelScheduledTransfer(ctx, id)
+		return err
+	})
+	return res, err
+}
+
+func (s PaymentsService) GetScheduledRuns(ctx context.Context, id entity.ScheduledTransferID) ([]entity.ScheduledRun, error) {
+	return s.next.GetScheduledRuns(ctx, id)
+}
+
+func (s PaymentsService) RunScheduledTransfers(ctx context.Context, now time.Time, limit int) (int, error) {
+	return s.next.RunScheduledTransfers(ctx, now, limit)
+}
+
+func (s PaymentsService) AppendAuditEntry(ctx context.Context, entry entity.AuditEntry) error {
+	return s.next.AppendAuditEntry(ctx, entry)
+}
+
+func (s PaymentsService) GetAuditLog(ctx context.Context, query service.AuditQuery) ([]entity.AuditEntry, error) {
+	return s.next.GetAuditLog(ctx, query)
+}
@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/go-kit/kit/log"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/payments/service/memory"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPaymentsService(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	svc := NewPaymentsService(memory.NewPaymentsService(), log.NewNopLogger())
+	svc.now = func() time.Time { return now }
+
+	ctx := service.ContextWithRequestId(service.ContextWithActor(context.Background(), "admin"), "r1")
+	if err := svc.CreateAccount(ctx, "bob", money.NewNumericFromInt64(100), "USD"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CreateAccount(ctx, "alice", money.NewNumericFromInt64(0), "USD"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := svc.Transfer(context.Background(), "bob", "alice", money.NewNumericFromInt64(1000), "USD", "")
+	assert.True(t, errors.Is(err, service.ErrInsufficientFunds))
+	_, err = svc.GetBalance(ctx, "bob", time.Time{})
+	assert.NoError(t, err)
+
+	entries, err := svc.GetAuditLog(ctx, service.AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, entries, 3, "reads aren't audited") {
+		return
+	}
+
+	assert.Equal(t, entity.AuditEntry{
+		Id:        1,
+		Time:      now,
+		Actor:     "admin",
+		RequestId: "r1",
+		Method:    "CreateAccount",
+		Params:    entries[2].Params,
+	}, entries[2])
+	assert.JSONEq(t, `{"id": "bob", "balance": "100", "currency": "USD"}`, string(entries[2].Params))
+
+	transfer := entries[0]
+	assert.Equal(t, "Transfer", transfer.Method)
+	assert.Empty(t, transfer.Actor)
+	assert.Equal(t, service.ErrInsufficientFunds.Error(), transfer.Error)
+	assert.JSONEq(t, `{"from": "bob", "to": "alice", "amount": "1000", "currency": "USD", "idempotency_key": ""}`,
+		string(transfer.Params))
+}
+
+// brokenLog is a service failing to append to the audit log.
+type brokenLog struct {
+	service.PaymentsService
+}
+
+func (brokenLog) AppendAuditEntry(context.Context, entity.AuditEntry) error {
+	return service.NewErrInternal(errors.New("connection refused"))
+}
+
+func TestPaymentsService_LogFailure(t *testing.T) {
+	var buf bytes.Buffer
+	svc := NewPaymentsService(brokenLog{memory.NewPaymentsService()}, log.NewLogfmtLogger(&buf))
+
+	err := svc.CreateAccount(context.Background(), "bob", money.NewNumericFromInt64(100), "USD")
+	assert.NoError(t, err, "the change is made anyway")
+	assert.Contains(t, buf.String(), `msg="failed to append audit entry" method=CreateAccount`)
+}
+
+// transactional is a service making calls within a transaction by calling them on tx.
+type transactional struct {
+	service.PaymentsService
+	tx service.PaymentsService
+
+	transactions int
+}
+
+func (s *transactional) InTransaction(_ context.Context, fn func(svc service.PaymentsService) error) error {
+	s.transactions++
+	return fn(s.tx)
+}
+
+func TestPaymentsService_Transactional(t *testing.T) {
+	t.Run("entry is appended in the transaction of the call", func(t *testing.T) {
+		next := memory.NewPaymentsService()
+		tx := &transactional{PaymentsService: next, tx: next}
+		svc := NewPaymentsService(tx, log.NewNopLogger())
+
+		err := svc.CreateAccount(context.Background(), "bob", money.NewNumericFromInt64(100), "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, tx.transactions)
+
+		entries, err := svc.GetAuditLog(context.Background(), service.AuditQuery{})
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("failure to append fails the call", func(t *testing.T) {
+		var buf bytes.Buffer
+		next := memory.NewPaymentsService()
+		svc := NewPaymentsService(&transactional{PaymentsService: next, tx: brokenLog{next}}, log.NewLogfmtLogger(&buf))
+
+		err := svc.CreateAccount(context.Background(), "bob", money.NewNumericFromInt64(100), "USD")
+		assert.Error(t, err, "the transaction is rolled back")
+		assert.Contains(t, buf.String(), `msg="failed to append audit entry, the call is rolled back" method=CreateAccount`)
+	})
+
+	t.Run("failed calls are appended after the transaction", func(t *testing.T) {
+		next := memory.NewPaymentsService()
+		svc := NewPaymentsService(&transactional{PaymentsService: next, tx: next}, log.NewNopLogger())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := svc.Transfer(ctx, "bob", "alice", money.NewNumericFromInt64(1), "USD", "")
+		assert.True(t, errors.Is(err, service.ErrAccountDoesNotExist))
+
+		entries, err := svc.GetAuditLog(context.Background(), service.AuditQuery{})
+		assert.NoError(t, err)
+		if assert.Len(t, entries, 1, "even if the caller has gone") {
+			assert.Equal(t, service.ErrAccountDoesNotExist.Error(), entries[0].Error)
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+)
+
+// MaxTransferBatchLegs is the largest number of legs TransferBatch accepts.
+const MaxTransferBatchLegs = 1000
+
+// TransferLeg is a single transfer of TransferBatch.
+type TransferLeg struct {
+	From     entity.AccountID
+	To       entity.AccountID
+	Amount   money.Numeric
+	Currency money.Currency
+}
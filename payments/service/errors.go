@@ -1,6 +1,11 @@
 package service
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+)
 
 var (
 	ErrAccountDoesNotExist  = errors.New("account does not exist")
@@ -9,6 +14,37 @@ var (
 	ErrInsufficientFunds    = errors.New("insufficient funds")
 	ErrAccountAlreadyExists = errors.New("account already exists")
 	ErrBadTransferTarget    = errors.New("bad transfer target")
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with different parameters")
+	ErrInvalidAmount        = errors.New("invalid amount")
+	ErrRateUnavailable      = errors.New("exchange rate unavailable")
+	ErrQuoteNotFound        = errors.New("quote not found")
+	ErrQuoteExpired         = errors.New("quote expired")
+	ErrBadQuery             = errors.New("bad query")
+	ErrBadCursor            = errors.New("bad cursor")
+	ErrHoldNotFound         = errors.New("hold not found")
+	ErrHoldExpired          = errors.New("hold expired")
+	ErrHoldNotActive        = errors.New("hold is already captured or voided")
+	ErrCaptureExceedsHold   = errors.New("capture exceeds held amount")
+	ErrPaymentNotFound      = errors.New("payment not found")
+	ErrNotRefundable        = errors.New("payment can't be refunded")
+	ErrRefundExceedsPayment = errors.New("refund exceeds payment amount")
+	ErrBadBatch             = errors.New("bad batch")
+	ErrAccountFrozen        = errors.New("account is frozen")
+	ErrAccountClosed        = errors.New("account is closed")
+	ErrAccountNotEmpty      = errors.New("account balance is not zero")
+	ErrBadAccountStatus     = errors.New("bad account status")
+	ErrReasonRequired       = errors.New("reason is required")
+	ErrLimitExceeded        = errors.New("limit exceeded")
+	ErrBadLimits            = errors.New("bad limits")
+	ErrBadCreditLimit       = errors.New("bad credit limit")
+	ErrCreditLimitTooLow    = errors.New("credit limit is below account debt")
+	ErrBadPeriod            = errors.New("bad period")
+	ErrBadWebhook           = errors.New("bad webhook")
+	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrBadSchedule          = errors.New("bad schedule")
+	ErrScheduleNotFound     = errors.New("scheduled transfer not found")
+	ErrScheduleNotActive    = errors.New("scheduled transfer is already completed or cancelled")
 )
 
 type ErrInternal struct {
@@ -28,3 +64,77 @@ func (e ErrInternal) Error() string {
 func (e ErrInternal) Unwrap() error {
 	return e.err
 }
+
+// ErrBatchLeg tells which leg of TransferBatch has failed and why.
+type ErrBatchLeg struct {
+	// Index is the index of the failed leg
+	Index int
+
+	err error
+}
+
+func NewErrBatchLeg(index int, err error) ErrBatchLeg {
+	return ErrBatchLeg{
+		Index: index,
+		err:   err,
+	}
+}
+
+func (e ErrBatchLeg) Error() string {
+	return fmt.Sprintf("leg %d: %s", e.Index, e.err.Error())
+}
+
+func (e ErrBatchLeg) Unwrap() error {
+	return e.err
+}
+
+// ErrAccountLimit tells which limit of an account a payment would exceed, it wraps ErrLimitExceeded.
+type ErrAccountLimit struct {
+	Account entity.AccountID
+
+	Limit entity.Limit
+
+	// Max is the value of the limit
+	Max string
+}
+
+func (e ErrAccountLimit) Error() string {
+	return fmt.Sprintf("%s: %s of %s is %s", ErrLimitExceeded, e.Limit, e.Account, e.Max)
+}
+
+func (e ErrAccountLimit) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// ErrorDetails returns machine-readable details of the error.
+func (e ErrAccountLimit) ErrorDetails() map[string]string {
+	return map[string]string{
+		"account": string(e.Account),
+		"limit":   string(e.Limit),
+		"max":     e.Max,
+	}
+}
+
+// Error classes, see ErrorClass.
+const (
+	ErrorClassNone     = "none"
+	ErrorClassRejected = "rejected"
+	ErrorClassInternal = "internal"
+	ErrorClassCanceled = "canceled"
+)
+
+// ErrorClass returns a coarse class of err returned by a method of PaymentsService, e.g. to label metrics
+// with a few values: ErrorClassNone if there is no error, ErrorClassInternal for ErrInternal,
+// ErrorClassCanceled if the caller gave up, and ErrorClassRejected for any other error, e.g. ErrInsufficientFunds.
+func ErrorClass(err error) string {
+	var internal ErrInternal
+	switch {
+	case err == nil:
+		return ErrorClassNone
+	case errors.As(err, &internal):
+		return ErrorClassInternal
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassCanceled
+	}
+	return ErrorClassRejected
+}
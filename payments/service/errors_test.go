@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestErrorClass(t *testing.T) {
+	assert.Equal(t, ErrorClass(nil), ErrorClassNone)
+	assert.Equal(t, ErrorClass(ErrInsufficientFunds), ErrorClassRejected)
+	assert.Equal(t, ErrorClass(NewErrBatchLeg(1, ErrAccountFrozen)), ErrorClassRejected)
+	assert.Equal(t, ErrorClass(NewErrInternal(errors.New("connection refused"))), ErrorClassInternal)
+	assert.Equal(t, ErrorClass(context.DeadlineExceeded), ErrorClassCanceled)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+// AccountCreated is the payload of entity.EventAccountCreated.
+type AccountCreated struct {
+	Account  entity.AccountID `json:"account_id"`
+	Balance  money.Numeric    `json:"balance"`
+	Currency string           `json:"currency"`
+}
+
+// PaymentCreated is the payload of entity.EventPaymentCreated.
+type PaymentCreated struct {
+	Payment  entity.PaymentID `json:"payment_id"`
+	Time     time.Time        `json:"time"`
+	From     entity.AccountID `json:"from"`
+	To       entity.AccountID `json:"to"`
+	Amount   money.Numeric    `json:"amount"`
+	Currency string           `json:"currency"`
+
+	// ToAmount and ToCurrency are set for cross-currency payments only
+	ToAmount   *money.Numeric `json:"to_amount,omitempty"`
+	ToCurrency string         `json:"to_currency,omitempty"`
+
+	RefundOf entity.PaymentID `json:"refund_of,omitempty"`
+
+	// Fee is set for payments charged a fee only
+	Fee        *money.Numeric   `json:"fee,omitempty"`
+	FeeAccount entity.AccountID `json:"fee_account,omitempty"`
+}
+
+// NewAccountCreatedEvent returns an event to record when an account is created.
+func NewAccountCreatedEvent(id entity.AccountID, balance money.Numeric, cur money.Currency, at time.Time) (entity.Event, error) {
+	return newEvent(entity.EventAccountCreated, at, AccountCreated{
+		Account:  id,
+		Balance:  balance,
+		Currency: string(cur),
+	})
+}
+
+// NewPaymentCreatedEvent returns an event to record when a payment is made.
+func NewPaymentCreatedEvent(id entity.PaymentID, value entity.PaymentValue) (entity.Event, error) {
+	payload := PaymentCreated{
+		Payment:  id,
+		Time:     value.Time,
+		From:     value.From,
+		To:       value.To,
+		Amount:   value.Amount,
+		Currency: string(value.Currency),
+		RefundOf: value.RefundOf,
+	}
+	if value.Exchange != nil {
+		payload.ToAmount = &value.Exchange.ToAmount
+		payload.ToCurrency = string(value.Exchange.ToCurrency)
+	}
+	if value.FeeAccount != "" {
+		payload.Fee = &value.Fee
+		payload.FeeAccount = value.FeeAccount
+	}
+	return newEvent(entity.EventPaymentCreated, value.Time, payload)
+}
+
+func newEvent(typ entity.EventType, at time.Time, payload interface{}) (entity.Event, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return entity.Event{}, err
+	}
+	return entity.Event{
+		Time:    at,
+		Type:    typ,
+		Payload: raw,
+	}, nil
+}
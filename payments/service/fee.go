@@ -0,0 +1,42 @@
+package service
+
+import (
+	"fmt"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/fee"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+)
+
+// Fees describes how senders of transfers are charged. The zero value charges nothing.
+// Fees are charged by Transfer, TransferBatch and scheduled transfers only. Captures are free, since the hold
+// reserves the exact amount to capture, so a fee on top of it could turn out unaffordable at capture time.
+// Cross-currency transfers are free, since the exchange rate is the price of them, and refunds are free as well.
+type Fees struct {
+	// Schedule decides how much to charge, nil disables fees
+	Schedule fee.Schedule
+
+	// RevenueAccounts are accounts receiving fees, by currency
+	RevenueAccounts map[money.Currency]entity.AccountID
+}
+
+// Charge returns a fee for the payment rounded to currency minor units and an account receiving it,
+// or zero fee and empty account if the payment is free. Payments of revenue accounts are always free.
+func (f Fees) Charge(value entity.PaymentValue, currency money.CurrencyInfo) (money.Numeric, entity.AccountID, error) {
+	zero := money.NewNumericFromInt64(0)
+	if f.Schedule == nil {
+		return zero, "", nil
+	}
+	revenue, ok := f.RevenueAccounts[value.Currency]
+	if ok && revenue == value.From {
+		return zero, "", nil
+	}
+
+	amount := f.Schedule.Fee(value.Amount, value.Currency).Round(currency.MinorUnits)
+	if !amount.IsPositive() {
+		return zero, "", nil
+	}
+	if !ok {
+		return zero, "", NewErrInternal(fmt.Errorf("no revenue account for %s fees", value.Currency))
+	}
+	return amount, revenue, nil
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"time"
+)
+
+// CheckHoldActive returns an error unless the hold can be captured or voided at the given moment.
+func CheckHoldActive(hold entity.Hold, at time.Time) error {
+	switch hold.StatusAt(at) {
+	case entity.HoldActive:
+		return nil
+	case entity.HoldExpired:
+		return ErrHoldExpired
+	default:
+		return ErrHoldNotActive
+	}
+}
@@ -0,0 +1,15 @@
+package service
+
+import "github.com/lightsgoout/fintech-go/payments/entity"
+
+// SameTransfer reports whether a payment was made with exactly the same transfer parameters,
+// i.e. whether a replay of a transfer carrying the same idempotency key is legitimate.
+func SameTransfer(a, b entity.PaymentValue) bool {
+	if a.From != b.From || a.To != b.To || !a.Amount.Equal(b.Amount) || a.Currency != b.Currency {
+		return false
+	}
+	if a.Exchange == nil || b.Exchange == nil {
+		return a.Exchange == b.Exchange
+	}
+	return a.Exchange.Quote == b.Exchange.Quote
+}
@@ -0,0 +1,329 @@
+// Package instrumenting provides service.PaymentsService middleware collecting metrics of every operation.
+package instrumenting
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// Metrics are collected by PaymentsService.
+type Metrics struct {
+	// Requests counts calls of every method, labeled by "method" and "error" class, see service.ErrorClass
+	Requests metrics.Counter
+
+	// Duration observes how long calls take in seconds, labeled by "method" and "error" class, see service.ErrorClass
+	Duration metrics.Histogram
+
+	// Volume sums amounts of payments made, labeled by "currency". Payments are counted once their
+	// PaymentCreated events are relayed by a successful RelayEvents call, which has marked them published,
+	// so every kind of payment is counted exactly once, and replays aren't. Nothing is counted unless events
+	// are relayed.
+	Volume metrics.Counter
+}
+
+// NewPrometheusMetrics returns Metrics registered with reg.
+func NewPrometheusMetrics(reg prometheus.Registerer) Metrics {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "payments",
+		Subsystem: "service",
+		Name:      "requests_total",
+		Help:      "Number of calls of service methods.",
+	}, []string{"method", "error"})
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "payments",
+		Subsystem: "service",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of calls of service methods in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "error"})
+	volume := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "payments",
+		Subsystem: "service",
+		Name:      "transfer_volume_total",
+		Help:      "Sum of amounts of payments made, by currency.",
+	}, []string{"currency"})
+	reg.MustRegister(requests, duration, volume)
+
+	return Metrics{
+		Requests: kitprometheus.NewCounter(requests),
+		Duration: kitprometheus.NewHistogram(duration),
+		Volume:   kitprometheus.NewCounter(volume),
+	}
+}
+
+// PaymentsService is service.PaymentsService collecting Metrics of calls to the next service.
+type PaymentsService struct {
+	next    service.PaymentsService
+	metrics Metrics
+}
+
+// NewPaymentsService returns next instrumented with m.
+func NewPaymentsService(next service.PaymentsService, m Metrics) *PaymentsService {
+	return &PaymentsService{
+		next:    next,
+		metrics: m,
+	}
+}
+
+func (s PaymentsService) observe(method string, begin time.Time, err error) {
+	class := service.ErrorClass(err)
+	s.metrics.Requests.With("method", method, "error", class).Add(1)
+	s.metrics.Duration.With("method", method, "error", class).Observe(time.Since(begin).Seconds())
+}
+
+// addVolume adds amounts of payments created by events to Volume.
+func (s PaymentsService) addVolume(events []entity.Event) {
+	for _, event := range events {
+		if event.Type != entity.EventPaymentCreated {
+			continue
+		}
+		var payment service.PaymentCreated
+		if err := json.Unmarshal(event.Payload, &payment); err != nil {
+			continue
+		}
+		s.metrics.Volume.With("currency", payment.Currency).Add(payment.Amount.Float64())
+	}
+}
+
+func (s PaymentsService) CreateAccount(ctx context.Context, id entity.AccountID, balance money.Numeric, cur money.Currency) (err error) {
+	defer func(begin time.Time) {
+		s.observe("CreateAccount", begin, err)
+	}(time.Now())
+	return s.next.CreateAccount(ctx, id, balance, cur)
+}
+
+func (s PaymentsService) Transfer(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency, idempotencyKey entity.IdempotencyKey) (_ entity.PaymentID, err error) {
+	defer func(begin time.Time) {
+		s.observe("Transfer", begin, err)
+	}(time.Now())
+	return s.next.Transfer(ctx, from, to, amount, cur, idempotencyKey)
+}
+
+func (s PaymentsService) TransferBatch(ctx context.Context, legs []service.TransferLeg) (_ []entity.PaymentID, err error) {
+	defer func(begin time.Time) {
+		s.observe("TransferBatch", begin, err)
+	}(time.Now())
+	return s.next.TransferBatch(ctx, legs)
+}
+
+func (s PaymentsService) QuoteExchange(ctx context.Context, from, to money.Currency) (_ entity.Quote, err error) {
+	defer func(begin time.Time) {
+		s.observe("QuoteExchange", begin, err)
+	}(time.Now())
+	return s.next.QuoteExchange(ctx, from, to)
+}
+
+func (s PaymentsService) TransferWithQuote(ctx context.Context, from, to entity.AccountID, amount money.Numeric, quoteId entity.QuoteID, idempotencyKey entity.IdempotencyKey) (_ entity.PaymentID, err error) {
+	defer func(begin time.Time) {
+		s.observe("TransferWithQuote", begin, err)
+	}(time.Now())
+	return s.next.TransferWithQuote(ctx, from, to, amount, quoteId, idempotencyKey)
+}
+
+func (s PaymentsService) Authorize(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency) (_ entity.Hold, err error) {
+	defer func(begin time.Time) {
+		s.observe("Authorize", begin, err)
+	}(time.Now())
+	return s.next.Authorize(ctx, from, to, amount, cur)
+}
+
+func (s PaymentsService) Capture(ctx context.Context, id entity.HoldID, amount *money.Numeric) (_ entity.PaymentID, err error) {
+	defer func(begin time.Time) {
+		s.observe("Capture", begin, err)
+	}(time.Now())
+	return s.next.Capture(ctx, id, amount)
+}
+
+func (s PaymentsService) Void(ctx context.Context, id entity.HoldID) (err error) {
+	defer func(begin time.Time) {
+		s.observe("Void", begin, err)
+	}(time.Now())
+	return s.next.Void(ctx, id)
+}
+
+func (s PaymentsService) Refund(ctx context.Context, id entity.PaymentID, amount *money.Numeric) (_ entity.PaymentID, err error) {
+	defer func(begin time.Time) {
+		s.observe("Refund", begin, err)
+	}(time.Now())
+	return s.next.Refund(ctx, id, amount)
+}
+
+func (s PaymentsService) SetAccountStatus(ctx context.Context, id entity.AccountID, status entity.AccountStatus, reason string) (_ entity.Account, err error) {
+	defer func(begin time.Time) {
+		s.observe("SetAccountStatus", begin, err)
+	}(time.Now())
+	return s.next.SetAccountStatus(ctx, id, status, reason)
+}
+
+func (s PaymentsService) SetCreditLimit(ctx context.Context, id entity.AccountID, limit money.Numeric, unbounded bool) (_ entity.Account, err error) {
+	defer func(begin time.Time) {
+		s.observe("SetCreditLimit", begin, err)
+	}(time.Now())
+	return s.next.SetCreditLimit(ctx, id, limit, unbounded)
+}
+
+func (s PaymentsService) SetAccountLimits(ctx context.Context, id entity.AccountID, limits entity.AccountLimits) (err error) {
+	defer func(begin time.Time) {
+		s.observe("SetAccountLimits", begin, err)
+	}(time.Now())
+	return s.next.SetAccountLimits(ctx, id, limits)
+}
+
+func (s PaymentsService) GetAccountLimits(ctx context.Context, id entity.AccountID) (_ entity.AccountLimits, err error) {
+	defer func(begin time.Time) {
+		s.observe("GetAccountLimits", begin, err)
+	}(time.Now())
+	return s.next.GetAccountLimits(ctx, id)
+}
+
+func (s PaymentsService) GetBalance(ctx context.Context, id entity.AccountID, at time.Time) (_ entity.Balance, err error) {
+	defer func(begin time.Time) {
+		s.observe("GetBalance", begin, err)
+	}(time.Now())
+	return s.next.GetBalance(ctx, id, at)
+}
+
+func (s PaymentsService) GetStatement(ctx context.Context, id entity.AccountID, since, until time.Time) (_ entity.Statement, err error) {
+	defer func(begin time.Time) {
+		s.observe("GetStatement", begin, err)
+	}(time.Now())
+	return s.next.GetStatement(ctx, id, since, until)
+}
+
+func (s PaymentsService) GetPayments(ctx context.Context, query service.PaymentsQuery) (_ service.PaymentsPage, err error) {
+	defer func(begin time.Time) {
+		s.observe("GetPayments", begin, err)
+	}(time.Now())
+	return s.next.GetPayments(ctx, query)
+}
+
+func (s PaymentsService) GetAccounts(ctx context.Context, cur money.Currency) (_ []entity.AccountID, err error) {
+	defer func(begin time.Time) {
+		s.observe("GetAccounts", begin, err)
+	}(time.Now())
+	return s.next.GetAccounts(ctx, cur)
+}
+
+func (s PaymentsService) RelayEvents(ctx context.Context, limit int, publish service.PublishFunc) (_ int, err error) {
+	defer func(begin time.Time) {
+		s.observe("RelayEvents", begin, err)
+	}(time.Now())
+	// Events are counted once they're marked published, since failed calls pass them again
+	var relayed []entity.Event
+	n, err := s.next.RelayEvents(ctx, limit, func(ctx context.Context, events []entity.Event) error {
+		if err := publish(ctx, events); err != nil {
+			return err
+		}
+		relayed = append(relayed, events...)
+		return nil
+	})
+	if err == nil {
+		s.addVolume(relayed)
+	}
+	return n, err
+}
+
+func (s PaymentsService) CreateWebhook(ctx context.Context, webhook entity.Webhook) (_ entity.Webhook, err error) {
+	defer func(begin time.Time) {
+		s.observe("CreateWebhook", begin, err)
+	}(time.Now())
+	return s.next.CreateWebhook(ctx, webhook)
+}
+
+func (s PaymentsService) GetWebhooks(ctx context.Context) (_ []entity.Webhook, err error) {
+	defer func(begin time.Time) {
+		s.observe("GetWebhooks", begin, err)
+	}(time.Now())
+	return s.next.GetWebhooks(ctx)
+}
+
+func (s PaymentsService) DeleteWebhook(ctx context.Context, id entity.WebhookID) (err error) {
+	defer func(begin time.Time) {
+		s.observe("DeleteWebhook", begin, err)
+	}(time.Now())
+	return s.next.DeleteWebhook(ctx, id)
+}
+
+func (s PaymentsService) EnqueueWebhookDeliveries(ctx context.Context, events []entity.Event) (err error) {
+	defer func(begin time.Time) {
+		s.observe("EnqueueWebhookDeliveries", begin, err)
+	}(time.Now())
+	return s.next.EnqueueWebhookDeliveries(ctx, events)
+}
+
+func (s PaymentsService) DeliverWebhooks(ctx context.Context, now time.Time, limit int, deliver service.DeliverFunc) (_ int, err error) {
+	defer func(begin time.Time) {
+		s.observe("DeliverWebhooks", begin, err)
+	}(time.Now())
+	return s.next.DeliverWebhooks(ctx, now, limit, deliver)
+}
+
+func (s PaymentsService) GetWebhookDeliveries(ctx context.Context, query service.DeliveriesQuery) (_ []entity.WebhookDelivery, err error) {
+	defer func(begin time.Time) {
+		s.observe("GetWebhookDeliveries", begin, err)
+	}(time.Now())
+	return s.next.GetWebhookDeliveries(ctx, query)
+}
+
+func (s PaymentsService) ReplayWebhookDelivery(ctx context.Context, id entity.DeliveryID, now time.Time) (_ entity.WebhookDelivery, err error) {
+	defer func(begin time.Time) {
+		s.observe("ReplayWebhookDelivery", begin, err)
+	}(time.Now())
+	return s.next.ReplayWebhookDelivery(ctx, id, now)
+}
+
+func (s PaymentsService) ScheduleTransfer(ctx context.Context, st entity.ScheduledTransfer) (_ entity.ScheduledTransfer, err error) {
+	defer func(begin time.Time) {
+		s.observe("ScheduleTransfer", begin, err)
+	}(time.Now())
+	return s.next.ScheduleTransfer(ctx, st)
+}
+
+func (s PaymentsService) GetScheduledTransfers(ctx context.Context, from entity.AccountID) (_ []entity.ScheduledTransfer, err error) {
+	defer func(begin time.Time) {
+		s.observe("GetScheduledTransfers", begin, err)
+	}(time.Now())
+	return s.next.GetScheduledTransfers(ctx, from)
+}
+
+func (s PaymentsService) CancelScheduledTransfer(ctx context.Context, id entity.ScheduledTransferID) (_ entity.ScheduledTransfer, err error) {
+	defer func(begin time.Time) {
+		s.observe("CancelScheduledTransfer", begin, err)
+	}(time.Now())
+	return s.next.CancelScheduledTransfer(ctx, id)
+}
+
+func (s PaymentsService) GetScheduledRuns(ctx context.Context, id entity.ScheduledTransferID) (_ []entity.ScheduledRun, err error) {
+	defer func(begin time.Time) {
+		s.observe("GetScheduledRuns", begin, err)
+	}(time.Now())
+	return s.next.GetScheduledRuns(ctx, id)
+}
+
+func (s PaymentsService) RunScheduledTransfers(ctx context.Context, now time.Time, limit int) (_ int, err error) {
+	defer func(begin time.Time) {
+		s.observe("RunScheduledTransfers", begin, err)
+	}(time.Now())
+	return s.next.RunScheduledTransfers(ctx, now, limit)
+}
+
+func (s PaymentsService) AppendAuditEntry(ctx context.Context, entry entity.AuditEntry) (err error) {
+	defer func(begin time.Time) {
+		s.observe("AppendAuditEntry", begin, err)
+	}(time.Now())
+	return s.next.AppendAuditEntry(ctx, entry)
+}
+
+func (s PaymentsService) GetAuditLog(ctx context.Context, query service.AuditQuery) (_ []entity.AuditEntry, err error) {
+	defer func(begin time.Time) {
+		s.observe("GetAuditLog", begin, err)
+	}(time.Now())
+	return s.next.GetAuditLog(ctx, query)
+}
@@ -0,0 +1,78 @@
+package instrumenting
+
+import (
+	"context"
+	"errors"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/payments/service/memory"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestPaymentsService(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+	var svc service.PaymentsService = NewPaymentsService(memory.NewPaymentsService(), NewPrometheusMetrics(reg))
+
+	if err := svc.CreateAccount(ctx, "bob", money.NewNumericFromInt64(100), "USD"); err != nil {
+		t.Fatal(err)
+	}
+	err := svc.CreateAccount(ctx, "bob", money.NewNumericFromInt64(100), "USD")
+	assert.True(t, errors.Is(err, service.ErrAccountAlreadyExists))
+	if err := svc.CreateAccount(ctx, "alice", money.NewNumericFromInt64(0), "USD"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.Transfer(ctx, "bob", "alice", money.NewNumericFromStringMust("12.5"), "USD", "key")
+	assert.NoError(t, err)
+	// A replay doesn't make a payment, so it doesn't add to the volume
+	_, err = svc.Transfer(ctx, "bob", "alice", money.NewNumericFromStringMust("12.5"), "USD", "key")
+	assert.NoError(t, err)
+	_, err = svc.TransferBatch(ctx, []service.TransferLeg{
+		{From: "bob", To: "alice", Amount: money.NewNumericFromInt64(10), Currency: "USD"},
+		{From: "alice", To: "bob", Amount: money.NewNumericFromInt64(5), Currency: "USD"},
+	})
+	assert.NoError(t, err)
+	_, err = svc.Transfer(ctx, "bob", "alice", money.NewNumericFromInt64(1000), "USD", "")
+	assert.True(t, errors.Is(err, service.ErrInsufficientFunds))
+	// Events of a failed relay are passed again, so they're counted once they're published
+	_, err = svc.RelayEvents(ctx, 100, func(context.Context, []entity.Event) error {
+		return errors.New("broker is down")
+	})
+	assert.Error(t, err)
+	_, err = svc.RelayEvents(ctx, 100, func(context.Context, []entity.Event) error {
+		return nil
+	})
+	assert.NoError(t, err)
+
+	expected := `
+# HELP payments_service_requests_total Number of calls of service methods.
+# TYPE payments_service_requests_total counter
+payments_service_requests_total{error="none",method="CreateAccount"} 2
+payments_service_requests_total{error="none",method="RelayEvents"} 1
+payments_service_requests_total{error="none",method="Transfer"} 2
+payments_service_requests_total{error="none",method="TransferBatch"} 1
+payments_service_requests_total{error="rejected",method="CreateAccount"} 1
+payments_service_requests_total{error="rejected",method="RelayEvents"} 1
+payments_service_requests_total{error="rejected",method="Transfer"} 1
+# HELP payments_service_transfer_volume_total Sum of amounts of payments made, by currency.
+# TYPE payments_service_transfer_volume_total counter
+payments_service_transfer_volume_total{currency="USD"} 27.5
+`
+	err = testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"payments_service_requests_total", "payments_service_transfer_volume_total",
+	)
+	assert.NoError(t, err)
+
+	families, err := reg.Gather()
+	assert.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == "payments_service_request_duration_seconds" {
+			assert.Len(t, family.GetMetric(), 7, "a histogram per method and error class")
+		}
+	}
+}
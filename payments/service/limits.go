@@ -0,0 +1,73 @@
+package service
+
+import (
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"strconv"
+	"time"
+)
+
+// LimitUsage is how much of its windowed limits an account has used before a payment.
+type LimitUsage struct {
+	DailyAmount   money.Numeric
+	MonthlyAmount money.Numeric
+	DailyCount    int
+	MonthlyCount  int
+}
+
+// LimitWindows returns starts of the UTC day and month containing t.
+func LimitWindows(t time.Time) (day, month time.Time) {
+	t = t.UTC()
+	day = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	month = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return day, month
+}
+
+// ValidateLimits returns ErrBadLimits if any of the limits is negative.
+func ValidateLimits(limits entity.AccountLimits) error {
+	zero := money.NewNumericFromInt64(0)
+	for _, amount := range []*money.Numeric{limits.MaxAmount, limits.DailyAmount, limits.MonthlyAmount} {
+		if amount != nil && amount.LessThan(zero) {
+			return ErrBadLimits
+		}
+	}
+	for _, count := range []*int{limits.DailyCount, limits.MonthlyCount} {
+		if count != nil && *count < 0 {
+			return ErrBadLimits
+		}
+	}
+	return nil
+}
+
+// CheckLimits returns ErrAccountLimit unless one more payment of amount from the account fits its limits.
+func CheckLimits(id entity.AccountID, limits entity.AccountLimits, usage LimitUsage, amount money.Numeric) error {
+	amountLimits := []struct {
+		limit entity.Limit
+		max   *money.Numeric
+		total money.Numeric
+	}{
+		{entity.LimitMaxAmount, limits.MaxAmount, amount},
+		{entity.LimitDailyAmount, limits.DailyAmount, usage.DailyAmount.Add(amount)},
+		{entity.LimitMonthlyAmount, limits.MonthlyAmount, usage.MonthlyAmount.Add(amount)},
+	}
+	for _, l := range amountLimits {
+		if l.max != nil && l.max.LessThan(l.total) {
+			return ErrAccountLimit{Account: id, Limit: l.limit, Max: l.max.String()}
+		}
+	}
+
+	countLimits := []struct {
+		limit entity.Limit
+		max   *int
+		total int
+	}{
+		{entity.LimitDailyCount, limits.DailyCount, usage.DailyCount + 1},
+		{entity.LimitMonthlyCount, limits.MonthlyCount, usage.MonthlyCount + 1},
+	}
+	for _, l := range countLimits {
+		if l.max != nil && *l.max < l.total {
+			return ErrAccountLimit{Account: id, Limit: l.limit, Max: strconv.Itoa(*l.max)}
+		}
+	}
+	return nil
+}
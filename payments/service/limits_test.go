@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLimitWindows(t *testing.T) {
+	at := time.Date(2020, 11, 2, 1, 30, 0, 0, time.FixedZone("UTC+3", 3*60*60))
+	day, month := LimitWindows(at)
+	assert.Equal(t, day, time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, month, time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func TestCheckLimits(t *testing.T) {
+	max := money.NewNumericFromInt64(100)
+	count := 3
+	limits := entity.AccountLimits{DailyAmount: &max, MonthlyCount: &count}
+
+	usage := LimitUsage{DailyAmount: money.NewNumericFromInt64(60), MonthlyCount: 2}
+	if err := CheckLimits("bob", limits, usage, money.NewNumericFromInt64(40)); err != nil {
+		t.Errorf("expected payment to fit limits, got err=%v", err)
+	}
+
+	err := CheckLimits("bob", limits, usage, money.NewNumericFromStringMust("40.01"))
+	assert.Equal(t, err, ErrAccountLimit{Account: "bob", Limit: entity.LimitDailyAmount, Max: "100"})
+	assert.Equal(t, err.Error(), "limit exceeded: daily_amount of bob is 100")
+
+	usage.MonthlyCount = 3
+	err = CheckLimits("bob", limits, usage, money.NewNumericFromInt64(1))
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected ErrLimitExceeded, got err=%v", err)
+	}
+}
@@ -0,0 +1,295 @@
+// Package logging provides service.PaymentsService middleware logging every operation.
+package logging
+
+import (
+	"context"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+// PaymentsService is service.PaymentsService logging calls to the next service: the method, accounts and amounts
+// it deals with, the outcome and how long it took. Records of a call carry the request id from its context,
+// so they can be told apart from records of concurrent requests.
+type PaymentsService struct {
+	next   service.PaymentsService
+	logger log.Logger
+}
+
+// NewPaymentsService returns next logging to logger.
+func NewPaymentsService(next service.PaymentsService, logger log.Logger) *PaymentsService {
+	return &PaymentsService{
+		next:   next,
+		logger: logger,
+	}
+}
+
+// log logs a call of method which failed with err unless it's nil, with keyvals describing the call.
+// Internal errors are logged at error level, everything else, including rejected calls, at info level.
+func (s PaymentsService) log(ctx context.Context, method string, begin time.Time, err error, keyvals ...interface{}) {
+	outcome := service.ErrorClass(err)
+	logger := level.Info(s.logger)
+	if outcome == service.ErrorClassInternal {
+		logger = level.Error(s.logger)
+	}
+	record := []interface{}{
+		"request_id", service.RequestIdFromContext(ctx),
+		"method", method,
+		"outcome", outcome,
+		"took", time.Since(begin),
+	}
+	record = append(record, keyvals...)
+	if err != nil {
+		record = append(record, "err", err)
+	}
+	_ = logger.Log(record...)
+}
+
+// logWorker logs a call of method made by a background worker, which did n units of work,
+// unless it did nothing and didn't fail. Workers call it every few seconds, mostly with nothing to do.
+func (s PaymentsService) logWorker(ctx context.Context, method string, begin time.Time, n int, err error) {
+	if n > 0 || err != nil {
+		s.log(ctx, method, begin, err, "count", n)
+	}
+}
+
+// optionalAmount describes an amount which is the whole amount of a hold or a payment if nil.
+func optionalAmount(amount *money.Numeric) interface{} {
+	if amount == nil {
+		return "full"
+	}
+	return *amount
+}
+
+func (s PaymentsService) CreateAccount(ctx context.Context, id entity.AccountID, balance money.Numeric, cur money.Currency) (err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "CreateAccount", begin, err, "account", id, "balance", balance, "currency", cur)
+	}(time.Now())
+	return s.next.CreateAccount(ctx, id, balance, cur)
+}
+
+func (s PaymentsService) Transfer(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency, idempotencyKey entity.IdempotencyKey) (res entity.PaymentID, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "Transfer", begin, err,
+			"from", from, "to", to, "amount", amount, "currency", cur, "idempotency_key", idempotencyKey, "payment", res,
+		)
+	}(time.Now())
+	return s.next.Transfer(ctx, from, to, amount, cur, idempotencyKey)
+}
+
+func (s PaymentsService) TransferBatch(ctx context.Context, legs []service.TransferLeg) (_ []entity.PaymentID, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "TransferBatch", begin, err, "legs", len(legs))
+	}(time.Now())
+	return s.next.TransferBatch(ctx, legs)
+}
+
+func (s PaymentsService) QuoteExchange(ctx context.Context, from, to money.Currency) (res entity.Quote, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "QuoteExchange", begin, err, "from_currency", from, "to_currency", to, "quote", res.Id)
+	}(time.Now())
+	return s.next.QuoteExchange(ctx, from, to)
+}
+
+func (s PaymentsService) TransferWithQuote(ctx context.Context, from, to entity.AccountID, amount money.Numeric, quoteId entity.QuoteID, idempotencyKey entity.IdempotencyKey) (res entity.PaymentID, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "TransferWithQuote", begin, err,
+			"from", from, "to", to, "amount", amount, "quote", quoteId, "idempotency_key", idempotencyKey, "payment", res,
+		)
+	}(time.Now())
+	return s.next.TransferWithQuote(ctx, from, to, amount, quoteId, idempotencyKey)
+}
+
+func (s PaymentsService) Authorize(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency) (res entity.Hold, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "Authorize", begin, err, "from", from, "to", to, "amount", amount, "currency", cur, "hold", res.Id)
+	}(time.Now())
+	return s.next.Authorize(ctx, from, to, amount, cur)
+}
+
+func (s PaymentsService) Capture(ctx context.Context, id entity.HoldID, amount *money.Numeric) (res entity.PaymentID, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "Capture", begin, err, "hold", id, "amount", optionalAmount(amount), "payment", res)
+	}(time.Now())
+	return s.next.Capture(ctx, id, amount)
+}
+
+func (s PaymentsService) Void(ctx context.Context, id entity.HoldID) (err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "Void", begin, err, "hold", id)
+	}(time.Now())
+	return s.next.Void(ctx, id)
+}
+
+func (s PaymentsService) Refund(ctx context.Context, id entity.PaymentID, amount *money.Numeric) (res entity.PaymentID, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "Refund", begin, err, "payment", id, "amount", optionalAmount(amount), "refund", res)
+	}(time.Now())
+	return s.next.Refund(ctx, id, amount)
+}
+
+func (s PaymentsService) SetAccountStatus(ctx context.Context, id entity.AccountID, status entity.AccountStatus, reason string) (_ entity.Account, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "SetAccountStatus", begin, err, "account", id, "status", status)
+	}(time.Now())
+	return s.next.SetAccountStatus(ctx, id, status, reason)
+}
+
+func (s PaymentsService) SetCreditLimit(ctx context.Context, id entity.AccountID, limit money.Numeric, unbounded bool) (_ entity.Account, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "SetCreditLimit", begin, err, "account", id, "limit", limit, "unbounded", unbounded)
+	}(time.Now())
+	return s.next.SetCreditLimit(ctx, id, limit, unbounded)
+}
+
+func (s PaymentsService) SetAccountLimits(ctx context.Context, id entity.AccountID, limits entity.AccountLimits) (err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "SetAccountLimits", begin, err, "account", id)
+	}(time.Now())
+	return s.next.SetAccountLimits(ctx, id, limits)
+}
+
+func (s PaymentsService) GetAccountLimits(ctx context.Context, id entity.AccountID) (_ entity.AccountLimits, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "GetAccountLimits", begin, err, "account", id)
+	}(time.Now())
+	return s.next.GetAccountLimits(ctx, id)
+}
+
+func (s PaymentsService) GetBalance(ctx context.Context, id entity.AccountID, at time.Time) (_ entity.Balance, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "GetBalance", begin, err, "account", id)
+	}(time.Now())
+	return s.next.GetBalance(ctx, id, at)
+}
+
+func (s PaymentsService) GetStatement(ctx context.Context, id entity.AccountID, since, until time.Time) (_ entity.Statement, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "GetStatement", begin, err, "account", id)
+	}(time.Now())
+	return s.next.GetStatement(ctx, id, since, until)
+}
+
+func (s PaymentsService) GetPayments(ctx context.Context, query service.PaymentsQuery) (_ service.PaymentsPage, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "GetPayments", begin, err, "account", query.Account)
+	}(time.Now())
+	return s.next.GetPayments(ctx, query)
+}
+
+func (s PaymentsService) GetAccounts(ctx context.Context, cur money.Currency) (_ []entity.AccountID, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "GetAccounts", begin, err, "currency", cur)
+	}(time.Now())
+	return s.next.GetAccounts(ctx, cur)
+}
+
+func (s PaymentsService) RelayEvents(ctx context.Context, limit int, publish service.PublishFunc) (n int, err error) {
+	defer func(begin time.Time) {
+		s.logWorker(ctx, "RelayEvents", begin, n, err)
+	}(time.Now())
+	return s.next.RelayEvents(ctx, limit, publish)
+}
+
+func (s PaymentsService) CreateWebhook(ctx context.Context, webhook entity.Webhook) (res entity.Webhook, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "CreateWebhook", begin, err, "account", webhook.Account, "webhook", res.Id)
+	}(time.Now())
+	return s.next.CreateWebhook(ctx, webhook)
+}
+
+func (s PaymentsService) GetWebhooks(ctx context.Context) (_ []entity.Webhook, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "GetWebhooks", begin, err)
+	}(time.Now())
+	return s.next.GetWebhooks(ctx)
+}
+
+func (s PaymentsService) DeleteWebhook(ctx context.Context, id entity.WebhookID) (err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "DeleteWebhook", begin, err, "webhook", id)
+	}(time.Now())
+	return s.next.DeleteWebhook(ctx, id)
+}
+
+func (s PaymentsService) EnqueueWebhookDeliveries(ctx context.Context, events []entity.Event) (err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "EnqueueWebhookDeliveries", begin, err, "events", len(events))
+	}(time.Now())
+	return s.next.EnqueueWebhookDeliveries(ctx, events)
+}
+
+func (s PaymentsService) DeliverWebhooks(ctx context.Context, now time.Time, limit int, deliver service.DeliverFunc) (n int, err error) {
+	defer func(begin time.Time) {
+		s.logWorker(ctx, "DeliverWebhooks", begin, n, err)
+	}(time.Now())
+	return s.next.DeliverWebhooks(ctx, now, limit, deliver)
+}
+
+func (s PaymentsService) GetWebhookDeliveries(ctx context.Context, query service.DeliveriesQuery) (_ []entity.WebhookDelivery, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "GetWebhookDeliveries", begin, err, "webhook", query.Webhook)
+	}(time.Now())
+	return s.next.GetWebhookDeliveries(ctx, query)
+}
+
+func (s PaymentsService) ReplayWebhookDelivery(ctx context.Context, id entity.DeliveryID, now time.Time) (_ entity.WebhookDelivery, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "ReplayWebhookDelivery", begin, err, "delivery", id)
+	}(time.Now())
+	return s.next.ReplayWebhookDelivery(ctx, id, now)
+}
+
+func (s PaymentsService) ScheduleTransfer(ctx context.Context, st entity.ScheduledTransfer) (res entity.ScheduledTransfer, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "ScheduleTransfer", begin, err,
+			"from", st.From, "to", st.To, "amount", st.Amount, "currency", st.Currency, "recurrence", st.Recurrence, "schedule", res.Id,
+		)
+	}(time.Now())
+	return s.next.ScheduleTransfer(ctx, st)
+}
+
+func (s PaymentsService) GetScheduledTransfers(ctx context.Context, from entity.AccountID) (_ []entity.ScheduledTransfer, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "GetScheduledTransfers", begin, err, "from", from)
+	}(time.Now())
+	return s.next.GetScheduledTransfers(ctx, from)
+}
+
+func (s PaymentsService) CancelScheduledTransfer(ctx context.Context, id entity.ScheduledTransferID) (_ entity.ScheduledTransfer, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "CancelScheduledTransfer", begin, err, "schedule", id)
+	}(time.Now())
+	return s.next.CancelScheduledTransfer(ctx, id)
+}
+
+func (s PaymentsService) GetScheduledRuns(ctx context.Context, id entity.ScheduledTransferID) (_ []entity.ScheduledRun, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "GetScheduledRuns", begin, err, "schedule", id)
+	}(time.Now())
+	return s.next.GetScheduledRuns(ctx, id)
+}
+
+func (s PaymentsService) RunScheduledTransfers(ctx context.Context, now time.Time, limit int) (n int, err error) {
+	defer func(begin time.Time) {
+		s.logWorker(ctx, "RunScheduledTransfers", begin, n, err)
+	}(time.Now())
+	return s.next.RunScheduledTransfers(ctx, now, limit)
+}
+
+func (s PaymentsService) AppendAuditEntry(ctx context.Context, entry entity.AuditEntry) (err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "AppendAuditEntry", begin, err, "audited_method", entry.Method)
+	}(time.Now())
+	return s.next.AppendAuditEntry(ctx, entry)
+}
+
+func (s PaymentsService) GetAuditLog(ctx context.Context, query service.AuditQuery) (_ []entity.AuditEntry, err error) {
+	defer func(begin time.Time) {
+		s.log(ctx, "GetAuditLog", begin, err)
+	}(time.Now())
+	return s.next.GetAuditLog(ctx, query)
+}
@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-kit/kit/log"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/payments/service/memory"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPaymentsService(t *testing.T) {
+	var buf bytes.Buffer
+	svc := NewPaymentsService(memory.NewPaymentsService(), log.NewJSONLogger(&buf))
+
+	ctx := service.ContextWithRequestId(context.Background(), "r1")
+	if err := svc.CreateAccount(ctx, "bob", money.NewNumericFromInt64(100), "USD"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CreateAccount(ctx, "alice", money.NewNumericFromInt64(0), "USD"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := svc.Transfer(ctx, "bob", "alice", money.NewNumericFromStringMust("12.5"), "USD", "")
+	assert.NoError(t, err)
+	_, err = svc.Transfer(ctx, "bob", "alice", money.NewNumericFromInt64(1000), "USD", "")
+	assert.True(t, errors.Is(err, service.ErrInsufficientFunds))
+	_, err = svc.RunScheduledTransfers(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if !assert.Len(t, records, 4, "idle workers aren't logged") {
+		return
+	}
+
+	transfer := records[2]
+	assert.Equal(t, "info", transfer["level"])
+	assert.Equal(t, "r1", transfer["request_id"])
+	assert.Equal(t, "Transfer", transfer["method"])
+	assert.Equal(t, "none", transfer["outcome"])
+	assert.Equal(t, "bob", transfer["from"])
+	assert.Equal(t, "alice", transfer["to"])
+	assert.Equal(t, "12.5", transfer["amount"])
+	assert.Equal(t, float64(1), transfer["payment"])
+	assert.NotEmpty(t, transfer["took"])
+
+	rejected := records[3]
+	assert.Equal(t, "info", rejected["level"], "rejected calls aren't errors of the service")
+	assert.Equal(t, "rejected", rejected["outcome"])
+	assert.Equal(t, service.ErrInsufficientFunds.Error(), rejected["err"])
+}
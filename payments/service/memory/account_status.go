@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+)
+
+func (s *PaymentsService) SetAccountStatus(ctx context.Context, id entity.AccountID, status entity.AccountStatus, reason string) (entity.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[id]
+	if !ok {
+		return entity.Account{}, service.ErrAccountDoesNotExist
+	}
+
+	if err := service.CheckStatusChange(*account, status, reason); err != nil {
+		return entity.Account{}, err
+	}
+
+	account.Status = status
+	account.StatusReason = reason
+	return *account, nil
+}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+)
+
+func (s *PaymentsService) AppendAuditEntry(ctx context.Context, entry entity.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.Id = entity.AuditEntryID(len(s.audit) + 1)
+	entry.Params = append(json.RawMessage(nil), entry.Params...)
+	s.audit = append(s.audit, entry)
+	return nil
+}
+
+func (s *PaymentsService) GetAuditLog(ctx context.Context, query service.AuditQuery) ([]entity.AuditEntry, error) {
+	limit, err := query.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []entity.AuditEntry
+	for i := len(s.audit) - 1; i >= 0 && len(result) < limit; i-- {
+		if query.Matches(s.audit[i]) {
+			result = append(result, s.audit[i])
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+)
+
+func (s *PaymentsService) TransferBatch(ctx context.Context, legs []service.TransferLeg) ([]entity.PaymentID, error) {
+	if len(legs) == 0 || len(legs) > service.MaxTransferBatchLegs {
+		return nil, service.ErrBadBatch
+	}
+
+	for i, leg := range legs {
+		if err := s.validateTransfer(leg.From, leg.To, leg.Amount, leg.Currency); err != nil {
+			return nil, service.NewErrBatchLeg(i, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Remember balances to restore them if any of the legs fails.
+	balances := make(map[entity.AccountID]money.Numeric)
+	for i, leg := range legs {
+		for _, id := range [2]entity.AccountID{leg.From, leg.To} {
+			account, ok := s.accounts[id]
+			if !ok {
+				return nil, service.NewErrBatchLeg(i, service.ErrAccountDoesNotExist)
+			}
+			balances[id] = account.Balance
+		}
+	}
+	for _, id := range s.fees.RevenueAccounts {
+		if account, ok := s.accounts[id]; ok {
+			balances[id] = account.Balance
+		}
+	}
+	paymentsCount, journalLength, eventsCount := len(s.payments), len(s.journal), len(s.events)
+
+	paymentIds := make([]entity.PaymentID, len(legs))
+	for i, leg := range legs {
+		var err error
+		paymentIds[i], err = s.transfer(transferRequest{
+			value: entity.PaymentValue{
+				From:     leg.From,
+				To:       leg.To,
+				Amount:   leg.Amount,
+				Currency: leg.Currency,
+			},
+			chargeFee: true,
+		})
+		if err != nil {
+			for id, balance := range balances {
+				s.accounts[id].Balance = balance
+			}
+			s.payments = s.payments[:paymentsCount]
+			s.journal = s.journal[:journalLength]
+			s.events = s.events[:eventsCount]
+			return nil, service.NewErrBatchLeg(i, err)
+		}
+	}
+	return paymentIds, nil
+}
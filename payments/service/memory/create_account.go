@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+func (s *PaymentsService) CreateAccount(ctx context.Context, id entity.AccountID, balance money.Numeric, cur money.Currency) error {
+	if balance.LessThan(money.NewNumericFromInt64(0)) {
+		return service.ErrInsufficientFunds
+	}
+
+	currency, ok := s.currencies.Lookup(cur)
+	if !ok {
+		return service.ErrIncompatibleCurrency
+	}
+
+	if !balance.FitsDecimalPlaces(currency.MinorUnits) {
+		return service.ErrInvalidAmount
+	}
+
+	if id == "" {
+		return service.ErrBadAccountID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.accounts[id]; exists {
+		return service.ErrAccountAlreadyExists
+	}
+	now := time.Now().UTC()
+	event, err := service.NewAccountCreatedEvent(id, balance, cur, now)
+	if err != nil {
+		return service.NewErrInternal(err)
+	}
+	s.accounts[id] = &entity.Account{
+		Id:          id,
+		Balance:     balance,
+		Currency:    cur,
+		Status:      entity.AccountActive,
+		CreditLimit: money.NewNumericFromInt64(0),
+	}
+	if balance.IsPositive() {
+		s.journal = append(s.journal, journalEntry{
+			Time:    now,
+			Account: id,
+			Amount:  balance,
+		})
+	}
+	s.recordEvent(event)
+	return nil
+}
@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+func (s *PaymentsService) SetCreditLimit(ctx context.Context, id entity.AccountID, limit money.Numeric, unbounded bool) (entity.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[id]
+	if !ok {
+		return entity.Account{}, service.ErrAccountDoesNotExist
+	}
+
+	currency, _ := s.currencies.Lookup(account.Currency)
+	available := account.Balance.Sub(s.heldAmount(id, time.Now().UTC(), 0))
+	if err := service.CheckCreditLimitChange(*account, available, limit, unbounded, currency); err != nil {
+		return entity.Account{}, err
+	}
+
+	account.CreditLimit = limit
+	account.Unbounded = unbounded
+	return *account, nil
+}
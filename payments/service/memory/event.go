@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+)
+
+// recordEvent assigns the next id to event and appends it to events.
+// Callers must hold the mutex.
+func (s *PaymentsService) recordEvent(event entity.Event) {
+	event.Id = entity.EventID(len(s.events) + 1)
+	s.events = append(s.events, event)
+}
+
+func (s *PaymentsService) RelayEvents(ctx context.Context, limit int, publish service.PublishFunc) (int, error) {
+	// Publishing may be slow, so don't block other operations meanwhile.
+	// Only events recorded so far are passed, and recorded events never change.
+	s.mu.Lock()
+	from := s.published
+	pending := s.events[from:]
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+	events := append([]entity.Event(nil), pending...)
+	s.mu.Unlock()
+
+	if len(events) == 0 {
+		return 0, nil
+	}
+	if err := publish(ctx, events); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if to := from + len(events); to > s.published {
+		s.published = to
+	}
+	return len(events), nil
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/fx"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+func (s *PaymentsService) QuoteExchange(ctx context.Context, from, to money.Currency) (entity.Quote, error) {
+	if !s.currencies.IsKnown(from) || !s.currencies.IsKnown(to) || from == to {
+		return entity.Quote{}, service.ErrIncompatibleCurrency
+	}
+	if s.rates == nil {
+		return entity.Quote{}, service.ErrRateUnavailable
+	}
+
+	rate, err := s.rates.Rate(ctx, from, to)
+	if err != nil {
+		if errors.Is(err, fx.ErrRateNotFound) {
+			return entity.Quote{}, service.ErrRateUnavailable
+		}
+		return entity.Quote{}, service.NewErrInternal(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quote := entity.Quote{
+		Id:        entity.QuoteID(len(s.quotes) + 1),
+		From:      from,
+		To:        to,
+		Rate:      rate,
+		ExpiresAt: time.Now().UTC().Add(s.quoteTTL),
+	}
+	s.quotes = append(s.quotes, quote)
+	return quote, nil
+}
+
+func (s *PaymentsService) TransferWithQuote(ctx context.Context, from, to entity.AccountID, amount money.Numeric, quoteId entity.QuoteID, idempotencyKey entity.IdempotencyKey) (entity.PaymentID, error) {
+	if from == to {
+		return 0, service.ErrBadTransferTarget
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if quoteId <= 0 || int(quoteId) > len(s.quotes) {
+		return 0, service.ErrQuoteNotFound
+	}
+	quote := s.quotes[quoteId-1]
+
+	fromCurrency, ok := s.currencies.Lookup(quote.From)
+	if !ok {
+		return 0, service.ErrIncompatibleCurrency
+	}
+	toCurrency, ok := s.currencies.Lookup(quote.To)
+	if !ok {
+		return 0, service.ErrIncompatibleCurrency
+	}
+
+	if !amount.IsPositive() || !amount.FitsDecimalPlaces(fromCurrency.MinorUnits) {
+		return 0, service.ErrInvalidAmount
+	}
+	toAmount := amount.Mul(quote.Rate).Round(toCurrency.MinorUnits)
+	if !toAmount.IsPositive() {
+		return 0, service.ErrInvalidAmount
+	}
+
+	return s.transfer(transferRequest{
+		value: entity.PaymentValue{
+			From:     from,
+			To:       to,
+			Amount:   amount,
+			Currency: quote.From,
+			Exchange: &entity.Exchange{
+				Quote:      quote.Id,
+				Rate:       quote.Rate,
+				ToAmount:   toAmount,
+				ToCurrency: quote.To,
+			},
+		},
+		idempotencyKey: idempotencyKey,
+		// NOTE: a replay of an already made transfer succeeds even if the quote has expired since.
+		quoteExpiresAt: quote.ExpiresAt,
+	})
+}
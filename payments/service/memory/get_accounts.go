@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"sort"
+)
+
+func (s *PaymentsService) GetAccounts(ctx context.Context, cur money.Currency) ([]entity.AccountID, error) {
+	if !s.currencies.IsKnown(cur) {
+		return nil, service.ErrIncompatibleCurrency
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []entity.AccountID
+	for id, account := range s.accounts {
+		if account.Currency == cur {
+			result = append(result, id)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result, nil
+}
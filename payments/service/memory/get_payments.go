@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"sort"
+)
+
+func (s *PaymentsService) GetPayments(ctx context.Context, query service.PaymentsQuery) (service.PaymentsPage, error) {
+	cursor, limit, err := query.Validate()
+	if err != nil {
+		return service.PaymentsPage{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.accounts[query.Account]; !exists {
+		return service.PaymentsPage{}, service.ErrAccountDoesNotExist
+	}
+
+	refunds := make(map[entity.PaymentID][]entity.PaymentID)
+	for _, p := range s.payments {
+		if p.Value.RefundOf != 0 {
+			refunds[p.Value.RefundOf] = append(refunds[p.Value.RefundOf], p.Id)
+		}
+	}
+
+	var result []entity.Payment
+	for _, p := range s.payments {
+		if p.Value.From == query.Account && query.Direction != service.DirectionIncoming {
+			p.Value.Outgoing = true
+		} else if p.Value.To == query.Account && query.Direction != service.DirectionOutgoing {
+			p.Value.Outgoing = false
+		} else {
+			continue
+		}
+		if matchesQuery(p, query) && cursor.Precedes(p) {
+			p.RefundedBy = refunds[p.Id]
+			result = append(result, p)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].Value.Time, result[j].Value.Time
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return result[i].Id > result[j].Id
+	})
+
+	page := service.PaymentsPage{
+		Payments: result,
+	}
+	if len(result) > limit {
+		page.Payments = result[:limit]
+		page.NextCursor = service.EncodePaymentsCursor(page.Payments[limit-1])
+	}
+	return page, nil
+}
+
+// matchesQuery reports whether payment p of query.Account passes optional filters of the query.
+func matchesQuery(p entity.Payment, query service.PaymentsQuery) bool {
+	counterparty, amount := p.Value.To, p.Value.Amount
+	if !p.Value.Outgoing {
+		// Incoming cross-currency payments are filtered by the amount received
+		counterparty, amount = p.Value.From, p.Value.CreditAmount()
+	}
+
+	if query.Counterparty != "" && counterparty != query.Counterparty {
+		return false
+	}
+	if !query.Since.IsZero() && p.Value.Time.Before(query.Since) {
+		return false
+	}
+	if !query.Until.IsZero() && !p.Value.Time.Before(query.Until) {
+		return false
+	}
+	if query.MinAmount != nil && amount.LessThan(*query.MinAmount) {
+		return false
+	}
+	if query.MaxAmount != nil && query.MaxAmount.LessThan(amount) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+func (s *PaymentsService) Authorize(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency) (entity.Hold, error) {
+	if from == to {
+		return entity.Hold{}, service.ErrBadTransferTarget
+	}
+
+	currency, ok := s.currencies.Lookup(cur)
+	if !ok {
+		return entity.Hold{}, service.ErrIncompatibleCurrency
+	}
+
+	if !amount.IsPositive() || !amount.FitsDecimalPlaces(currency.MinorUnits) {
+		return entity.Hold{}, service.ErrInvalidAmount
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accountFrom, ok := s.accounts[from]
+	if !ok {
+		return entity.Hold{}, service.ErrAccountDoesNotExist
+	}
+	accountTo, ok := s.accounts[to]
+	if !ok {
+		return entity.Hold{}, service.ErrAccountDoesNotExist
+	}
+
+	if err := service.CheckCanDebit(*accountFrom); err != nil {
+		return entity.Hold{}, err
+	}
+	if err := service.CheckCanCredit(*accountTo); err != nil {
+		return entity.Hold{}, err
+	}
+
+	if accountFrom.Currency != cur || accountTo.Currency != cur {
+		return entity.Hold{}, service.ErrIncompatibleCurrency
+	}
+
+	now := time.Now().UTC()
+	// Limits are checked as if the hold was captured now, and once again when it's captured.
+	if err := s.checkLimits(entity.PaymentValue{From: from, To: to, Amount: amount, Currency: cur, Time: now}); err != nil {
+		return entity.Hold{}, err
+	}
+
+	held := s.heldAmount(from, now, 0)
+	if err := service.CheckSufficientFunds(*accountFrom, accountFrom.Balance.Sub(held).Sub(amount)); err != nil {
+		return entity.Hold{}, err
+	}
+
+	hold := entity.Hold{
+		Id:        entity.HoldID(len(s.holds) + 1),
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		Currency:  cur,
+		Status:    entity.HoldActive,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.holdTTL),
+	}
+	s.holds = append(s.holds, hold)
+	return hold, nil
+}
+
+func (s *PaymentsService) Capture(ctx context.Context, id entity.HoldID, amount *money.Numeric) (entity.PaymentID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, err := s.getActiveHold(id)
+	if err != nil {
+		return 0, err
+	}
+
+	captured := hold.Amount
+	if amount != nil {
+		currency, ok := s.currencies.Lookup(hold.Currency)
+		if !ok {
+			return 0, service.ErrIncompatibleCurrency
+		}
+		if !amount.IsPositive() || !amount.FitsDecimalPlaces(currency.MinorUnits) {
+			return 0, service.ErrInvalidAmount
+		}
+		if hold.Amount.LessThan(*amount) {
+			return 0, service.ErrCaptureExceedsHold
+		}
+		captured = *amount
+	}
+
+	paymentId, err := s.transfer(transferRequest{
+		value: entity.PaymentValue{
+			From:     hold.From,
+			To:       hold.To,
+			Amount:   captured,
+			Currency: hold.Currency,
+		},
+		hold: hold.Id,
+	})
+	if err != nil {
+		return 0, err
+	}
+	hold.Status = entity.HoldCaptured
+	hold.Payment = paymentId
+	return paymentId, nil
+}
+
+func (s *PaymentsService) Void(ctx context.Context, id entity.HoldID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hold, err := s.getActiveHold(id)
+	if err != nil {
+		return err
+	}
+	hold.Status = entity.HoldVoided
+	return nil
+}
+
+// getActiveHold returns a hold which can be captured or voided. Must be called with s.mu held.
+func (s *PaymentsService) getActiveHold(id entity.HoldID) (*entity.Hold, error) {
+	if id <= 0 || int(id) > len(s.holds) {
+		return nil, service.ErrHoldNotFound
+	}
+	hold := &s.holds[id-1]
+	if err := service.CheckHoldActive(*hold, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// heldAmount returns money of an account reserved by holds active at the given moment, except the given hold.
+// Must be called with s.mu held.
+func (s *PaymentsService) heldAmount(id entity.AccountID, at time.Time, except entity.HoldID) money.Numeric {
+	held := money.NewNumericFromInt64(0)
+	for _, hold := range s.holds {
+		if hold.From == id && hold.Id != except && hold.StatusAt(at) == entity.HoldActive {
+			held = held.Add(hold.Amount)
+		}
+	}
+	return held
+}
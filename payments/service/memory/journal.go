@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+// journalEntry is a single balance change of an account, like the journal of persistent.PaymentsService.
+type journalEntry struct {
+	Time time.Time
+
+	Account entity.AccountID
+
+	// Payment is a payment the entry is a leg of, zero for initial balances
+	Payment entity.PaymentID
+
+	// Amount is positive for credits and negative for debits
+	Amount money.Numeric
+}
+
+// journalPayment records legs of a payment: debit and credit of the amount and of the fee if any.
+// Must be called with s.mu held.
+func (s *PaymentsService) journalPayment(p entity.Payment) {
+	s.journal = append(s.journal,
+		journalEntry{Time: p.Value.Time, Account: p.Value.From, Payment: p.Id, Amount: p.Value.Amount.Neg()},
+		journalEntry{Time: p.Value.Time, Account: p.Value.To, Payment: p.Id, Amount: p.Value.CreditAmount()},
+	)
+	if p.Value.FeeAccount != "" {
+		s.journal = append(s.journal,
+			journalEntry{Time: p.Value.Time, Account: p.Value.From, Payment: p.Id, Amount: p.Value.Fee.Neg()},
+			journalEntry{Time: p.Value.Time, Account: p.Value.FeeAccount, Payment: p.Id, Amount: p.Value.Fee},
+		)
+	}
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+func (s *PaymentsService) SetAccountLimits(ctx context.Context, id entity.AccountID, limits entity.AccountLimits) error {
+	if err := service.ValidateLimits(limits); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.accounts[id]; !ok {
+		return service.ErrAccountDoesNotExist
+	}
+	s.limits[id] = limits
+	return nil
+}
+
+func (s *PaymentsService) GetAccountLimits(ctx context.Context, id entity.AccountID) (entity.AccountLimits, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.accounts[id]; !ok {
+		return entity.AccountLimits{}, service.ErrAccountDoesNotExist
+	}
+	return s.limits[id], nil
+}
+
+// checkLimits returns an error unless a new payment fits limits of its sender. Must be called with s.mu held.
+func (s *PaymentsService) checkLimits(value entity.PaymentValue) error {
+	limits := s.limits[value.From]
+	if limits == (entity.AccountLimits{}) {
+		return nil
+	}
+	return service.CheckLimits(value.From, limits, s.limitUsage(value.From, value.Time), value.Amount)
+}
+
+// limitUsage sums up payments of an account made within the windows containing the given moment.
+// Must be called with s.mu held.
+func (s *PaymentsService) limitUsage(id entity.AccountID, at time.Time) service.LimitUsage {
+	day, month := service.LimitWindows(at)
+	usage := service.LimitUsage{
+		DailyAmount:   money.NewNumericFromInt64(0),
+		MonthlyAmount: money.NewNumericFromInt64(0),
+	}
+	for _, p := range s.payments {
+		if p.Value.From != id || p.Value.Time.Before(month) {
+			continue
+		}
+		usage.MonthlyAmount = usage.MonthlyAmount.Add(p.Value.Amount)
+		usage.MonthlyCount++
+		if !p.Value.Time.Before(day) {
+			usage.DailyAmount = usage.DailyAmount.Add(p.Value.Amount)
+			usage.DailyCount++
+		}
+	}
+	return usage
+}
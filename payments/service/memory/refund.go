@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+)
+
+func (s *PaymentsService) Refund(ctx context.Context, id entity.PaymentID, amount *money.Numeric) (entity.PaymentID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id <= 0 || int(id) > len(s.payments) {
+		return 0, service.ErrPaymentNotFound
+	}
+	original := s.payments[id-1]
+	if original.Value.Exchange != nil || original.Value.RefundOf != 0 {
+		return 0, service.ErrNotRefundable
+	}
+
+	refund := original.Value.Amount
+	for _, p := range s.payments {
+		if p.Value.RefundOf == id {
+			refund = refund.Sub(p.Value.Amount)
+		}
+	}
+	if amount != nil {
+		currency, ok := s.currencies.Lookup(original.Value.Currency)
+		if !ok {
+			return 0, service.ErrIncompatibleCurrency
+		}
+		if !amount.IsPositive() || !amount.FitsDecimalPlaces(currency.MinorUnits) {
+			return 0, service.ErrInvalidAmount
+		}
+		if refund.LessThan(*amount) {
+			return 0, service.ErrRefundExceedsPayment
+		}
+		refund = *amount
+	}
+	if !refund.IsPositive() {
+		return 0, service.ErrRefundExceedsPayment
+	}
+
+	return s.transfer(transferRequest{
+		value: entity.PaymentValue{
+			From:     original.Value.To,
+			To:       original.Value.From,
+			Amount:   refund,
+			Currency: original.Value.Currency,
+			RefundOf: id,
+		},
+	})
+}
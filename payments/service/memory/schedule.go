@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"time"
+)
+
+func (s *PaymentsService) ScheduleTransfer(ctx context.Context, st entity.ScheduledTransfer) (entity.ScheduledTransfer, error) {
+	if err := s.validateTransfer(st.From, st.To, st.Amount, st.Currency); err != nil {
+		return entity.ScheduledTransfer{}, err
+	}
+	st, err := service.NewScheduledTransfer(st, time.Now().UTC())
+	if err != nil {
+		return entity.ScheduledTransfer{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range [2]entity.AccountID{st.From, st.To} {
+		if _, exists := s.accounts[id]; !exists {
+			return entity.ScheduledTransfer{}, service.ErrAccountDoesNotExist
+		}
+	}
+	st.Id = entity.ScheduledTransferID(len(s.schedules) + 1)
+	s.schedules = append(s.schedules, st)
+	return st, nil
+}
+
+func (s *PaymentsService) GetScheduledTransfers(ctx context.Context, from entity.AccountID) ([]entity.ScheduledTransfer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if from != "" {
+		if _, exists := s.accounts[from]; !exists {
+			return nil, service.ErrAccountDoesNotExist
+		}
+	}
+	schedules := make([]entity.ScheduledTransfer, 0)
+	for _, st := range s.schedules {
+		if from == "" || st.From == from {
+			schedules = append(schedules, st)
+		}
+	}
+	return schedules, nil
+}
+
+func (s *PaymentsService) CancelScheduledTransfer(ctx context.Context, id entity.ScheduledTransferID) (entity.ScheduledTransfer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id <= 0 || int(id) > len(s.schedules) {
+		return entity.ScheduledTransfer{}, service.ErrScheduleNotFound
+	}
+	st, err := service.CancelSchedule(s.schedules[id-1])
+	if err != nil {
+		return entity.ScheduledTransfer{}, err
+	}
+	s.schedules[id-1] = st
+	return st, nil
+}
+
+func (s *PaymentsService) GetScheduledRuns(ctx context.Context, id entity.ScheduledTransferID) ([]entity.ScheduledRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id <= 0 || int(id) > len(s.schedules) {
+		return nil, service.ErrScheduleNotFound
+	}
+	runs := make([]entity.ScheduledRun, 0)
+	for _, run := range s.runs {
+		if run.Schedule == id {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+func (s *PaymentsService) RunScheduledTransfers(ctx context.Context, now time.Time, limit int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Schedules which failed internally stay due, so they're skipped for the rest of the pass
+	// instead of blocking the others.
+	failed := make(map[entity.ScheduledTransferID]bool)
+	var firstErr error
+	for ran := 0; ran < limit; {
+		st, found := s.nextDueSchedule(now, failed)
+		if !found {
+			return ran, firstErr
+		}
+		paymentId, err := s.runScheduledTransfer(st)
+		if err != nil && !service.IsRunFailure(err) {
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed[st.Id] = true
+			continue
+		}
+		var run entity.ScheduledRun
+		s.schedules[st.Id-1], run = service.RecordRun(st, now, paymentId, err)
+		run.Id = entity.ScheduledRunID(len(s.runs) + 1)
+		s.runs = append(s.runs, run)
+		ran++
+	}
+	return limit, firstErr
+}
+
+// nextDueSchedule returns the active scheduled transfer which is overdue the most at now, except those in skip.
+func (s *PaymentsService) nextDueSchedule(now time.Time, skip map[entity.ScheduledTransferID]bool) (entity.ScheduledTransfer, bool) {
+	var (
+		due   entity.ScheduledTransfer
+		found bool
+	)
+	for _, st := range s.schedules {
+		if st.Status != entity.ScheduleActive || st.NextRunAt.After(now) || skip[st.Id] {
+			continue
+		}
+		if !found || st.NextRunAt.Before(due.NextRunAt) {
+			due, found = st, true
+		}
+	}
+	return due, found
+}
+
+// runScheduledTransfer makes a transfer of st the same way Transfer does. Must be called with s.mu held.
+func (s *PaymentsService) runScheduledTransfer(st entity.ScheduledTransfer) (entity.PaymentID, error) {
+	if err := s.validateTransfer(st.From, st.To, st.Amount, st.Currency); err != nil {
+		return 0, err
+	}
+	return s.transfer(transferRequest{
+		value: entity.PaymentValue{
+			From:     st.From,
+			To:       st.To,
+			Amount:   st.Amount,
+			Currency: st.Currency,
+		},
+		chargeFee: true,
+	})
+}
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/fx"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"sync"
+	"time"
+)
+
+// DefaultIdempotencyRetention is how long idempotency keys are remembered unless configured otherwise.
+const DefaultIdempotencyRetention = 24 * time.Hour
+
+// DefaultQuoteTTL is how long exchange rates stay locked unless configured otherwise.
+const DefaultQuoteTTL = 30 * time.Second
+
+// DefaultHoldTTL is how long holds reserve money unless configured otherwise.
+const DefaultHoldTTL = 7 * 24 * time.Hour
+
+// PaymentsService implements service.PaymentsService interface by keeping all the data in memory.
+// It behaves the same way persistent.PaymentsService does, but loses everything on exit,
+// so it's only meant for tests and local development.
+type PaymentsService struct {
+	// currencies are the currencies accounts and payments may be denominated in.
+	currencies *money.CurrencyRegistry
+
+	// idempotencyRetention is a window during which a replayed Transfer returns the original payment.
+	idempotencyRetention time.Duration
+
+	// rates is a source of exchange rates for cross-currency transfers, which are disabled if it's nil.
+	rates fx.RateProvider
+
+	// quoteTTL is how long a quoted exchange rate can be used.
+	quoteTTL time.Duration
+
+	// holdTTL is how long a hold reserves money unless captured or voided.
+	holdTTL time.Duration
+
+	// fees are charged from senders of transfers.
+	fees service.Fees
+
+	// mu guards all the data below. Every operation holds it till the end,
+	// which gives the same isolation persistent.PaymentsService gets from row locks.
+	mu sync.Mutex
+
+	accounts map[entity.AccountID]*entity.Account
+
+	limits map[entity.AccountID]entity.AccountLimits
+
+	// payments are ordered by id, payment with id N is payments[N-1]
+	payments []entity.Payment
+
+	// idempotencyKeys maps keys to payments made with them.
+	idempotencyKeys map[entity.IdempotencyKey]entity.PaymentID
+
+	// quotes are ordered by id, quote with id N is quotes[N-1]
+	quotes []entity.Quote
+
+	// holds are ordered by id, hold with id N is holds[N-1]
+	holds []entity.Hold
+
+	// journal lists all balance changes in order they were made
+	journal []journalEntry
+
+	// events are ordered by id, event with id N is events[N-1]
+	events []entity.Event
+
+	// published is how many events from the start of events were published
+	published int
+
+	webhooks map[entity.WebhookID]entity.Webhook
+
+	// lastWebhookId is the id of the latest created webhook, deleted ones included
+	lastWebhookId entity.WebhookID
+
+	// deliveries are ordered by id, delivery with id N is deliveries[N-1].
+	// Deliveries of deleted webhooks are kept, but never shown.
+	deliveries []entity.WebhookDelivery
+
+	// enqueued prevents delivering the same event to a webhook twice
+	enqueued map[webhookEvent]bool
+
+	// delivering are deliveries being sent right now, see DeliverWebhooks
+	delivering map[entity.DeliveryID]bool
+
+	// schedules are ordered by id, scheduled transfer with id N is schedules[N-1]
+	schedules []entity.ScheduledTransfer
+
+	// runs of all scheduled transfers are ordered by id, run with id N is runs[N-1]
+	runs []entity.ScheduledRun
+
+	// audit is the audit log ordered by id, entry with id N is audit[N-1]
+	audit []entity.AuditEntry
+}
+
+// Option configures optional settings of PaymentsService.
+type Option func(s *PaymentsService)
+
+// WithIdempotencyRetention sets how long idempotency keys of transfers are remembered.
+func WithIdempotencyRetention(retention time.Duration) Option {
+	return func(s *PaymentsService) {
+		s.idempotencyRetention = retention
+	}
+}
+
+// WithCurrencyRegistry sets currencies the service operates with.
+func WithCurrencyRegistry(currencies *money.CurrencyRegistry) Option {
+	return func(s *PaymentsService) {
+		s.currencies = currencies
+	}
+}
+
+// WithRateProvider enables cross-currency transfers using exchange rates of the given provider.
+func WithRateProvider(rates fx.RateProvider) Option {
+	return func(s *PaymentsService) {
+		s.rates = rates
+	}
+}
+
+// WithQuoteTTL sets how long quoted exchange rates stay locked.
+func WithQuoteTTL(ttl time.Duration) Option {
+	return func(s *PaymentsService) {
+		s.quoteTTL = ttl
+	}
+}
+
+// WithHoldTTL sets how long holds reserve money.
+func WithHoldTTL(ttl time.Duration) Option {
+	return func(s *PaymentsService) {
+		s.holdTTL = ttl
+	}
+}
+
+// WithFees enables charging fees for transfers.
+func WithFees(fees service.Fees) Option {
+	return func(s *PaymentsService) {
+		s.fees = fees
+	}
+}
+
+// NewPaymentsService returns new empty PaymentsService.
+func NewPaymentsService(opts ...Option) *PaymentsService {
+	s := &PaymentsService{
+		currencies:           money.DefaultCurrencyRegistry,
+		idempotencyRetention: DefaultIdempotencyRetention,
+		quoteTTL:             DefaultQuoteTTL,
+		holdTTL:              DefaultHoldTTL,
+		accounts:             make(map[entity.AccountID]*entity.Account),
+		limits:               make(map[entity.AccountID]entity.AccountLimits),
+		idempotencyKeys:      make(map[entity.IdempotencyKey]entity.PaymentID),
+		webhooks:             make(map[entity.WebhookID]entity.Webhook),
+		enqueued:             make(map[webhookEvent]bool),
+		delivering:           make(map[entity.DeliveryID]bool),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/payments/service/servicetest"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestPaymentsService_Conformance(t *testing.T) {
+	servicetest.Run(t, func(t *testing.T, cfg servicetest.Config) service.PaymentsService {
+		return NewPaymentsService(
+			WithRateProvider(cfg.Rates),
+			WithQuoteTTL(cfg.QuoteTTL),
+			WithIdempotencyRetention(cfg.IdempotencyRetention),
+			WithHoldTTL(cfg.HoldTTL),
+			WithFees(cfg.Fees),
+		)
+	})
+}
+
+func TestPaymentsService_ConcurrentTransfers(t *testing.T) {
+	ctx := context.Background()
+	svc := NewPaymentsService()
+	for _, id := range [...]entity.AccountID{"bob", "alice"} {
+		err := svc.CreateAccount(ctx, id, money.NewNumericFromInt64(100), "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// bob can afford only 50 of 75 concurrent transfers of 2 USD
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		insufficient int
+	)
+	transfer := func(from, to entity.AccountID) {
+		defer wg.Done()
+		_, err := svc.Transfer(ctx, from, to, money.NewNumericFromInt64(2), "USD", "")
+		if errors.Is(err, service.ErrInsufficientFunds) {
+			mu.Lock()
+			insufficient++
+			mu.Unlock()
+		} else if err != nil {
+			t.Error(err)
+		}
+	}
+	for i := 0; i < 75; i++ {
+		wg.Add(1)
+		go transfer("bob", "alice")
+	}
+	wg.Wait()
+
+	assert.Equal(t, insufficient, 25)
+	_, err := svc.Transfer(ctx, "bob", "alice", money.NewNumericFromStringMust("0.01"), "USD", "")
+	if !errors.Is(err, service.ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds, got err=%v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+func (s *PaymentsService) GetBalance(ctx context.Context, id entity.AccountID, at time.Time) (entity.Balance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[id]
+	if !ok {
+		return entity.Balance{}, service.ErrAccountDoesNotExist
+	}
+
+	balance := money.NewNumericFromInt64(0)
+	for _, e := range s.journal {
+		if e.Account == id && !e.Time.After(at) {
+			balance = balance.Add(e.Amount)
+		}
+	}
+	return entity.Balance{
+		Account:  id,
+		At:       at,
+		Amount:   balance,
+		Currency: account.Currency,
+	}, nil
+}
+
+func (s *PaymentsService) GetStatement(ctx context.Context, id entity.AccountID, since, until time.Time) (entity.Statement, error) {
+	until, err := service.StatementPeriod(since, until)
+	if err != nil {
+		return entity.Statement{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[id]
+	if !ok {
+		return entity.Statement{}, service.ErrAccountDoesNotExist
+	}
+
+	opening := money.NewNumericFromInt64(0)
+	var lines []entity.StatementLine
+	for _, e := range s.journal {
+		if e.Account != id || !e.Time.Before(until) {
+			continue
+		}
+		if e.Time.Before(since) {
+			opening = opening.Add(e.Amount)
+			continue
+		}
+		// Entries of a payment are adjacent, they make a single line.
+		if n := len(lines); n > 0 && e.Payment != 0 && lines[n-1].Payment == e.Payment {
+			lines[n-1].Amount = lines[n-1].Amount.Add(e.Amount)
+			continue
+		}
+		line := entity.StatementLine{
+			Time:    e.Time,
+			Payment: e.Payment,
+			Amount:  e.Amount,
+		}
+		if e.Payment != 0 {
+			value := s.payments[e.Payment-1].Value
+			line.Counterparty = value.From
+			if value.From == id {
+				line.Counterparty = value.To
+			}
+		}
+		lines = append(lines, line)
+	}
+	return service.NewStatement(*account, since, until, opening, lines), nil
+}
@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+func (s *PaymentsService) Transfer(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency, idempotencyKey entity.IdempotencyKey) (entity.PaymentID, error) {
+	if err := s.validateTransfer(from, to, amount, cur); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.transfer(transferRequest{
+		value: entity.PaymentValue{
+			From:     from,
+			To:       to,
+			Amount:   amount,
+			Currency: cur,
+		},
+		idempotencyKey: idempotencyKey,
+		chargeFee:      true,
+	})
+}
+
+// validateTransfer checks transfer parameters which don't depend on the state of accounts.
+func (s *PaymentsService) validateTransfer(from, to entity.AccountID, amount money.Numeric, cur money.Currency) error {
+	if from == to {
+		return service.ErrBadTransferTarget
+	}
+
+	currency, ok := s.currencies.Lookup(cur)
+	if !ok {
+		return service.ErrIncompatibleCurrency
+	}
+
+	if !amount.IsPositive() || !amount.FitsDecimalPlaces(currency.MinorUnits) {
+		return service.ErrInvalidAmount
+	}
+	return nil
+}
+
+// transferRequest is a validated request to move money between two accounts.
+type transferRequest struct {
+	// value describes the payment to be made
+	value entity.PaymentValue
+
+	idempotencyKey entity.IdempotencyKey
+
+	// quoteExpiresAt is a moment after which the exchange rate of value can't be used for a new payment
+	quoteExpiresAt time.Time
+
+	// hold is a hold being captured by the transfer, money reserved by it is spendable
+	hold entity.HoldID
+
+	// chargeFee tells whether the sender pays a fee, see service.Fees
+	chargeFee bool
+}
+
+// transfer debits value.Amount from one account and credits value.CreditAmount() to another.
+// Must be called with s.mu held.
+func (s *PaymentsService) transfer(req transferRequest) (entity.PaymentID, error) {
+	value, idempotencyKey := req.value, req.idempotencyKey
+	from, to := value.From, value.To
+
+	value.Time = time.Now().UTC()
+
+	if idempotencyKey != "" {
+		if paymentId, found := s.idempotencyKeys[idempotencyKey]; found {
+			previous := s.payments[paymentId-1]
+			if previous.Value.Time.After(value.Time.Add(-s.idempotencyRetention)) {
+				if !service.SameTransfer(previous.Value, value) {
+					return 0, service.ErrIdempotencyKeyReused
+				}
+				return previous.Id, nil
+			}
+			// The key is past its retention window, so it's treated as a brand new one.
+			delete(s.idempotencyKeys, idempotencyKey)
+		}
+	}
+
+	if value.Exchange != nil && value.Time.After(req.quoteExpiresAt) {
+		return 0, service.ErrQuoteExpired
+	}
+
+	if req.chargeFee {
+		currency, _ := s.currencies.Lookup(value.Currency)
+		var err error
+		value.Fee, value.FeeAccount, err = s.fees.Charge(value, currency)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	accountFrom, ok := s.accounts[from]
+	if !ok {
+		return 0, service.ErrAccountDoesNotExist
+	}
+	accountTo, ok := s.accounts[to]
+	if !ok {
+		return 0, service.ErrAccountDoesNotExist
+	}
+	var accountFee *entity.Account
+	if value.FeeAccount != "" {
+		accountFee, ok = s.accounts[value.FeeAccount]
+		if !ok {
+			return 0, service.ErrAccountDoesNotExist
+		}
+	}
+
+	if err := service.CheckCanDebit(*accountFrom); err != nil {
+		return 0, err
+	}
+	if err := service.CheckCanCredit(*accountTo); err != nil {
+		return 0, err
+	}
+
+	if accountFrom.Currency != value.Currency {
+		return 0, service.ErrIncompatibleCurrency
+	}
+
+	if accountTo.Currency != value.CreditCurrency() {
+		return 0, service.ErrIncompatibleCurrency
+	}
+
+	if accountFee != nil {
+		if accountFee.Currency != value.Currency {
+			return 0, service.NewErrInternal(fmt.Errorf("revenue account %s is not in %s", value.FeeAccount, value.Currency))
+		}
+		if err := service.CheckCanCredit(*accountFee); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := s.checkLimits(value); err != nil {
+		return 0, err
+	}
+
+	// Money reserved by holds can't be spent, see Authorize.
+	held := s.heldAmount(from, value.Time, req.hold)
+
+	newBalanceFrom := accountFrom.Balance.Sub(value.Amount).Sub(value.Fee)
+	if err := service.CheckSufficientFunds(*accountFrom, newBalanceFrom.Sub(held)); err != nil {
+		return 0, err
+	}
+
+	accountFrom.Balance = newBalanceFrom
+	accountTo.Balance = accountTo.Balance.Add(value.CreditAmount())
+	if accountFee != nil {
+		accountFee.Balance = accountFee.Balance.Add(value.Fee)
+	}
+
+	payment := entity.Payment{
+		Id:    entity.PaymentID(len(s.payments) + 1),
+		Value: value,
+	}
+	event, err := service.NewPaymentCreatedEvent(payment.Id, value)
+	if err != nil {
+		return 0, service.NewErrInternal(err)
+	}
+	s.payments = append(s.payments, payment)
+	s.journalPayment(payment)
+	s.recordEvent(event)
+	if idempotencyKey != "" {
+		s.idempotencyKeys[idempotencyKey] = payment.Id
+	}
+	return payment.Id, nil
+}
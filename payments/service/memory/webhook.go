@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"sort"
+	"time"
+)
+
+type webhookEvent struct {
+	webhook entity.WebhookID
+	event   entity.EventID
+}
+
+func (s *PaymentsService) CreateWebhook(ctx context.Context, webhook entity.Webhook) (entity.Webhook, error) {
+	if err := service.ValidateWebhook(webhook); err != nil {
+		return entity.Webhook{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if webhook.Account != "" {
+		if _, exists := s.accounts[webhook.Account]; !exists {
+			return entity.Webhook{}, service.ErrAccountDoesNotExist
+		}
+	}
+	s.lastWebhookId++
+	webhook.Id = s.lastWebhookId
+	webhook.EventTypes = append([]entity.EventType(nil), webhook.EventTypes...)
+	webhook.CreatedAt = time.Now().UTC()
+	s.webhooks[webhook.Id] = webhook
+	return webhook, nil
+}
+
+func (s *PaymentsService) GetWebhooks(ctx context.Context) ([]entity.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhooks := make([]entity.Webhook, 0, len(s.webhooks))
+	for _, webhook := range s.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].Id < webhooks[j].Id
+	})
+	return webhooks, nil
+}
+
+func (s *PaymentsService) DeleteWebhook(ctx context.Context, id entity.WebhookID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.webhooks[id]; !exists {
+		return service.ErrWebhookNotFound
+	}
+	delete(s.webhooks, id)
+	return nil
+}
+
+func (s *PaymentsService) EnqueueWebhookDeliveries(ctx context.Context, events []entity.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhooks := make([]entity.Webhook, 0, len(s.webhooks))
+	for _, webhook := range s.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].Id < webhooks[j].Id
+	})
+
+	now := time.Now().UTC()
+	for _, event := range events {
+		for _, webhook := range webhooks {
+			key := webhookEvent{webhook.Id, event.Id}
+			if s.enqueued[key] || !service.WebhookMatches(webhook, event) {
+				continue
+			}
+			delivery := service.NewWebhookDelivery(webhook.Id, event, now)
+			delivery.Id = entity.DeliveryID(len(s.deliveries) + 1)
+			s.deliveries = append(s.deliveries, delivery)
+			s.enqueued[key] = true
+		}
+	}
+	return nil
+}
+
+func (s *PaymentsService) DeliverWebhooks(ctx context.Context, now time.Time, limit int, deliver service.DeliverFunc) (int, error) {
+	// Sending may be slow, so don't block other operations meanwhile,
+	// deliveries being sent are skipped by concurrent calls instead.
+	s.mu.Lock()
+	var due []entity.WebhookDelivery
+	for _, d := range s.deliveries {
+		if _, exists := s.webhooks[d.Webhook]; !exists || s.delivering[d.Id] {
+			continue
+		}
+		if d.Status == entity.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	webhooks := make(map[entity.DeliveryID]entity.Webhook, len(due))
+	for _, d := range due {
+		s.delivering[d.Id] = true
+		webhooks[d.Id] = s.webhooks[d.Webhook]
+	}
+	s.mu.Unlock()
+
+	for i, d := range due {
+		err := deliver(ctx, webhooks[d.Id], d)
+		if ctx.Err() != nil {
+			// Delivery was interrupted rather than failed, it's going to be retried.
+			s.release(due[i:])
+			return i, ctx.Err()
+		}
+
+		s.mu.Lock()
+		delete(s.delivering, d.Id)
+		s.deliveries[d.Id-1] = service.RecordDeliveryAttempt(s.deliveries[d.Id-1], now, err)
+		s.mu.Unlock()
+	}
+	return len(due), nil
+}
+
+// release makes deliveries available to DeliverWebhooks again.
+func (s *PaymentsService) release(deliveries []entity.WebhookDelivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range deliveries {
+		delete(s.delivering, d.Id)
+	}
+}
+
+func (s *PaymentsService) GetWebhookDeliveries(ctx context.Context, query service.DeliveriesQuery) ([]entity.WebhookDelivery, error) {
+	limit, err := query.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []entity.WebhookDelivery
+	for i := len(s.deliveries) - 1; i >= 0 && len(result) < limit; i-- {
+		d := s.deliveries[i]
+		if _, exists := s.webhooks[d.Webhook]; !exists {
+			continue
+		}
+		if query.Webhook != 0 && d.Webhook != query.Webhook {
+			continue
+		}
+		if query.Status != "" && d.Status != query.Status {
+			continue
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+func (s *PaymentsService) ReplayWebhookDelivery(ctx context.Context, id entity.DeliveryID, now time.Time) (entity.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id <= 0 || int(id) > len(s.deliveries) {
+		return entity.WebhookDelivery{}, service.ErrDeliveryNotFound
+	}
+	delivery := s.deliveries[id-1]
+	if _, exists := s.webhooks[delivery.Webhook]; !exists {
+		return entity.WebhookDelivery{}, service.ErrDeliveryNotFound
+	}
+	delivery = service.ReplayDelivery(delivery, now)
+	s.deliveries[id-1] = delivery
+	return delivery, nil
+}
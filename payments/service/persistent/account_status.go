@@ -0,0 +1,36 @@
+package persistent
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
+)
+
+func (s PaymentsService) SetAccountStatus(ctx context.Context, id entity.AccountID, status entity.AccountStatus, reason string) (entity.Account, error) {
+	var account entity.Account
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		// The lock makes sure the balance can't change between the check and closing the account.
+		accounts, err := s.lockAccounts(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		account = accounts[id]
+
+		if err := service.CheckStatusChange(account, status, reason); err != nil {
+			return err
+		}
+
+		const sql = `UPDATE account SET status = ?, status_reason = ? WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, sql, string(status), reason, id); err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		account.Status = status
+		account.StatusReason = reason
+		return nil
+	})
+	if err != nil {
+		return entity.Account{}, err
+	}
+	return account, nil
+}
@@ -0,0 +1,90 @@
+package persistent
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"strings"
+	"time"
+)
+
+func (s PaymentsService) AppendAuditEntry(ctx context.Context, entry entity.AuditEntry) error {
+	const sql = `--audit_log_insert
+		INSERT INTO audit_log (time, actor, request_id, method, params, error) VALUES (?, ?, ?, ?, ?::jsonb, ?)
+	`
+	_, err := s.pg.ExecContext(ctx, sql,
+		entry.Time, entry.Actor, entry.RequestId, entry.Method, string(entry.Params), entry.Error,
+	)
+	if err != nil {
+		return NewInternalErrorFromDBError(err)
+	}
+	return nil
+}
+
+func (s PaymentsService) GetAuditLog(ctx context.Context, query service.AuditQuery) ([]entity.AuditEntry, error) {
+	limit, err := query.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Id        int64     `sql:"id"`
+		Time      time.Time `sql:"time"`
+		Actor     string    `sql:"actor"`
+		RequestId string    `sql:"request_id"`
+		Method    string    `sql:"method"`
+		Params    string    `sql:"params"`
+		Error     string    `sql:"error"`
+	}
+	// Only filters which are set make it to the query, since go-pg renders zero values of parameters as NULL.
+	conditions := []string{"true"}
+	if query.Actor != "" {
+		conditions = append(conditions, `actor = ?actor`)
+	}
+	if query.Method != "" {
+		conditions = append(conditions, `method = ?method`)
+	}
+	if !query.Since.IsZero() {
+		conditions = append(conditions, `time >= ?since`)
+	}
+	if !query.Until.IsZero() {
+		conditions = append(conditions, `time < ?until`)
+	}
+	sql := `--audit_log_list
+		SELECT id, time, actor, request_id, method, params::text AS params, error
+		FROM audit_log
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY id DESC
+		LIMIT ?limit
+	`
+	_, err = s.pg.QueryContext(ctx, &rows, sql, struct {
+		Actor  string    `sql:"actor"`
+		Method string    `sql:"method"`
+		Since  time.Time `sql:"since"`
+		Until  time.Time `sql:"until"`
+		Limit  int       `sql:"limit"`
+	}{
+		Actor:  query.Actor,
+		Method: query.Method,
+		Since:  query.Since,
+		Until:  query.Until,
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, NewInternalErrorFromDBError(err)
+	}
+	entries := make([]entity.AuditEntry, 0, len(rows))
+	for _, r := range rows {
+		entries = append(entries, entity.AuditEntry{
+			Id:        entity.AuditEntryID(r.Id),
+			Time:      r.Time,
+			Actor:     r.Actor,
+			RequestId: r.RequestId,
+			Method:    r.Method,
+			Params:    json.RawMessage(r.Params),
+			Error:     r.Error,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,92 @@
+package persistent
+
+import (
+	"context"
+	"github.com/go-pg/pg/v10"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
+)
+
+func (s PaymentsService) TransferBatch(ctx context.Context, legs []service.TransferLeg) ([]entity.PaymentID, error) {
+	if len(legs) == 0 || len(legs) > service.MaxTransferBatchLegs {
+		return nil, service.ErrBadBatch
+	}
+
+	accountIds := make([]entity.AccountID, 0, 2*len(legs))
+	for i, leg := range legs {
+		if err := s.validateTransfer(leg.From, leg.To, leg.Amount, leg.Currency); err != nil {
+			return nil, service.NewErrBatchLeg(i, err)
+		}
+		accountIds = append(accountIds, leg.From, leg.To)
+		if revenue, ok := s.fees.RevenueAccounts[leg.Currency]; ok && s.fees.Schedule != nil {
+			accountIds = append(accountIds, revenue)
+		}
+	}
+
+	paymentIds := make([]entity.PaymentID, len(legs))
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		// Missing accounts are reported with the index of their leg. Accounts are never deleted,
+		// so they can be checked before locking.
+		existing, err := s.existingAccounts(ctx, tx, accountIds)
+		if err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		for i, leg := range legs {
+			if !existing[leg.From] || !existing[leg.To] {
+				return service.NewErrBatchLeg(i, service.ErrAccountDoesNotExist)
+			}
+		}
+
+		// Lock all the accounts upfront in the same global order single transfers use,
+		// so batches can't deadlock with each other or with other operations.
+		// Legs lock their accounts once again, which is a no-op for locks already held.
+		// A missing revenue account is left to the leg charging a fee, so it's reported with its index.
+		lock := make([]entity.AccountID, 0, len(accountIds))
+		for _, id := range accountIds {
+			if existing[id] {
+				lock = append(lock, id)
+			}
+		}
+		if _, err = s.lockAccounts(ctx, tx, lock...); err != nil {
+			return err
+		}
+
+		for i, leg := range legs {
+			var err error
+			paymentIds[i], err = s.transferInTx(ctx, tx, transferRequest{
+				value: entity.PaymentValue{
+					From:     leg.From,
+					To:       leg.To,
+					Amount:   leg.Amount,
+					Currency: leg.Currency,
+				},
+				chargeFee: true,
+			})
+			if err != nil {
+				return service.NewErrBatchLeg(i, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paymentIds, nil
+}
+
+// existingAccounts tells which of ids are accounts.
+func (s PaymentsService) existingAccounts(ctx context.Context, tx postgres.Database, ids []entity.AccountID) (map[entity.AccountID]bool, error) {
+	var rows []struct {
+		Id string `sql:"id"`
+	}
+	_, err := tx.QueryContext(ctx, &rows, `SELECT id FROM account WHERE id IN (?)`, pg.In(ids))
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[entity.AccountID]bool, len(rows))
+	for _, r := range rows {
+		existing[entity.AccountID(r.Id)] = true
+	}
+	return existing, nil
+}
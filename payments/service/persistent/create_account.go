@@ -5,7 +5,9 @@ import (
 	"github.com/lightsgoout/fintech-go/payments/entity"
 	"github.com/lightsgoout/fintech-go/payments/service"
 	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
 	"strings"
+	"time"
 )
 
 func (s PaymentsService) CreateAccount(ctx context.Context, id entity.AccountID, balance money.Numeric, cur money.Currency) error {
@@ -13,23 +15,53 @@ func (s PaymentsService) CreateAccount(ctx context.Context, id entity.AccountID,
 		return service.ErrInsufficientFunds
 	}
 
-	if !money.IsKnownCurrency(cur) {
+	currency, ok := s.currencies.Lookup(cur)
+	if !ok {
 		return service.ErrIncompatibleCurrency
 	}
 
+	if !balance.FitsDecimalPlaces(currency.MinorUnits) {
+		return service.ErrInvalidAmount
+	}
+
 	if id == "" {
 		return service.ErrBadAccountID
 	}
 
+	now := time.Now().UTC()
+	event, err := service.NewAccountCreatedEvent(id, balance, cur, now)
+	if err != nil {
+		return service.NewErrInternal(err)
+	}
+
 	const sql = `INSERT INTO account (id, currency, balance) VALUES (?id, ?currency, ?balance)`
-	_, err := s.pg.ExecContext(ctx, sql, struct {
-		Id       string `sql:"id"`
-		Balance  string `sql:"balance"`
-		Currency string `sql:"currency"`
-	}{
-		Id:       string(id),
-		Balance:  balance.String(),
-		Currency: string(cur),
+	err = postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		_, err := tx.ExecContext(ctx, sql, struct {
+			Id       string        `sql:"id"`
+			Balance  money.Numeric `sql:"balance"`
+			Currency string        `sql:"currency"`
+		}{
+			Id:       string(id),
+			Balance:  balance,
+			Currency: string(cur),
+		})
+		if err != nil {
+			return err
+		}
+		// Initial balance has no payment behind it, but still has to be journaled
+		// for the balance to be provable from history.
+		if balance.IsPositive() {
+			err = s.createJournalEntry(ctx, tx, journalEntry{
+				Time:     now,
+				Account:  id,
+				Amount:   balance,
+				Currency: cur,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return s.createEvent(ctx, tx, event)
 	})
 	if err != nil {
 		if strings.Contains(err.Error(), `duplicate key value violates unique constraint "account_pkey"`) {
@@ -62,4 +62,11 @@ func TestPaymentsService_CreateAccount(t *testing.T) {
 			t.Errorf("expected ErrBadAccountID, got err=%v", err)
 		}
 	}))
+
+	t.Run("balance must fit currency minor units", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		err := svc.CreateAccount(env.Ctx, "bob", money.NewNumericFromStringMust("10.005"), "EUR")
+		if !errors.Is(err, service.ErrInvalidAmount) {
+			t.Errorf("expected ErrInvalidAmount, got err=%v", err)
+		}
+	}))
 }
@@ -0,0 +1,43 @@
+package persistent
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
+	"time"
+)
+
+func (s PaymentsService) SetCreditLimit(ctx context.Context, id entity.AccountID, limit money.Numeric, unbounded bool) (entity.Account, error) {
+	var account entity.Account
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		// The lock makes sure the balance can't go down between the check and the change.
+		accounts, err := s.lockAccounts(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		account = accounts[id]
+
+		currency, _ := s.currencies.Lookup(account.Currency)
+		held, err := s.getHeldAmount(ctx, tx, id, time.Now().UTC(), 0)
+		if err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		if err := service.CheckCreditLimitChange(account, account.Balance.Sub(held), limit, unbounded, currency); err != nil {
+			return err
+		}
+
+		const sql = `UPDATE account SET credit_limit = ?, unbounded = ? WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, sql, limit, unbounded, id); err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		account.CreditLimit = limit
+		account.Unbounded = unbounded
+		return nil
+	})
+	if err != nil {
+		return entity.Account{}, err
+	}
+	return account, nil
+}
@@ -0,0 +1,44 @@
+package persistent
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
+)
+
+// SyncCurrencies makes all currencies of the registry known to the database,
+// so accounts and payments can reference them. Existing currencies are updated in place.
+func (s PaymentsService) SyncCurrencies(ctx context.Context) error {
+	const sql = `--currency_upsert
+		INSERT INTO currency
+			(code, numeric_code, minor_units, symbol)
+		VALUES
+			(?code, ?numeric_code, ?minor_units, ?symbol)
+		ON CONFLICT (code) DO UPDATE SET
+			numeric_code = excluded.numeric_code,
+			minor_units = excluded.minor_units,
+			symbol = excluded.symbol`
+
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		for _, c := range s.currencies.Currencies() {
+			_, err := tx.ExecContext(ctx, sql, struct {
+				Code        string `sql:"code"`
+				NumericCode int    `sql:"numeric_code"`
+				MinorUnits  int32  `pg:"minor_units,use_zero"`
+				Symbol      string `sql:"symbol"`
+			}{
+				Code:        string(c.Code),
+				NumericCode: c.NumericCode,
+				MinorUnits:  c.MinorUnits,
+				Symbol:      c.Symbol,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return NewInternalErrorFromDBError(err)
+	}
+	return nil
+}
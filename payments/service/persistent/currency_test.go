@@ -0,0 +1,36 @@
+package persistent
+
+import (
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/lightsgoout/fintech-go/pkg/testing/isolation"
+	"testing"
+)
+
+func TestPaymentsService_SyncCurrencies(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	currencies := money.MustNewCurrencyRegistry(
+		money.CurrencyInfo{Code: "USD", NumericCode: 840, MinorUnits: 2, Symbol: "$"},
+		money.CurrencyInfo{Code: "JPY", NumericCode: 392, MinorUnits: 0, Symbol: "¥"},
+	)
+	svc := NewPaymentsService(env.Tx, WithCurrencyRegistry(currencies))
+
+	t.Run("new currency becomes usable", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		if err := svc.SyncCurrencies(env.Ctx); err != nil {
+			t.Error(err)
+		}
+		err := svc.CreateAccount(env.Ctx, "bob", money.NewNumericFromInt64(1000), "JPY")
+		if err != nil {
+			t.Error(err)
+		}
+	}))
+
+	t.Run("sync is repeatable", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if err := svc.SyncCurrencies(env.Ctx); err != nil {
+				t.Error(err)
+			}
+		}
+	}))
+}
@@ -0,0 +1,82 @@
+package persistent
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-pg/pg/v10"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
+	"time"
+)
+
+// createEvent records event in the outbox, it must be called in the transaction making the change
+// the event describes, so the event is published if and only if the change is committed.
+func (s PaymentsService) createEvent(ctx context.Context, tx postgres.Database, event entity.Event) error {
+	const sql = `--outbox_insert
+		INSERT INTO outbox (time, type, payload) VALUES (?, ?, ?::jsonb)
+	`
+	_, err := tx.ExecContext(ctx, sql, event.Time, string(event.Type), string(event.Payload))
+	return err
+}
+
+func (s PaymentsService) RelayEvents(ctx context.Context, limit int, publish service.PublishFunc) (int, error) {
+	var (
+		published  int
+		publishErr error
+	)
+	// Events stay locked while being published, so concurrent relays don't publish them twice,
+	// and SKIP LOCKED lets them go on with the next events meanwhile.
+	// NOTE: concurrent relays may publish events out of order.
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		var rows []struct {
+			Id      int64     `sql:"id"`
+			Time    time.Time `sql:"time"`
+			Type    string    `sql:"type"`
+			Payload string    `sql:"payload"`
+		}
+		const sql = `--outbox_pending
+			SELECT id, time, type, payload::text AS payload
+			FROM outbox
+			WHERE published_at IS NULL
+			ORDER BY id ASC
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		`
+		if _, err := tx.QueryContext(ctx, &rows, sql, limit); err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		events := make([]entity.Event, 0, len(rows))
+		ids := make([]int64, 0, len(rows))
+		for _, r := range rows {
+			events = append(events, entity.Event{
+				Id:      entity.EventID(r.Id),
+				Time:    r.Time,
+				Type:    entity.EventType(r.Type),
+				Payload: json.RawMessage(r.Payload),
+			})
+			ids = append(ids, r.Id)
+		}
+		if publishErr = publish(ctx, events); publishErr != nil {
+			return publishErr
+		}
+
+		_, err := tx.ExecContext(ctx, `UPDATE outbox SET published_at = now() WHERE id IN (?)`, pg.In(ids))
+		if err != nil {
+			return err
+		}
+		published = len(events)
+		return nil
+	})
+	if publishErr != nil {
+		return 0, publishErr
+	}
+	if err != nil {
+		return 0, NewInternalErrorFromDBError(err)
+	}
+	return published, nil
+}
@@ -0,0 +1,144 @@
+package persistent
+
+import (
+	"context"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/fx"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+func (s PaymentsService) QuoteExchange(ctx context.Context, from, to money.Currency) (entity.Quote, error) {
+	if !s.currencies.IsKnown(from) || !s.currencies.IsKnown(to) || from == to {
+		return entity.Quote{}, service.ErrIncompatibleCurrency
+	}
+	if s.rates == nil {
+		return entity.Quote{}, service.ErrRateUnavailable
+	}
+
+	rate, err := s.rates.Rate(ctx, from, to)
+	if err != nil {
+		if errors.Is(err, fx.ErrRateNotFound) {
+			return entity.Quote{}, service.ErrRateUnavailable
+		}
+		return entity.Quote{}, service.NewErrInternal(err)
+	}
+
+	quote := entity.Quote{
+		From:      from,
+		To:        to,
+		Rate:      rate,
+		ExpiresAt: time.Now().UTC().Add(s.quoteTTL),
+	}
+	quote.Id, err = s.createQuote(ctx, quote)
+	if err != nil {
+		return entity.Quote{}, NewInternalErrorFromDBError(err)
+	}
+	return quote, nil
+}
+
+func (s PaymentsService) TransferWithQuote(ctx context.Context, from, to entity.AccountID, amount money.Numeric, quoteId entity.QuoteID, idempotencyKey entity.IdempotencyKey) (entity.PaymentID, error) {
+	if from == to {
+		return 0, service.ErrBadTransferTarget
+	}
+
+	quote, err := s.getQuote(ctx, quoteId)
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return 0, service.ErrQuoteNotFound
+		}
+		return 0, NewInternalErrorFromDBError(err)
+	}
+
+	fromCurrency, ok := s.currencies.Lookup(quote.From)
+	if !ok {
+		return 0, service.ErrIncompatibleCurrency
+	}
+	toCurrency, ok := s.currencies.Lookup(quote.To)
+	if !ok {
+		return 0, service.ErrIncompatibleCurrency
+	}
+
+	if !amount.IsPositive() || !amount.FitsDecimalPlaces(fromCurrency.MinorUnits) {
+		return 0, service.ErrInvalidAmount
+	}
+	toAmount := amount.Mul(quote.Rate).Round(toCurrency.MinorUnits)
+	if !toAmount.IsPositive() {
+		return 0, service.ErrInvalidAmount
+	}
+
+	return s.transfer(ctx, transferRequest{
+		value: entity.PaymentValue{
+			From:     from,
+			To:       to,
+			Amount:   amount,
+			Currency: quote.From,
+			Exchange: &entity.Exchange{
+				Quote:      quote.Id,
+				Rate:       quote.Rate,
+				ToAmount:   toAmount,
+				ToCurrency: quote.To,
+			},
+		},
+		idempotencyKey: idempotencyKey,
+		// NOTE: a replay of an already made transfer succeeds even if the quote has expired since.
+		quoteExpiresAt: quote.ExpiresAt,
+	})
+}
+
+func (s PaymentsService) createQuote(ctx context.Context, quote entity.Quote) (entity.QuoteID, error) {
+	var result struct {
+		Id int64 `sql:"id"`
+	}
+	const sql = `--fx_quote_insert
+		INSERT INTO fx_quote
+			(from_currency, to_currency, rate, expires_at)
+		VALUES
+			(?from_currency, ?to_currency, ?rate, ?expires_at)
+		RETURNING
+			id as id;
+	`
+	_, err := s.pg.QueryOneContext(ctx, &result, sql, struct {
+		FromCurrency string        `sql:"from_currency"`
+		ToCurrency   string        `sql:"to_currency"`
+		Rate         money.Numeric `sql:"rate"`
+		ExpiresAt    time.Time     `sql:"expires_at"`
+	}{
+		FromCurrency: string(quote.From),
+		ToCurrency:   string(quote.To),
+		Rate:         quote.Rate,
+		ExpiresAt:    quote.ExpiresAt,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return entity.QuoteID(result.Id), nil
+}
+
+func (s PaymentsService) getQuote(ctx context.Context, id entity.QuoteID) (entity.Quote, error) {
+	var model struct {
+		Id           int64         `sql:"id"`
+		FromCurrency string        `sql:"from_currency"`
+		ToCurrency   string        `sql:"to_currency"`
+		Rate         money.Numeric `sql:"rate"`
+		ExpiresAt    time.Time     `sql:"expires_at"`
+	}
+
+	const sql = `SELECT id, from_currency, to_currency, rate, expires_at FROM fx_quote WHERE id = ?`
+
+	_, err := s.pg.QueryOneContext(ctx, &model, sql, id)
+	if err != nil {
+		return entity.Quote{}, err
+	}
+
+	return entity.Quote{
+		Id:        entity.QuoteID(model.Id),
+		From:      money.Currency(model.FromCurrency),
+		To:        money.Currency(model.ToCurrency),
+		Rate:      model.Rate,
+		ExpiresAt: model.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,114 @@
+package persistent
+
+import (
+	"errors"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/fx"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/lightsgoout/fintech-go/pkg/testing/isolation"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPaymentsService_TransferWithQuote(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	rates, err := fx.NewStaticRateProvider(
+		fx.Rate{From: "USD", To: "EUR", Rate: money.NewNumericFromStringMust("0.9")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewPaymentsService(env.Tx, WithRateProvider(rates))
+
+	var (
+		bob   entity.AccountID = "bob"
+		alice entity.AccountID = "alice"
+	)
+	err = svc.CreateAccount(env.Ctx, bob, money.NewNumericFromInt64(100), "USD")
+	if err != nil {
+		t.Error(err)
+	}
+	err = svc.CreateAccount(env.Ctx, alice, money.NewNumericFromInt64(100), "EUR")
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("transfer with quote OK", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		quote, err := svc.QuoteExchange(env.Ctx, "USD", "EUR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, quote.Rate, money.NewNumericFromStringMust("0.9"))
+
+		paymentId, err := svc.TransferWithQuote(env.Ctx, bob, alice, money.NewNumericFromStringMust("10.01"), quote.Id, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		page, err := svc.GetPayments(env.Ctx, service.PaymentsQuery{Account: alice})
+		if err != nil {
+			t.Fatal(err)
+		}
+		payments := page.Payments
+		assert.Equal(t, len(payments), 1)
+		assert.Equal(t, payments[0].Id, paymentId)
+		assert.Equal(t, payments[0].Value.Amount.String(), "10.01")
+		assert.Equal(t, payments[0].Value.Currency, money.Currency("USD"))
+		if assert.NotNil(t, payments[0].Value.Exchange) {
+			assert.Equal(t, payments[0].Value.Exchange.Quote, quote.Id)
+			assert.Equal(t, payments[0].Value.Exchange.Rate.String(), "0.9")
+			// 10.01 * 0.9 = 9.009, rounded to cents
+			assert.Equal(t, payments[0].Value.Exchange.ToAmount.String(), "9.01")
+			assert.Equal(t, payments[0].Value.Exchange.ToCurrency, money.Currency("EUR"))
+		}
+	}))
+
+	t.Run("no rate for the pair", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		quote, err := svc.QuoteExchange(env.Ctx, "EUR", "USD")
+		if !errors.Is(err, service.ErrRateUnavailable) {
+			t.Errorf("expected ErrRateUnavailable, got quote=%v, err=%v", quote, err)
+		}
+	}))
+
+	t.Run("quote currencies must match accounts", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		quote, err := svc.QuoteExchange(env.Ctx, "USD", "EUR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		paymentId, err := svc.TransferWithQuote(env.Ctx, alice, bob, money.NewNumericFromInt64(10), quote.Id, "")
+		if !errors.Is(err, service.ErrIncompatibleCurrency) {
+			t.Errorf("expected ErrIncompatibleCurrency, got paymentId=%v, err=%v", paymentId, err)
+		}
+	}))
+
+	t.Run("unknown quote", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		paymentId, err := svc.TransferWithQuote(env.Ctx, bob, alice, money.NewNumericFromInt64(10), 0, "")
+		if !errors.Is(err, service.ErrQuoteNotFound) {
+			t.Errorf("expected ErrQuoteNotFound, got paymentId=%v, err=%v", paymentId, err)
+		}
+	}))
+
+	t.Run("expired quote", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		svc := NewPaymentsService(env.Tx, WithRateProvider(rates), WithQuoteTTL(-time.Second))
+		quote, err := svc.QuoteExchange(env.Ctx, "USD", "EUR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		paymentId, err := svc.TransferWithQuote(env.Ctx, bob, alice, money.NewNumericFromInt64(10), quote.Id, "")
+		if !errors.Is(err, service.ErrQuoteExpired) {
+			t.Errorf("expected ErrQuoteExpired, got paymentId=%v, err=%v", paymentId, err)
+		}
+	}))
+
+	t.Run("cross-currency transfers disabled without rate provider", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		svc := NewPaymentsService(env.Tx)
+		quote, err := svc.QuoteExchange(env.Ctx, "USD", "EUR")
+		if !errors.Is(err, service.ErrRateUnavailable) {
+			t.Errorf("expected ErrRateUnavailable, got quote=%v, err=%v", quote, err)
+		}
+	}))
+}
@@ -8,7 +8,7 @@ import (
 )
 
 func (s PaymentsService) GetAccounts(ctx context.Context, cur money.Currency) ([]entity.AccountID, error) {
-	if !money.IsKnownCurrency(cur) {
+	if !s.currencies.IsKnown(cur) {
 		return nil, service.ErrIncompatibleCurrency
 	}
 	result, err := s.getAccounts(ctx, cur)
@@ -5,25 +5,35 @@ import (
 	"github.com/lightsgoout/fintech-go/payments/entity"
 	"github.com/lightsgoout/fintech-go/payments/service"
 	"github.com/lightsgoout/fintech-go/pkg/money"
+	"strings"
 	"time"
 )
 
-func (s PaymentsService) GetPayments(ctx context.Context, accountId entity.AccountID) ([]entity.Payment, error) {
-	if accountId == "" {
-		return nil, service.ErrBadAccountID
+func (s PaymentsService) GetPayments(ctx context.Context, query service.PaymentsQuery) (service.PaymentsPage, error) {
+	cursor, limit, err := query.Validate()
+	if err != nil {
+		return service.PaymentsPage{}, err
 	}
-	exists, err := s.accountExists(ctx, accountId)
+	exists, err := s.accountExists(ctx, query.Account)
 	if err != nil {
-		return nil, NewInternalErrorFromDBError(err)
+		return service.PaymentsPage{}, NewInternalErrorFromDBError(err)
 	}
 	if !exists {
-		return nil, service.ErrAccountDoesNotExist
+		return service.PaymentsPage{}, service.ErrAccountDoesNotExist
 	}
-	result, err := s.getPayments(ctx, accountId)
+	// Fetch one extra payment to find out whether there's a next page
+	result, err := s.getPayments(ctx, query, cursor, limit+1)
 	if err != nil {
-		return nil, NewInternalErrorFromDBError(err)
+		return service.PaymentsPage{}, NewInternalErrorFromDBError(err)
 	}
-	return result, nil
+	page := service.PaymentsPage{
+		Payments: result,
+	}
+	if len(result) > limit {
+		page.Payments = result[:limit]
+		page.NextCursor = service.EncodePaymentsCursor(page.Payments[limit-1])
+	}
+	return page, nil
 }
 
 func (s PaymentsService) accountExists(ctx context.Context, accountId entity.AccountID) (bool, error) {
@@ -35,56 +45,91 @@ func (s PaymentsService) accountExists(ctx context.Context, accountId entity.Acc
 	return result.Exists, err
 }
 
-func (s PaymentsService) getPayments(ctx context.Context, accountId entity.AccountID) ([]entity.Payment, error) {
-	const sql = `--payments_get
-		SELECT * FROM (
-			SELECT 
-				id, 
-				time, 
-				from_account_id, 
-				to_account_id, 
-				amount::text as amount, 
-				currency,
-				true as outgoing
-			FROM payment WHERE from_account_id = ?
-			UNION
-			SELECT 
-				id, 
-				time, 
-				from_account_id, 
-				to_account_id, 
-				amount::text as amount, 
-				currency,
-				false as outgoing
-			FROM payment WHERE to_account_id = ?
-		) x ORDER BY time DESC`
+// getPayments selects outgoing and incoming payments separately, so each part is read in order
+// from (from_account_id, time desc) and (to_account_id, time desc) indexes respectively,
+// stopping after limit rows. Only those (at most 2 * limit) rows are merged afterwards.
+func (s PaymentsService) getPayments(ctx context.Context, query service.PaymentsQuery, cursor service.PaymentsCursor, limit int) ([]entity.Payment, error) {
+	params := struct {
+		Account      string         `sql:"account"`
+		Counterparty string         `sql:"counterparty"`
+		Since        time.Time      `sql:"since"`
+		Until        time.Time      `sql:"until"`
+		CursorTime   time.Time      `sql:"cursor_time"`
+		CursorId     int64          `sql:"cursor_id"`
+		MinAmount    *money.Numeric `sql:"min_amount"`
+		MaxAmount    *money.Numeric `sql:"max_amount"`
+		Limit        int            `sql:"limit"`
+	}{
+		Account:      string(query.Account),
+		Counterparty: string(query.Counterparty),
+		Since:        query.Since,
+		Until:        query.Until,
+		CursorTime:   cursor.Time,
+		CursorId:     int64(cursor.Id),
+		MinAmount:    query.MinAmount,
+		MaxAmount:    query.MaxAmount,
+		Limit:        limit,
+	}
+
+	var branches []string
+	if query.Direction != service.DirectionIncoming {
+		branches = append(branches, paymentsBranch(query, cursor, true))
+	}
+	if query.Direction != service.DirectionOutgoing {
+		branches = append(branches, paymentsBranch(query, cursor, false))
+	}
+	sql := `--payments_get
+		` + strings.Join(branches, `
+		UNION ALL`) + `
+		ORDER BY time DESC, id DESC
+		LIMIT ?limit`
 
-	var rows []struct {
-		Id       int64     `sql:"id"`
-		Time     time.Time `sql:"time"`
-		From     string    `pg:"from_account_id"`
-		To       string    `pg:"to_account_id"`
-		Amount   string    `sql:"amount"`
-		Currency string    `sql:"currency"`
-		Outgoing bool      `sql:"outgoing"`
-	}
-	_, err := s.pg.QueryContext(ctx, &rows, sql, accountId, accountId)
+	var rows []paymentModel
+	_, err := s.pg.QueryContext(ctx, &rows, sql, params)
 	if err != nil {
 		return nil, err
 	}
 	result := make([]entity.Payment, 0, len(rows))
 	for _, r := range rows {
-		result = append(result, entity.Payment{
-			Id: entity.PaymentID(r.Id),
-			Value: entity.PaymentValue{
-				Time:     r.Time,
-				From:     entity.AccountID(r.From),
-				To:       entity.AccountID(r.To),
-				Amount:   money.NewNumericFromStringMust(r.Amount),
-				Currency: money.Currency(r.Currency),
-				Outgoing: r.Outgoing,
-			},
-		})
+		result = append(result, r.toEntity())
 	}
 	return result, nil
 }
+
+// paymentsBranch returns a query selecting either outgoing or incoming payments of the account.
+func paymentsBranch(query service.PaymentsQuery, cursor service.PaymentsCursor, outgoing bool) string {
+	account, counterparty, amount, flag := "from_account_id", "to_account_id", "amount", "true"
+	if !outgoing {
+		// Incoming cross-currency payments are filtered by the amount received
+		account, counterparty, amount, flag = "to_account_id", "from_account_id", "coalesce(to_amount, amount)", "false"
+	}
+
+	conditions := []string{account + ` = ?account`}
+	if query.Counterparty != "" {
+		conditions = append(conditions, counterparty+` = ?counterparty`)
+	}
+	if !query.Since.IsZero() {
+		conditions = append(conditions, `time >= ?since`)
+	}
+	if !query.Until.IsZero() {
+		conditions = append(conditions, `time < ?until`)
+	}
+	if query.MinAmount != nil {
+		conditions = append(conditions, amount+` >= ?min_amount`)
+	}
+	if query.MaxAmount != nil {
+		conditions = append(conditions, amount+` <= ?max_amount`)
+	}
+	if !cursor.IsZero() {
+		conditions = append(conditions, `(time, id) < (?cursor_time, ?cursor_id)`)
+	}
+
+	return `(
+			SELECT ` + paymentColumns + `,
+				` + flag + ` as outgoing
+			FROM payment
+			WHERE ` + strings.Join(conditions, ` AND `) + `
+			ORDER BY time DESC, id DESC
+			LIMIT ?limit
+		)`
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/lightsgoout/fintech-go/pkg/testing/isolation"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestPaymentsService_GetPayments(t *testing.T) {
@@ -17,9 +18,9 @@ func TestPaymentsService_GetPayments(t *testing.T) {
 	svc := NewPaymentsService(env.Tx)
 
 	t.Run("check account exists", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
-		res, err := svc.GetPayments(env.Ctx, "zzz")
+		page, err := svc.GetPayments(env.Ctx, service.PaymentsQuery{Account: "zzz"})
 		if !errors.Is(err, service.ErrAccountDoesNotExist) {
-			t.Errorf("expected ErrAccountDoesNotExist, got result=%v, err=%v", res, err)
+			t.Errorf("expected ErrAccountDoesNotExist, got result=%v, err=%v", page, err)
 		}
 	}))
 
@@ -28,12 +29,12 @@ func TestPaymentsService_GetPayments(t *testing.T) {
 		if err != nil {
 			t.Error(err)
 		}
-		res, err := svc.GetPayments(env.Ctx, "bob")
+		page, err := svc.GetPayments(env.Ctx, service.PaymentsQuery{Account: "bob"})
 		if err != nil {
 			t.Error(err)
 		}
-		if len(res) != 0 {
-			t.Errorf("expected empty result, got len %d", len(res))
+		if len(page.Payments) != 0 {
+			t.Errorf("expected empty result, got len %d", len(page.Payments))
 		}
 	}))
 
@@ -49,21 +50,22 @@ func TestPaymentsService_GetPayments(t *testing.T) {
 		}
 
 		// Transfer some money from bob to alice
-		_, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(50), "USD")
+		_, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(50), "USD", "")
 		if err != nil {
 			t.Error(err)
 		}
 		// And from alice to bob
-		_, err = svc.Transfer(env.Ctx, alice, bob, money.NewNumericFromInt64(35), "USD")
+		_, err = svc.Transfer(env.Ctx, alice, bob, money.NewNumericFromInt64(35), "USD", "")
 		if err != nil {
 			t.Error(err)
 		}
 
 		// Check bob's payments
-		bobPayments, err := svc.GetPayments(env.Ctx, bob)
+		page, err := svc.GetPayments(env.Ctx, service.PaymentsQuery{Account: bob})
 		if err != nil {
 			t.Error(err)
 		}
+		bobPayments := page.Payments
 		if len(bobPayments) != 2 {
 			t.Error("incorrect bob payments count")
 		}
@@ -79,10 +81,11 @@ func TestPaymentsService_GetPayments(t *testing.T) {
 		assert.Equal(t, bobPayments[1].Value.Outgoing, true)
 
 		// Check alice's payments
-		alicePayments, err := svc.GetPayments(env.Ctx, alice)
+		page, err = svc.GetPayments(env.Ctx, service.PaymentsQuery{Account: alice})
 		if err != nil {
 			t.Error(err)
 		}
+		alicePayments := page.Payments
 		if len(alicePayments) != 2 {
 			t.Error("incorrect alice payments count")
 		}
@@ -97,4 +100,113 @@ func TestPaymentsService_GetPayments(t *testing.T) {
 		assert.Equal(t, alicePayments[1].Value.Currency, money.Currency("USD"))
 		assert.Equal(t, alicePayments[1].Value.Outgoing, false)
 	}))
+
+	t.Run("pagination", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		const bob = entity.AccountID("bob")
+		const alice = entity.AccountID("alice")
+		for _, id := range [...]entity.AccountID{bob, alice} {
+			err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+			if err != nil {
+				t.Error(err)
+			}
+		}
+		var paymentIds []entity.PaymentID
+		for i := 1; i <= 5; i++ {
+			paymentId, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(int64(i)), "USD", "")
+			if err != nil {
+				t.Error(err)
+			}
+			paymentIds = append([]entity.PaymentID{paymentId}, paymentIds...)
+		}
+
+		var (
+			seen   []entity.PaymentID
+			cursor string
+		)
+		for pages := 0; pages < 3; pages++ {
+			page, err := svc.GetPayments(env.Ctx, service.PaymentsQuery{Account: bob, Limit: 2, Cursor: cursor})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range page.Payments {
+				seen = append(seen, p.Id)
+			}
+			cursor = page.NextCursor
+			if cursor == "" {
+				break
+			}
+		}
+		assert.Equal(t, seen, paymentIds)
+		assert.Equal(t, cursor, "")
+	}))
+
+	t.Run("filters", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		const bob = entity.AccountID("bob")
+		const alice = entity.AccountID("alice")
+		const clyde = entity.AccountID("clyde")
+		for _, id := range [...]entity.AccountID{bob, alice, clyde} {
+			err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+			if err != nil {
+				t.Error(err)
+			}
+		}
+		toAlice, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(10), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+		toClyde, err := svc.Transfer(env.Ctx, bob, clyde, money.NewNumericFromInt64(20), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+		fromAlice, err := svc.Transfer(env.Ctx, alice, bob, money.NewNumericFromInt64(30), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+
+		fifteen := money.NewNumericFromInt64(15)
+		twenty := money.NewNumericFromInt64(20)
+		for _, testcase := range []struct {
+			query service.PaymentsQuery
+			want  []entity.PaymentID
+		}{
+			{
+				query: service.PaymentsQuery{Account: bob, Direction: service.DirectionOutgoing},
+				want:  []entity.PaymentID{toClyde, toAlice},
+			},
+			{
+				query: service.PaymentsQuery{Account: bob, Direction: service.DirectionIncoming},
+				want:  []entity.PaymentID{fromAlice},
+			},
+			{
+				query: service.PaymentsQuery{Account: bob, Counterparty: alice},
+				want:  []entity.PaymentID{fromAlice, toAlice},
+			},
+			{
+				query: service.PaymentsQuery{Account: bob, MinAmount: &fifteen},
+				want:  []entity.PaymentID{fromAlice, toClyde},
+			},
+			{
+				query: service.PaymentsQuery{Account: bob, MaxAmount: &twenty},
+				want:  []entity.PaymentID{toClyde, toAlice},
+			},
+			{
+				query: service.PaymentsQuery{Account: bob, Until: time.Now().Add(-time.Hour)},
+				want:  nil,
+			},
+			{
+				query: service.PaymentsQuery{Account: bob, Since: time.Now().Add(-time.Hour), Direction: service.DirectionIncoming},
+				want:  []entity.PaymentID{fromAlice},
+			},
+		} {
+			page, err := svc.GetPayments(env.Ctx, testcase.query)
+			if err != nil {
+				t.Error(err)
+			}
+			var have []entity.PaymentID
+			for _, p := range page.Payments {
+				have = append(have, p.Id)
+			}
+			assert.Equal(t, testcase.want, have, "%+v", testcase.query)
+		}
+	}))
 }
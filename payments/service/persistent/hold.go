@@ -0,0 +1,258 @@
+package persistent
+
+import (
+	"context"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
+	"time"
+)
+
+func (s PaymentsService) Authorize(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency) (entity.Hold, error) {
+	if from == to {
+		return entity.Hold{}, service.ErrBadTransferTarget
+	}
+
+	currency, ok := s.currencies.Lookup(cur)
+	if !ok {
+		return entity.Hold{}, service.ErrIncompatibleCurrency
+	}
+
+	if !amount.IsPositive() || !amount.FitsDecimalPlaces(currency.MinorUnits) {
+		return entity.Hold{}, service.ErrInvalidAmount
+	}
+
+	now := time.Now().UTC()
+	hold := entity.Hold{
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		Currency:  cur,
+		Status:    entity.HoldActive,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.holdTTL),
+	}
+
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		// Lock accounts the same way Transfer does, so holds and transfers can't overspend together.
+		accounts, err := s.lockAccounts(ctx, tx, from, to)
+		if err != nil {
+			return err
+		}
+
+		if err := service.CheckCanDebit(accounts[from]); err != nil {
+			return err
+		}
+		if err := service.CheckCanCredit(accounts[to]); err != nil {
+			return err
+		}
+
+		if accounts[from].Currency != cur || accounts[to].Currency != cur {
+			return service.ErrIncompatibleCurrency
+		}
+
+		// Limits are checked as if the hold was captured now, and once again when it's captured.
+		if err := s.checkLimits(ctx, tx, entity.PaymentValue{From: from, To: to, Amount: amount, Currency: cur, Time: now}); err != nil {
+			return err
+		}
+
+		held, err := s.getHeldAmount(ctx, tx, from, now, 0)
+		if err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		if err := service.CheckSufficientFunds(accounts[from], accounts[from].Balance.Sub(held).Sub(amount)); err != nil {
+			return err
+		}
+
+		hold.Id, err = s.createHold(ctx, tx, hold)
+		if err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return entity.Hold{}, err
+	}
+	return hold, nil
+}
+
+func (s PaymentsService) Capture(ctx context.Context, id entity.HoldID, amount *money.Numeric) (entity.PaymentID, error) {
+	var paymentId entity.PaymentID
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		hold, err := s.getHoldWithLock(ctx, tx, id)
+		if err != nil {
+			if errors.Is(err, pg.ErrNoRows) {
+				return service.ErrHoldNotFound
+			}
+			return NewInternalErrorFromDBError(err)
+		}
+		if err := service.CheckHoldActive(hold, time.Now().UTC()); err != nil {
+			return err
+		}
+
+		captured := hold.Amount
+		if amount != nil {
+			currency, ok := s.currencies.Lookup(hold.Currency)
+			if !ok {
+				return service.ErrIncompatibleCurrency
+			}
+			if !amount.IsPositive() || !amount.FitsDecimalPlaces(currency.MinorUnits) {
+				return service.ErrInvalidAmount
+			}
+			if hold.Amount.LessThan(*amount) {
+				return service.ErrCaptureExceedsHold
+			}
+			captured = *amount
+		}
+
+		paymentId, err = s.transferInTx(ctx, tx, transferRequest{
+			value: entity.PaymentValue{
+				From:     hold.From,
+				To:       hold.To,
+				Amount:   captured,
+				Currency: hold.Currency,
+			},
+			hold: hold.Id,
+		})
+		if err != nil {
+			return err
+		}
+		err = s.updateHold(ctx, tx, id, entity.HoldCaptured, paymentId)
+		if err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return paymentId, nil
+}
+
+func (s PaymentsService) Void(ctx context.Context, id entity.HoldID) error {
+	return postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		hold, err := s.getHoldWithLock(ctx, tx, id)
+		if err != nil {
+			if errors.Is(err, pg.ErrNoRows) {
+				return service.ErrHoldNotFound
+			}
+			return NewInternalErrorFromDBError(err)
+		}
+		if err := service.CheckHoldActive(hold, time.Now().UTC()); err != nil {
+			return err
+		}
+		err = s.updateHold(ctx, tx, id, entity.HoldVoided, 0)
+		if err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		return nil
+	})
+}
+
+// getHeldAmount returns money of an account reserved by holds active at the given moment, except the given hold.
+func (s PaymentsService) getHeldAmount(ctx context.Context, tx postgres.Database, id entity.AccountID, at time.Time, except entity.HoldID) (money.Numeric, error) {
+	var result struct {
+		Held money.Numeric `sql:"held"`
+	}
+	const sql = `--hold_sum
+		SELECT coalesce(sum(amount), 0) as held
+		FROM hold
+		WHERE from_account_id = ?account AND status = 'active' AND expires_at > ?at AND id <> ?except
+	`
+	_, err := tx.QueryOneContext(ctx, &result, sql, struct {
+		Account string    `sql:"account"`
+		At      time.Time `sql:"at"`
+		Except  int64     `pg:"except,use_zero"`
+	}{
+		Account: string(id),
+		At:      at,
+		Except:  int64(except),
+	})
+	return result.Held, err
+}
+
+func (s PaymentsService) createHold(ctx context.Context, tx postgres.Database, hold entity.Hold) (entity.HoldID, error) {
+	var result struct {
+		Id int64 `sql:"id"`
+	}
+	const sql = `--hold_insert
+		INSERT INTO hold
+			(from_account_id, to_account_id, amount, currency, status, created_at, expires_at)
+		VALUES
+			(?from_account_id, ?to_account_id, ?amount, ?currency, ?status, ?created_at, ?expires_at)
+		RETURNING
+			id as id;
+	`
+	_, err := tx.QueryOneContext(ctx, &result, sql, struct {
+		FromAccountId string        `sql:"from_account_id"`
+		ToAccountId   string        `sql:"to_account_id"`
+		Amount        money.Numeric `sql:"amount"`
+		Currency      string        `sql:"currency"`
+		Status        string        `sql:"status"`
+		CreatedAt     time.Time     `sql:"created_at"`
+		ExpiresAt     time.Time     `sql:"expires_at"`
+	}{
+		FromAccountId: string(hold.From),
+		ToAccountId:   string(hold.To),
+		Amount:        hold.Amount,
+		Currency:      string(hold.Currency),
+		Status:        string(hold.Status),
+		CreatedAt:     hold.CreatedAt,
+		ExpiresAt:     hold.ExpiresAt,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return entity.HoldID(result.Id), nil
+}
+
+func (s PaymentsService) getHoldWithLock(ctx context.Context, tx postgres.Database, id entity.HoldID) (entity.Hold, error) {
+	var model struct {
+		Id        int64         `sql:"id"`
+		From      string        `pg:"from_account_id"`
+		To        string        `pg:"to_account_id"`
+		Amount    money.Numeric `sql:"amount"`
+		Currency  string        `sql:"currency"`
+		Status    string        `sql:"status"`
+		CreatedAt time.Time     `sql:"created_at"`
+		ExpiresAt time.Time     `sql:"expires_at"`
+		PaymentId int64         `sql:"payment_id"`
+	}
+
+	const sql = `--hold_get
+		SELECT id, from_account_id, to_account_id, amount, currency, status, created_at, expires_at, payment_id
+		FROM hold WHERE id = ? FOR UPDATE`
+
+	_, err := tx.QueryOneContext(ctx, &model, sql, id)
+	if err != nil {
+		return entity.Hold{}, err
+	}
+
+	return entity.Hold{
+		Id:        entity.HoldID(model.Id),
+		From:      entity.AccountID(model.From),
+		To:        entity.AccountID(model.To),
+		Amount:    model.Amount,
+		Currency:  money.Currency(model.Currency),
+		Status:    entity.HoldStatus(model.Status),
+		CreatedAt: model.CreatedAt,
+		ExpiresAt: model.ExpiresAt,
+		Payment:   entity.PaymentID(model.PaymentId),
+	}, nil
+}
+
+func (s PaymentsService) updateHold(ctx context.Context, tx postgres.Database, id entity.HoldID, status entity.HoldStatus, paymentId entity.PaymentID) error {
+	_, err := tx.ExecContext(ctx, `UPDATE hold SET status = ?status, payment_id = ?payment_id WHERE id = ?id`, struct {
+		Id        int64  `sql:"id"`
+		Status    string `sql:"status"`
+		PaymentId int64  `sql:"payment_id"`
+	}{
+		Id:        int64(id),
+		Status:    string(status),
+		PaymentId: int64(paymentId),
+	})
+	return err
+}
@@ -0,0 +1,34 @@
+package persistent
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
+)
+
+// lockIdempotencyKey serializes concurrent requests carrying the same idempotency key.
+// The lock is released automatically when the surrounding transaction ends.
+func (s PaymentsService) lockIdempotencyKey(ctx context.Context, tx postgres.Database, key entity.IdempotencyKey) error {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext(?))`, string(key))
+	return err
+}
+
+// getPaymentByIdempotencyKey returns a payment previously made with the given key, if any.
+func (s PaymentsService) getPaymentByIdempotencyKey(ctx context.Context, tx postgres.Database, key entity.IdempotencyKey) (entity.Payment, bool, error) {
+	const sql = `--payments_get_by_idempotency_key
+		SELECT ` + paymentColumns + `
+		FROM payment WHERE idempotency_key = ?`
+
+	var rows []paymentModel
+	_, err := tx.QueryContext(ctx, &rows, sql, string(key))
+	if err != nil || len(rows) == 0 {
+		return entity.Payment{}, false, err
+	}
+	return rows[0].toEntity(), true, nil
+}
+
+// expireIdempotencyKey detaches an outdated key from its payment so that it can be used again.
+func (s PaymentsService) expireIdempotencyKey(ctx context.Context, tx postgres.Database, key entity.IdempotencyKey) error {
+	_, err := tx.ExecContext(ctx, `UPDATE payment SET idempotency_key = NULL WHERE idempotency_key = ?`, string(key))
+	return err
+}
@@ -0,0 +1,144 @@
+package persistent
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
+	"time"
+)
+
+// journalEntry is an immutable record of a single balance change of an account.
+// Sum of all entries of an account must be equal to its balance.
+type journalEntry struct {
+	Time time.Time
+
+	Account entity.AccountID
+
+	// Payment is a payment the entry is a leg of, zero for initial balances
+	Payment entity.PaymentID
+
+	// Amount is positive for credits and negative for debits
+	Amount money.Numeric
+
+	Currency money.Currency
+}
+
+func (s PaymentsService) createJournalEntry(ctx context.Context, tx postgres.Database, entry journalEntry) error {
+	const sql = `--journal_entry_insert
+		INSERT INTO journal_entry
+			(time, account_id, payment_id, amount, currency)
+		VALUES
+			(?time, ?account_id, ?payment_id, ?amount, ?currency)
+	`
+	_, err := tx.ExecContext(ctx, sql, struct {
+		Time      time.Time     `sql:"time"`
+		AccountId string        `sql:"account_id"`
+		PaymentId int64         `sql:"payment_id"`
+		Amount    money.Numeric `sql:"amount"`
+		Currency  string        `sql:"currency"`
+	}{
+		Time:      entry.Time,
+		AccountId: string(entry.Account),
+		PaymentId: int64(entry.Payment),
+		Amount:    entry.Amount,
+		Currency:  string(entry.Currency),
+	})
+	return err
+}
+
+// journalPayment records both legs of a payment: a debit of the sender and a credit of the receiver,
+// and if there was a fee, one more debit of the sender and a credit of the revenue account.
+func (s PaymentsService) journalPayment(ctx context.Context, tx postgres.Database, id entity.PaymentID, value entity.PaymentValue) error {
+	legs := []journalEntry{
+		{
+			Time:     value.Time,
+			Account:  value.From,
+			Payment:  id,
+			Amount:   value.Amount.Neg(),
+			Currency: value.Currency,
+		},
+		{
+			Time:     value.Time,
+			Account:  value.To,
+			Payment:  id,
+			Amount:   value.CreditAmount(),
+			Currency: value.CreditCurrency(),
+		},
+	}
+	if value.FeeAccount != "" {
+		legs = append(legs,
+			journalEntry{
+				Time:     value.Time,
+				Account:  value.From,
+				Payment:  id,
+				Amount:   value.Fee.Neg(),
+				Currency: value.Currency,
+			},
+			journalEntry{
+				Time:     value.Time,
+				Account:  value.FeeAccount,
+				Payment:  id,
+				Amount:   value.Fee,
+				Currency: value.Currency,
+			},
+		)
+	}
+	for _, leg := range legs {
+		if err := s.createJournalEntry(ctx, tx, leg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BalanceDiscrepancy describes an account whose balance doesn't match its journal.
+type BalanceDiscrepancy struct {
+	Account entity.AccountID
+
+	Currency money.Currency
+
+	// Balance is the balance stored on the account
+	Balance money.Numeric
+
+	// JournalBalance is the balance recomputed from the journal
+	JournalBalance money.Numeric
+}
+
+// VerifyBalances recomputes balances of all accounts from the journal and reports the ones not matching.
+// An empty result means all balances are proven by history.
+func (s PaymentsService) VerifyBalances(ctx context.Context) ([]BalanceDiscrepancy, error) {
+	const sql = `--journal_verify
+		SELECT
+			a.id,
+			a.currency,
+			a.balance,
+			coalesce(j.balance, 0) as journal_balance
+		FROM account a
+		LEFT JOIN (
+			SELECT account_id, sum(amount) as balance FROM journal_entry GROUP BY account_id
+		) j ON j.account_id = a.id
+		WHERE a.balance IS DISTINCT FROM coalesce(j.balance, 0)
+		ORDER BY a.id ASC`
+
+	var rows []struct {
+		Id             string        `sql:"id"`
+		Currency       string        `sql:"currency"`
+		Balance        money.Numeric `sql:"balance"`
+		JournalBalance money.Numeric `sql:"journal_balance"`
+	}
+	_, err := s.pg.QueryContext(ctx, &rows, sql)
+	if err != nil {
+		return nil, NewInternalErrorFromDBError(err)
+	}
+	result := make([]BalanceDiscrepancy, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, BalanceDiscrepancy{
+			Account:        entity.AccountID(r.Id),
+			Currency:       money.Currency(r.Currency),
+			Balance:        r.Balance,
+			JournalBalance: r.JournalBalance,
+		})
+	}
+	return result, nil
+}
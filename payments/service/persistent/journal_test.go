@@ -0,0 +1,82 @@
+package persistent
+
+import (
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/lightsgoout/fintech-go/pkg/testing/isolation"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPaymentsService_VerifyBalances(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	svc := NewPaymentsService(env.Tx)
+
+	var (
+		bob   entity.AccountID = "bob"
+		alice entity.AccountID = "alice"
+		clyde entity.AccountID = "clyde"
+	)
+	for _, id := range [...]entity.AccountID{bob, alice} {
+		err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	err := svc.CreateAccount(env.Ctx, clyde, money.NewNumericFromInt64(0), "USD")
+	if err != nil {
+		t.Error(err)
+	}
+
+	t.Run("balances match journal", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		for _, transfer := range []struct {
+			from, to entity.AccountID
+			amount   int64
+		}{
+			{bob, alice, 30},
+			{alice, clyde, 50},
+			{clyde, bob, 20},
+		} {
+			_, err := svc.Transfer(env.Ctx, transfer.from, transfer.to, money.NewNumericFromInt64(transfer.amount), "USD", "")
+			if err != nil {
+				t.Error(err)
+			}
+		}
+
+		discrepancies, err := svc.VerifyBalances(env.Ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, len(discrepancies), 0)
+	}))
+
+	t.Run("balance changed bypassing journal", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		if _, err := env.Tx.Exec(`UPDATE account SET balance = 1000 WHERE id = ?`, bob); err != nil {
+			t.Error(err)
+		}
+
+		discrepancies, err := svc.VerifyBalances(env.Ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		if assert.Equal(t, len(discrepancies), 1) {
+			assert.Equal(t, discrepancies[0].Account, bob)
+			assert.Equal(t, discrepancies[0].Balance.String(), "1000")
+			assert.Equal(t, discrepancies[0].JournalBalance.String(), "100")
+		}
+	}))
+
+	t.Run("journal is immutable", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		_, err := env.Tx.Exec(`SAVEPOINT journal_immutable`)
+		if err != nil {
+			t.Error(err)
+		}
+		_, err = env.Tx.Exec(`UPDATE journal_entry SET amount = 1 WHERE account_id = ?`, bob)
+		assert.Error(t, err)
+		if _, err := env.Tx.Exec(`ROLLBACK TO SAVEPOINT journal_immutable`); err != nil {
+			t.Error(err)
+		}
+	}))
+}
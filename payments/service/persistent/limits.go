@@ -0,0 +1,136 @@
+package persistent
+
+import (
+	"context"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
+	"time"
+)
+
+func (s PaymentsService) SetAccountLimits(ctx context.Context, id entity.AccountID, limits entity.AccountLimits) error {
+	if err := service.ValidateLimits(limits); err != nil {
+		return err
+	}
+
+	return postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		if _, err := s.lockAccounts(ctx, tx, id); err != nil {
+			return err
+		}
+
+		const sql = `--account_limit_upsert
+			INSERT INTO account_limit
+				(account_id, max_amount, daily_amount, monthly_amount, daily_count, monthly_count)
+			VALUES
+				(?, ?, ?, ?, ?, ?)
+			ON CONFLICT (account_id) DO UPDATE SET
+				max_amount = excluded.max_amount,
+				daily_amount = excluded.daily_amount,
+				monthly_amount = excluded.monthly_amount,
+				daily_count = excluded.daily_count,
+				monthly_count = excluded.monthly_count
+		`
+		_, err := tx.ExecContext(ctx, sql,
+			id, limits.MaxAmount, limits.DailyAmount, limits.MonthlyAmount, limits.DailyCount, limits.MonthlyCount,
+		)
+		if err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		return nil
+	})
+}
+
+func (s PaymentsService) GetAccountLimits(ctx context.Context, id entity.AccountID) (entity.AccountLimits, error) {
+	return s.getAccountLimits(ctx, s.pg, id)
+}
+
+func (s PaymentsService) getAccountLimits(ctx context.Context, db postgres.Database, id entity.AccountID) (entity.AccountLimits, error) {
+	var model struct {
+		Id            string         `sql:"id"`
+		MaxAmount     *money.Numeric `sql:"max_amount"`
+		DailyAmount   *money.Numeric `sql:"daily_amount"`
+		MonthlyAmount *money.Numeric `sql:"monthly_amount"`
+		DailyCount    *int           `sql:"daily_count"`
+		MonthlyCount  *int           `sql:"monthly_count"`
+	}
+	const sql = `
+		SELECT a.id, l.max_amount, l.daily_amount, l.monthly_amount, l.daily_count, l.monthly_count
+		FROM account a
+		LEFT JOIN account_limit l ON l.account_id = a.id
+		WHERE a.id = ?
+	`
+	_, err := db.QueryOneContext(ctx, &model, sql, id)
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return entity.AccountLimits{}, service.ErrAccountDoesNotExist
+		}
+		return entity.AccountLimits{}, NewInternalErrorFromDBError(err)
+	}
+	return entity.AccountLimits{
+		MaxAmount:     model.MaxAmount,
+		DailyAmount:   model.DailyAmount,
+		MonthlyAmount: model.MonthlyAmount,
+		DailyCount:    model.DailyCount,
+		MonthlyCount:  model.MonthlyCount,
+	}, nil
+}
+
+// checkLimits returns an error unless a new payment fits limits of its sender.
+// The sender's account must be locked, so concurrent payments can't exceed limits together.
+func (s PaymentsService) checkLimits(ctx context.Context, tx postgres.Database, value entity.PaymentValue) error {
+	limits, err := s.getAccountLimits(ctx, tx, value.From)
+	if err != nil {
+		return err
+	}
+	if limits == (entity.AccountLimits{}) {
+		return nil
+	}
+
+	usage, err := s.getLimitUsage(ctx, tx, value.From, value.Time)
+	if err != nil {
+		return NewInternalErrorFromDBError(err)
+	}
+	return service.CheckLimits(value.From, limits, usage, value.Amount)
+}
+
+// getLimitUsage sums up payments of an account made within the windows containing the given moment.
+func (s PaymentsService) getLimitUsage(ctx context.Context, tx postgres.Database, id entity.AccountID, at time.Time) (service.LimitUsage, error) {
+	day, month := service.LimitWindows(at)
+	var result struct {
+		DailyAmount   money.Numeric `sql:"daily_amount"`
+		MonthlyAmount money.Numeric `sql:"monthly_amount"`
+		DailyCount    int           `sql:"daily_count"`
+		MonthlyCount  int           `sql:"monthly_count"`
+	}
+	// NOTE: a day always starts within its month, so daily figures are a subset of monthly ones.
+	const sql = `
+		SELECT
+			coalesce(sum(amount) FILTER (WHERE time >= ?day), 0) as daily_amount,
+			coalesce(sum(amount), 0) as monthly_amount,
+			count(*) FILTER (WHERE time >= ?day) as daily_count,
+			count(*) as monthly_count
+		FROM payment
+		WHERE from_account_id = ?id AND time >= ?month
+	`
+	_, err := tx.QueryOneContext(ctx, &result, sql, struct {
+		Id    string    `sql:"id"`
+		Day   time.Time `sql:"day"`
+		Month time.Time `sql:"month"`
+	}{
+		Id:    string(id),
+		Day:   day,
+		Month: month,
+	})
+	if err != nil {
+		return service.LimitUsage{}, err
+	}
+	return service.LimitUsage{
+		DailyAmount:   result.DailyAmount,
+		MonthlyAmount: result.MonthlyAmount,
+		DailyCount:    result.DailyCount,
+		MonthlyCount:  result.MonthlyCount,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package persistent
+
+import (
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+// paymentColumns are columns to select into paymentModel.
+const paymentColumns = `
+	id,
+	time,
+	from_account_id,
+	to_account_id,
+	amount,
+	currency,
+	fx_quote_id,
+	fx_rate,
+	to_amount,
+	to_currency,
+	refund_of,
+	fee,
+	fee_account_id,
+	array(SELECT r.id FROM payment r WHERE r.refund_of = payment.id ORDER BY r.id) as refunded_by`
+
+type paymentModel struct {
+	Id         int64          `sql:"id"`
+	Time       time.Time      `sql:"time"`
+	From       string         `pg:"from_account_id"`
+	To         string         `pg:"to_account_id"`
+	Amount     money.Numeric  `sql:"amount"`
+	Currency   string         `sql:"currency"`
+	FxQuoteId  int64          `sql:"fx_quote_id"`
+	FxRate     *money.Numeric `sql:"fx_rate"`
+	ToAmount   *money.Numeric `sql:"to_amount"`
+	ToCurrency string         `sql:"to_currency"`
+	RefundOf   int64          `sql:"refund_of"`
+	Fee        *money.Numeric `sql:"fee"`
+	FeeAccount string         `pg:"fee_account_id"`
+	RefundedBy []int64        `pg:"refunded_by,array"`
+	Outgoing   bool           `sql:"outgoing"`
+}
+
+func (m paymentModel) toEntity() entity.Payment {
+	p := entity.Payment{
+		Id: entity.PaymentID(m.Id),
+		Value: entity.PaymentValue{
+			Time:     m.Time,
+			From:     entity.AccountID(m.From),
+			To:       entity.AccountID(m.To),
+			Amount:   m.Amount,
+			Currency: money.Currency(m.Currency),
+			Outgoing: m.Outgoing,
+			RefundOf: entity.PaymentID(m.RefundOf),
+		},
+	}
+	for _, id := range m.RefundedBy {
+		p.RefundedBy = append(p.RefundedBy, entity.PaymentID(id))
+	}
+	if m.Fee != nil {
+		p.Value.Fee = *m.Fee
+		p.Value.FeeAccount = entity.AccountID(m.FeeAccount)
+	}
+	if m.FxRate != nil {
+		p.Value.Exchange = &entity.Exchange{
+			Quote:      entity.QuoteID(m.FxQuoteId),
+			Rate:       *m.FxRate,
+			ToAmount:   *m.ToAmount,
+			ToCurrency: money.Currency(m.ToCurrency),
+		}
+	}
+	return p
+}
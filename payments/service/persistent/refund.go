@@ -0,0 +1,88 @@
+package persistent
+
+import (
+	"context"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
+)
+
+func (s PaymentsService) Refund(ctx context.Context, id entity.PaymentID, amount *money.Numeric) (entity.PaymentID, error) {
+	var refundId entity.PaymentID
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		// Concurrent refunds of the same payment must not exceed its amount together.
+		original, err := s.getPaymentWithLock(ctx, tx, id)
+		if err != nil {
+			if errors.Is(err, pg.ErrNoRows) {
+				return service.ErrPaymentNotFound
+			}
+			return NewInternalErrorFromDBError(err)
+		}
+		if original.Value.Exchange != nil || original.Value.RefundOf != 0 {
+			return service.ErrNotRefundable
+		}
+
+		refunded, err := s.getRefundedAmount(ctx, tx, id)
+		if err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		refund := original.Value.Amount.Sub(refunded)
+		if amount != nil {
+			currency, ok := s.currencies.Lookup(original.Value.Currency)
+			if !ok {
+				return service.ErrIncompatibleCurrency
+			}
+			if !amount.IsPositive() || !amount.FitsDecimalPlaces(currency.MinorUnits) {
+				return service.ErrInvalidAmount
+			}
+			if refund.LessThan(*amount) {
+				return service.ErrRefundExceedsPayment
+			}
+			refund = *amount
+		}
+		if !refund.IsPositive() {
+			return service.ErrRefundExceedsPayment
+		}
+
+		refundId, err = s.transferInTx(ctx, tx, transferRequest{
+			value: entity.PaymentValue{
+				From:     original.Value.To,
+				To:       original.Value.From,
+				Amount:   refund,
+				Currency: original.Value.Currency,
+				RefundOf: id,
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return refundId, nil
+}
+
+func (s PaymentsService) getPaymentWithLock(ctx context.Context, tx postgres.Database, id entity.PaymentID) (entity.Payment, error) {
+	const sql = `--payments_get_with_lock
+		SELECT ` + paymentColumns + `
+		FROM payment WHERE id = ? FOR UPDATE`
+
+	var model paymentModel
+	_, err := tx.QueryOneContext(ctx, &model, sql, id)
+	if err != nil {
+		return entity.Payment{}, err
+	}
+	return model.toEntity(), nil
+}
+
+// getRefundedAmount returns how much of a payment has been refunded so far.
+func (s PaymentsService) getRefundedAmount(ctx context.Context, tx postgres.Database, id entity.PaymentID) (money.Numeric, error) {
+	var result struct {
+		Refunded money.Numeric `sql:"refunded"`
+	}
+	const sql = `SELECT coalesce(sum(amount), 0) as refunded FROM payment WHERE refund_of = ?`
+	_, err := tx.QueryOneContext(ctx, &result, sql, id)
+	return result.Refunded, err
+}
@@ -0,0 +1,313 @@
+package persistent
+
+import (
+	"context"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
+	"time"
+)
+
+// scheduleColumns are columns to select into scheduleModel.
+const scheduleColumns = `
+	id, from_account_id, to_account_id, amount, currency, recurrence, day_of_month,
+	start_at, end_at, status, next_run_at, created_at`
+
+type scheduleModel struct {
+	Id            int64         `sql:"id"`
+	FromAccountId string        `sql:"from_account_id"`
+	ToAccountId   string        `sql:"to_account_id"`
+	Amount        money.Numeric `sql:"amount"`
+	Currency      string        `sql:"currency"`
+	Recurrence    string        `sql:"recurrence"`
+	DayOfMonth    int           `sql:"day_of_month"`
+	StartAt       time.Time     `sql:"start_at"`
+	EndAt         *time.Time    `sql:"end_at"`
+	Status        string        `sql:"status"`
+	NextRunAt     *time.Time    `sql:"next_run_at"`
+	CreatedAt     time.Time     `sql:"created_at"`
+}
+
+func (m scheduleModel) toEntity() entity.ScheduledTransfer {
+	st := entity.ScheduledTransfer{
+		Id:         entity.ScheduledTransferID(m.Id),
+		From:       entity.AccountID(m.FromAccountId),
+		To:         entity.AccountID(m.ToAccountId),
+		Amount:     m.Amount,
+		Currency:   money.Currency(m.Currency),
+		Recurrence: entity.Recurrence(m.Recurrence),
+		DayOfMonth: m.DayOfMonth,
+		StartAt:    m.StartAt,
+		Status:     entity.ScheduleStatus(m.Status),
+		CreatedAt:  m.CreatedAt,
+	}
+	if m.EndAt != nil {
+		st.EndAt = *m.EndAt
+	}
+	if m.NextRunAt != nil {
+		st.NextRunAt = *m.NextRunAt
+	}
+	return st
+}
+
+// runColumns are columns to select into runModel.
+const runColumns = `id, scheduled_transfer_id, scheduled_at, ran_at, status, coalesce(payment_id, 0) AS payment_id, error`
+
+type runModel struct {
+	Id          int64     `sql:"id"`
+	Schedule    int64     `pg:"scheduled_transfer_id"`
+	ScheduledAt time.Time `sql:"scheduled_at"`
+	RanAt       time.Time `sql:"ran_at"`
+	Status      string    `sql:"status"`
+	PaymentId   int64     `sql:"payment_id"`
+	Error       string    `sql:"error"`
+}
+
+func (m runModel) toEntity() entity.ScheduledRun {
+	return entity.ScheduledRun{
+		Id:          entity.ScheduledRunID(m.Id),
+		Schedule:    entity.ScheduledTransferID(m.Schedule),
+		ScheduledAt: m.ScheduledAt,
+		RanAt:       m.RanAt,
+		Status:      entity.RunStatus(m.Status),
+		Payment:     entity.PaymentID(m.PaymentId),
+		Error:       m.Error,
+	}
+}
+
+// nullTime returns nil for zero t, so it's stored as NULL.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func (s PaymentsService) ScheduleTransfer(ctx context.Context, st entity.ScheduledTransfer) (entity.ScheduledTransfer, error) {
+	if err := s.validateTransfer(st.From, st.To, st.Amount, st.Currency); err != nil {
+		return entity.ScheduledTransfer{}, err
+	}
+	st, err := service.NewScheduledTransfer(st, time.Now().UTC())
+	if err != nil {
+		return entity.ScheduledTransfer{}, err
+	}
+
+	for _, id := range [2]entity.AccountID{st.From, st.To} {
+		exists, err := s.accountExists(ctx, id)
+		if err != nil {
+			return entity.ScheduledTransfer{}, NewInternalErrorFromDBError(err)
+		}
+		if !exists {
+			return entity.ScheduledTransfer{}, service.ErrAccountDoesNotExist
+		}
+	}
+
+	var model scheduleModel
+	const sql = `--scheduled_transfer_insert
+		INSERT INTO scheduled_transfer
+			(from_account_id, to_account_id, amount, currency, recurrence, day_of_month,
+			 start_at, end_at, status, next_run_at, created_at)
+		VALUES
+			(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING ` + scheduleColumns
+	_, err = s.pg.QueryOneContext(ctx, &model, sql,
+		string(st.From), string(st.To), st.Amount, string(st.Currency), string(st.Recurrence), st.DayOfMonth,
+		st.StartAt, nullTime(st.EndAt), string(st.Status), nullTime(st.NextRunAt), st.CreatedAt,
+	)
+	if err != nil {
+		return entity.ScheduledTransfer{}, NewInternalErrorFromDBError(err)
+	}
+	return model.toEntity(), nil
+}
+
+func (s PaymentsService) GetScheduledTransfers(ctx context.Context, from entity.AccountID) ([]entity.ScheduledTransfer, error) {
+	if from != "" {
+		exists, err := s.accountExists(ctx, from)
+		if err != nil {
+			return nil, NewInternalErrorFromDBError(err)
+		}
+		if !exists {
+			return nil, service.ErrAccountDoesNotExist
+		}
+	}
+
+	var rows []scheduleModel
+	const sql = `--scheduled_transfer_list
+		SELECT ` + scheduleColumns + `
+		FROM scheduled_transfer
+		WHERE ? = '' OR from_account_id = ?
+		ORDER BY id ASC
+	`
+	_, err := s.pg.QueryContext(ctx, &rows, sql, string(from), string(from))
+	if err != nil {
+		return nil, NewInternalErrorFromDBError(err)
+	}
+	schedules := make([]entity.ScheduledTransfer, 0, len(rows))
+	for _, r := range rows {
+		schedules = append(schedules, r.toEntity())
+	}
+	return schedules, nil
+}
+
+func (s PaymentsService) CancelScheduledTransfer(ctx context.Context, id entity.ScheduledTransferID) (entity.ScheduledTransfer, error) {
+	var st entity.ScheduledTransfer
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		// Waits for a run in progress, so the transfer is either made or cancelled, never both.
+		var model scheduleModel
+		_, err := tx.QueryOneContext(ctx, &model,
+			`SELECT `+scheduleColumns+` FROM scheduled_transfer WHERE id = ? FOR UPDATE`, id,
+		)
+		if errors.Is(err, pg.ErrNoRows) {
+			return service.ErrScheduleNotFound
+		}
+		if err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		st, err = service.CancelSchedule(model.toEntity())
+		if err != nil {
+			return err
+		}
+		if err := s.updateSchedule(ctx, tx, st); err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return entity.ScheduledTransfer{}, err
+	}
+	return st, nil
+}
+
+func (s PaymentsService) updateSchedule(ctx context.Context, tx postgres.Database, st entity.ScheduledTransfer) error {
+	const sql = `--scheduled_transfer_update
+		UPDATE scheduled_transfer SET status = ?, next_run_at = ? WHERE id = ?
+	`
+	_, err := tx.ExecContext(ctx, sql, string(st.Status), nullTime(st.NextRunAt), st.Id)
+	return err
+}
+
+func (s PaymentsService) GetScheduledRuns(ctx context.Context, id entity.ScheduledTransferID) ([]entity.ScheduledRun, error) {
+	var result struct {
+		Exists bool `sql:"exists"`
+	}
+	_, err := s.pg.QueryOneContext(ctx, &result, `SELECT exists(select id from scheduled_transfer where id = ?) as exists`, id)
+	if err != nil {
+		return nil, NewInternalErrorFromDBError(err)
+	}
+	if !result.Exists {
+		return nil, service.ErrScheduleNotFound
+	}
+
+	var rows []runModel
+	_, err = s.pg.QueryContext(ctx, &rows,
+		`SELECT `+runColumns+` FROM scheduled_transfer_run WHERE scheduled_transfer_id = ? ORDER BY id ASC`, id,
+	)
+	if err != nil {
+		return nil, NewInternalErrorFromDBError(err)
+	}
+	runs := make([]entity.ScheduledRun, 0, len(rows))
+	for _, r := range rows {
+		runs = append(runs, r.toEntity())
+	}
+	return runs, nil
+}
+
+// errNoDueSchedules stops RunScheduledTransfers when there is nothing left to run.
+var errNoDueSchedules = errors.New("no due scheduled transfers")
+
+func (s PaymentsService) RunScheduledTransfers(ctx context.Context, now time.Time, limit int) (int, error) {
+	// Schedules which failed internally stay due, so they're skipped for the rest of the pass
+	// instead of blocking the others. Not nil, since go-pg renders nil slices as NULL.
+	failed := []int64{}
+	var firstErr error
+	for ran := 0; ran < limit; {
+		id, err := s.runScheduledTransfer(ctx, now, failed)
+		if errors.Is(err, errNoDueSchedules) {
+			return ran, firstErr
+		}
+		if err != nil {
+			if ctx.Err() != nil || id == 0 {
+				return ran, err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed = append(failed, int64(id))
+			continue
+		}
+		ran++
+	}
+	return limit, firstErr
+}
+
+// runScheduledTransfer makes a single due scheduled transfer, except those in skip, which stays locked meanwhile,
+// so concurrent schedulers skip it. The transfer, its run and the next occurrence are committed together,
+// so every occurrence is run exactly once. Returns id of the scheduled transfer, if one was picked.
+func (s PaymentsService) runScheduledTransfer(ctx context.Context, now time.Time, skip []int64) (entity.ScheduledTransferID, error) {
+	var id entity.ScheduledTransferID
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		var model scheduleModel
+		const sql = `--scheduled_transfer_due
+			SELECT ` + scheduleColumns + `
+			FROM scheduled_transfer
+			WHERE status = 'active' AND next_run_at <= ? AND id <> ALL(?)
+			ORDER BY next_run_at ASC, id ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		`
+		_, err := tx.QueryOneContext(ctx, &model, sql, now, pg.Array(skip))
+		if errors.Is(err, pg.ErrNoRows) {
+			return errNoDueSchedules
+		}
+		if err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		st := model.toEntity()
+		id = st.Id
+
+		// The transfer runs in a savepoint, so a failed one is rolled back, but still recorded.
+		var paymentId entity.PaymentID
+		transferErr := s.validateTransfer(st.From, st.To, st.Amount, st.Currency)
+		if transferErr == nil {
+			transferErr = postgres.NestedRunInTransaction(ctx, tx, func(tx postgres.Database) error {
+				var err error
+				paymentId, err = s.transferInTx(ctx, tx, transferRequest{
+					value: entity.PaymentValue{
+						From:     st.From,
+						To:       st.To,
+						Amount:   st.Amount,
+						Currency: st.Currency,
+					},
+					chargeFee: true,
+				})
+				return err
+			})
+		}
+		if transferErr != nil && !service.IsRunFailure(transferErr) {
+			return transferErr
+		}
+
+		st, run := service.RecordRun(st, now, paymentId, transferErr)
+		var payment *int64
+		if run.Payment != 0 {
+			id := int64(run.Payment)
+			payment = &id
+		}
+		const insertRun = `--scheduled_transfer_run_insert
+			INSERT INTO scheduled_transfer_run (scheduled_transfer_id, scheduled_at, ran_at, status, payment_id, error)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`
+		_, err = tx.ExecContext(ctx, insertRun, run.Schedule, run.ScheduledAt, run.RanAt, string(run.Status), payment, run.Error)
+		if err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		if err := s.updateSchedule(ctx, tx, st); err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		return nil
+	})
+	return id, err
+}
@@ -1,25 +1,139 @@
 package persistent
 
 import (
+	"context"
 	"fmt"
 	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/payments/service/tracing"
+	"github.com/lightsgoout/fintech-go/pkg/fx"
+	"github.com/lightsgoout/fintech-go/pkg/money"
 	"github.com/lightsgoout/fintech-go/pkg/postgres"
+	"go.opentelemetry.io/otel/trace"
+	"time"
 )
 
+// DefaultIdempotencyRetention is how long idempotency keys are remembered unless configured otherwise.
+const DefaultIdempotencyRetention = 24 * time.Hour
+
+// DefaultQuoteTTL is how long exchange rates stay locked unless configured otherwise.
+const DefaultQuoteTTL = 30 * time.Second
+
+// DefaultHoldTTL is how long holds reserve money unless configured otherwise.
+const DefaultHoldTTL = 7 * 24 * time.Hour
+
 // PaymentsService implements service.PaymentsService interface by using persistent storage.
 //
 // NOTE: pg can be pg.DB (in production) or pg.Tx (in tests), because we must isolate tests in transactions.
 type PaymentsService struct {
 	pg postgres.Database
+
+	// currencies are the currencies accounts and payments may be denominated in.
+	currencies *money.CurrencyRegistry
+
+	// idempotencyRetention is a window during which a replayed Transfer returns the original payment.
+	idempotencyRetention time.Duration
+
+	// rates is a source of exchange rates for cross-currency transfers, which are disabled if it's nil.
+	rates fx.RateProvider
+
+	// quoteTTL is how long a quoted exchange rate can be used.
+	quoteTTL time.Duration
+
+	// holdTTL is how long a hold reserves money unless captured or voided.
+	holdTTL time.Duration
+
+	// fees are charged from senders of transfers.
+	fees service.Fees
+
+	// tracer traces steps of operations worth a span of their own, e.g. waiting for locks.
+	tracer trace.Tracer
+}
+
+// Option configures optional settings of PaymentsService.
+type Option func(s *PaymentsService)
+
+// WithIdempotencyRetention sets how long idempotency keys of transfers are remembered.
+func WithIdempotencyRetention(retention time.Duration) Option {
+	return func(s *PaymentsService) {
+		s.idempotencyRetention = retention
+	}
+}
+
+// WithCurrencyRegistry sets currencies the service operates with.
+// Use SyncCurrencies to make them known to the database as well.
+func WithCurrencyRegistry(currencies *money.CurrencyRegistry) Option {
+	return func(s *PaymentsService) {
+		s.currencies = currencies
+	}
+}
+
+// WithRateProvider enables cross-currency transfers using exchange rates of the given provider.
+func WithRateProvider(rates fx.RateProvider) Option {
+	return func(s *PaymentsService) {
+		s.rates = rates
+	}
+}
+
+// WithQuoteTTL sets how long quoted exchange rates stay locked.
+func WithQuoteTTL(ttl time.Duration) Option {
+	return func(s *PaymentsService) {
+		s.quoteTTL = ttl
+	}
+}
+
+// WithHoldTTL sets how long holds reserve money.
+func WithHoldTTL(ttl time.Duration) Option {
+	return func(s *PaymentsService) {
+		s.holdTTL = ttl
+	}
+}
+
+// WithFees enables charging fees for transfers.
+func WithFees(fees service.Fees) Option {
+	return func(s *PaymentsService) {
+		s.fees = fees
+	}
+}
+
+// WithTracerProvider enables tracing of steps of operations. Queries are traced by postgres.TracingHook.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *PaymentsService) {
+		s.tracer = tp.Tracer(tracing.InstrumentationName)
+	}
 }
 
 // NewPaymentsService returns new PaymentsService with Postgres connection.
-func NewPaymentsService(pg postgres.Database) PaymentsService {
-	return PaymentsService{
-		pg: pg,
+func NewPaymentsService(pg postgres.Database, opts ...Option) PaymentsService {
+	s := PaymentsService{
+		pg:                   pg,
+		currencies:           money.DefaultCurrencyRegistry,
+		idempotencyRetention: DefaultIdempotencyRetention,
+		quoteTTL:             DefaultQuoteTTL,
+		holdTTL:              DefaultHoldTTL,
+		tracer:               trace.NewNoopTracerProvider().Tracer(""),
 	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
 }
 
 func NewInternalErrorFromDBError(err error) service.ErrInternal {
 	return service.NewErrInternal(fmt.Errorf("database error: %w", err))
 }
+
+// InTransaction implements service.Transactional, calls of svc are made within a transaction of s.
+func (s PaymentsService) InTransaction(ctx context.Context, fn func(svc service.PaymentsService) error) error {
+	var fnErr error
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		txSvc := s
+		txSvc.pg = tx
+		fnErr = fn(txSvc)
+		return fnErr
+	})
+	if err != nil && fnErr == nil {
+		// The transaction has failed to commit
+		return NewInternalErrorFromDBError(err)
+	}
+	return err
+}
@@ -0,0 +1,24 @@
+package persistent
+
+import (
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/payments/service/servicetest"
+	"github.com/lightsgoout/fintech-go/pkg/testing/isolation"
+	"testing"
+)
+
+func TestPaymentsService_Conformance(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	servicetest.Run(t, func(t *testing.T, cfg servicetest.Config) service.PaymentsService {
+		isolation.Isolate(t, env.Tx)
+		return NewPaymentsService(env.Tx,
+			WithRateProvider(cfg.Rates),
+			WithQuoteTTL(cfg.QuoteTTL),
+			WithIdempotencyRetention(cfg.IdempotencyRetention),
+			WithHoldTTL(cfg.HoldTTL),
+			WithFees(cfg.Fees),
+		)
+	})
+}
@@ -0,0 +1,118 @@
+package persistent
+
+import (
+	"context"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+func (s PaymentsService) GetBalance(ctx context.Context, id entity.AccountID, at time.Time) (entity.Balance, error) {
+	var result struct {
+		Currency string        `sql:"currency"`
+		Balance  money.Numeric `sql:"balance"`
+	}
+	// Historical balances come from the journal, the same way VerifyBalances proves current ones.
+	const sql = `--journal_balance
+		SELECT
+			a.currency,
+			(SELECT coalesce(sum(j.amount), 0) FROM journal_entry j WHERE j.account_id = a.id AND j.time <= ?at) as balance
+		FROM account a
+		WHERE a.id = ?id
+	`
+	_, err := s.pg.QueryOneContext(ctx, &result, sql, struct {
+		Id string    `sql:"id"`
+		At time.Time `sql:"at"`
+	}{
+		Id: string(id),
+		At: at,
+	})
+	if err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return entity.Balance{}, service.ErrAccountDoesNotExist
+		}
+		return entity.Balance{}, NewInternalErrorFromDBError(err)
+	}
+	return entity.Balance{
+		Account:  id,
+		At:       at,
+		Amount:   result.Balance,
+		Currency: money.Currency(result.Currency),
+	}, nil
+}
+
+func (s PaymentsService) GetStatement(ctx context.Context, id entity.AccountID, since, until time.Time) (entity.Statement, error) {
+	until, err := service.StatementPeriod(since, until)
+	if err != nil {
+		return entity.Statement{}, err
+	}
+
+	// Zero since means the very beginning, it mustn't be rendered as NULL, which matches nothing.
+	params := struct {
+		Id    string    `sql:"id"`
+		Since time.Time `pg:"since,use_zero"`
+		Until time.Time `sql:"until"`
+	}{
+		Id:    string(id),
+		Since: since,
+		Until: until,
+	}
+
+	var opening struct {
+		Currency string        `sql:"currency"`
+		Balance  money.Numeric `sql:"balance"`
+	}
+	const openingSql = `--statement_opening
+		SELECT
+			a.currency,
+			(SELECT coalesce(sum(j.amount), 0) FROM journal_entry j WHERE j.account_id = a.id AND j.time < ?since) as balance
+		FROM account a
+		WHERE a.id = ?id
+	`
+	if _, err := s.pg.QueryOneContext(ctx, &opening, openingSql, params); err != nil {
+		if errors.Is(err, pg.ErrNoRows) {
+			return entity.Statement{}, service.ErrAccountDoesNotExist
+		}
+		return entity.Statement{}, NewInternalErrorFromDBError(err)
+	}
+
+	// A payment may have several entries of the account, e.g. the amount and the fee, they make a single line.
+	// NOTE: closing balance is derived from the lines, so the statement is consistent
+	// even if payments are made while it's being read.
+	var rows []struct {
+		Time         time.Time     `sql:"time"`
+		PaymentId    int64         `sql:"payment_id"`
+		Counterparty string        `sql:"counterparty"`
+		Amount       money.Numeric `sql:"amount"`
+	}
+	const linesSql = `--statement_lines
+		SELECT
+			j.time,
+			j.payment_id,
+			CASE WHEN p.from_account_id = ?id THEN p.to_account_id ELSE p.from_account_id END as counterparty,
+			sum(j.amount) as amount
+		FROM journal_entry j
+		LEFT JOIN payment p ON p.id = j.payment_id
+		WHERE j.account_id = ?id AND j.time >= ?since AND j.time < ?until
+		GROUP BY j.time, j.payment_id, p.from_account_id, p.to_account_id
+		ORDER BY min(j.id) ASC
+	`
+	if _, err := s.pg.QueryContext(ctx, &rows, linesSql, params); err != nil {
+		return entity.Statement{}, NewInternalErrorFromDBError(err)
+	}
+
+	lines := make([]entity.StatementLine, 0, len(rows))
+	for _, r := range rows {
+		lines = append(lines, entity.StatementLine{
+			Time:         r.Time,
+			Payment:      entity.PaymentID(r.PaymentId),
+			Counterparty: entity.AccountID(r.Counterparty),
+			Amount:       r.Amount,
+		})
+	}
+	account := entity.Account{Id: id, Currency: money.Currency(opening.Currency)}
+	return service.NewStatement(account, since, until, opening.Balance, lines), nil
+}
@@ -0,0 +1,43 @@
+package persistent
+
+import (
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/lightsgoout/fintech-go/pkg/testing/isolation"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPaymentsService_GetStatement(t *testing.T) {
+	env := isolation.PrepareTest(t)
+	defer env.Rollback()
+
+	svc := NewPaymentsService(env.Tx)
+
+	t.Run("zero since starts from the first payment", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		const bob = entity.AccountID("bob")
+		const alice = entity.AccountID("alice")
+		for _, id := range [...]entity.AccountID{bob, alice} {
+			err := svc.CreateAccount(env.Ctx, id, money.NewNumericFromInt64(100), "USD")
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		_, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(30), "USD", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		statement, err := svc.GetStatement(env.Ctx, bob, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, statement.OpeningBalance.Equal(money.NewNumericFromInt64(0)), "opening balance is %s", statement.OpeningBalance)
+		if assert.Len(t, statement.Lines, 2, "initial deposit and the transfer") {
+			assert.Equal(t, statement.Lines[1].Counterparty, alice)
+			assert.True(t, statement.Lines[1].Amount.Equal(money.NewNumericFromInt64(-30)))
+		}
+		assert.True(t, statement.ClosingBalance.Equal(money.NewNumericFromInt64(70)), "closing balance is %s", statement.ClosingBalance)
+	}))
+}
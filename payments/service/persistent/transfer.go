@@ -2,143 +2,346 @@ package persistent
 
 import (
 	"context"
+	"fmt"
 	"github.com/lightsgoout/fintech-go/payments/entity"
 	"github.com/lightsgoout/fintech-go/payments/service"
 	"github.com/lightsgoout/fintech-go/pkg/money"
 	"github.com/lightsgoout/fintech-go/pkg/postgres"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sort"
 	"strings"
 	"time"
 )
 
-func (s PaymentsService) Transfer(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency) (entity.PaymentID, error) {
-	if from == to {
-		return 0, service.ErrBadTransferTarget
+func (s PaymentsService) Transfer(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency, idempotencyKey entity.IdempotencyKey) (entity.PaymentID, error) {
+	if err := s.validateTransfer(from, to, amount, cur); err != nil {
+		return 0, err
 	}
 
-	// Freeze time so it would be consistent across all possible operations
-	ts := time.Now().UTC()
+	return s.transfer(ctx, transferRequest{
+		value: entity.PaymentValue{
+			From:     from,
+			To:       to,
+			Amount:   amount,
+			Currency: cur,
+		},
+		idempotencyKey: idempotencyKey,
+		chargeFee:      true,
+	})
+}
 
-	var paymentId entity.PaymentID
+// validateTransfer checks transfer parameters which don't depend on the state of accounts.
+func (s PaymentsService) validateTransfer(from, to entity.AccountID, amount money.Numeric, cur money.Currency) error {
+	if from == to {
+		return service.ErrBadTransferTarget
+	}
 
-	// Open a transaction and lock both accounts for update
-	// NOTE: we're not gonna modify accounts' primary keys,
-	// so FOR NO KEY UPDATE is sufficient here and improves concurrency.
-	// Also it's important to lock rows in deterministic order to prevent deadlocks.
-	// We'll always lock the FROM account first.
-	var lockOrder = [2]entity.AccountID{
-		from,
-		to,
+	currency, ok := s.currencies.Lookup(cur)
+	if !ok {
+		return service.ErrIncompatibleCurrency
 	}
 
+	if !amount.IsPositive() || !amount.FitsDecimalPlaces(currency.MinorUnits) {
+		return service.ErrInvalidAmount
+	}
+	return nil
+}
+
+// transferRequest is a validated request to move money between two accounts.
+type transferRequest struct {
+	// value describes the payment to be made
+	value entity.PaymentValue
+
+	idempotencyKey entity.IdempotencyKey
+
+	// quoteExpiresAt is a moment after which the exchange rate of value can't be used for a new payment
+	quoteExpiresAt time.Time
+
+	// hold is a hold being captured by the transfer, money reserved by it is spendable
+	hold entity.HoldID
+
+	// chargeFee tells whether the sender pays a fee, see service.Fees
+	chargeFee bool
+}
+
+// transfer debits value.Amount from one account and credits value.CreditAmount() to another, atomically.
+func (s PaymentsService) transfer(ctx context.Context, req transferRequest) (entity.PaymentID, error) {
+	var paymentId entity.PaymentID
 	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
-		accounts := make(map[entity.AccountID]entity.Account, 2)
-		for _, id := range lockOrder {
-			account, err := s.getAccountWithLock(ctx, tx, id)
-			if err != nil {
-				if strings.Contains(err.Error(), "no rows in result set") {
-					return service.ErrAccountDoesNotExist
+		var err error
+		paymentId, err = s.transferInTx(ctx, tx, req)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return paymentId, nil
+}
+
+// transferInTx does the job of transfer within transaction tx, so it could be a part of a larger operation.
+func (s PaymentsService) transferInTx(ctx context.Context, tx postgres.Database, req transferRequest) (entity.PaymentID, error) {
+	value, idempotencyKey := req.value, req.idempotencyKey
+	from, to := value.From, value.To
+
+	// Freeze time so it would be consistent across all possible operations
+	value.Time = time.Now().UTC()
+
+	if idempotencyKey != "" {
+		// A retried request must either see the original payment or make it by itself, never both.
+		if err := s.lockIdempotencyKey(ctx, tx, idempotencyKey); err != nil {
+			return 0, NewInternalErrorFromDBError(err)
+		}
+		previous, found, err := s.getPaymentByIdempotencyKey(ctx, tx, idempotencyKey)
+		if err != nil {
+			return 0, NewInternalErrorFromDBError(err)
+		}
+		if found {
+			if previous.Value.Time.After(value.Time.Add(-s.idempotencyRetention)) {
+				if !service.SameTransfer(previous.Value, value) {
+					return 0, service.ErrIdempotencyKeyReused
 				}
-				return NewInternalErrorFromDBError(err)
+				return previous.Id, nil
+			}
+			// The key is past its retention window, so it's treated as a brand new one.
+			if err := s.expireIdempotencyKey(ctx, tx, idempotencyKey); err != nil {
+				return 0, NewInternalErrorFromDBError(err)
 			}
-			accounts[id] = account
 		}
+	}
 
-		if accounts[from].Currency != accounts[to].Currency {
-			return service.ErrIncompatibleCurrency
-		}
+	if value.Exchange != nil && value.Time.After(req.quoteExpiresAt) {
+		return 0, service.ErrQuoteExpired
+	}
 
-		if accounts[from].Currency != cur {
-			return service.ErrIncompatibleCurrency
+	if req.chargeFee {
+		currency, _ := s.currencies.Lookup(value.Currency)
+		var err error
+		value.Fee, value.FeeAccount, err = s.fees.Charge(value, currency)
+		if err != nil {
+			return 0, err
 		}
+	}
 
-		newBalanceFrom := accounts[from].Balance.Sub(amount)
-		newBalanceTo := accounts[to].Balance.Add(amount)
-		if newBalanceFrom.LessThan(money.NewNumericFromInt64(0)) {
-			return service.ErrInsufficientFunds
-		}
+	lock := []entity.AccountID{from, to}
+	if value.FeeAccount != "" {
+		lock = append(lock, value.FeeAccount)
+	}
+	accounts, err := s.lockAccounts(ctx, tx, lock...)
+	if err != nil {
+		return 0, err
+	}
 
-		// With both accounts' locks acquired we can proceed to transfer the money.
-		err := s.updateBalance(ctx, tx, from, newBalanceFrom)
-		if err != nil {
-			return NewInternalErrorFromDBError(err)
+	if err := service.CheckCanDebit(accounts[from]); err != nil {
+		return 0, err
+	}
+	if err := service.CheckCanCredit(accounts[to]); err != nil {
+		return 0, err
+	}
+
+	if accounts[from].Currency != value.Currency {
+		return 0, service.ErrIncompatibleCurrency
+	}
+
+	if accounts[to].Currency != value.CreditCurrency() {
+		return 0, service.ErrIncompatibleCurrency
+	}
+
+	if value.FeeAccount != "" {
+		if accounts[value.FeeAccount].Currency != value.Currency {
+			return 0, service.NewErrInternal(fmt.Errorf("revenue account %s is not in %s", value.FeeAccount, value.Currency))
 		}
-		err = s.updateBalance(ctx, tx, to, newBalanceTo)
-		if err != nil {
-			return NewInternalErrorFromDBError(err)
+		if err := service.CheckCanCredit(accounts[value.FeeAccount]); err != nil {
+			return 0, err
 		}
+	}
 
-		// Create new Payment
-		paymentId, err = s.createPayment(ctx, tx, entity.PaymentValue{
-			Time:     ts,
-			From:     from,
-			To:       to,
-			Amount:   amount,
-			Currency: cur,
-		})
+	// The sender's account is locked, so concurrent payments can't exceed its limits together.
+	if err := s.checkLimits(ctx, tx, value); err != nil {
+		return 0, err
+	}
+
+	// Money reserved by holds can't be spent, see Authorize.
+	held, err := s.getHeldAmount(ctx, tx, from, value.Time, req.hold)
+	if err != nil {
+		return 0, NewInternalErrorFromDBError(err)
+	}
+
+	newBalanceFrom := accounts[from].Balance.Sub(value.Amount).Sub(value.Fee)
+	newBalanceTo := accounts[to].Balance.Add(value.CreditAmount())
+	if err := service.CheckSufficientFunds(accounts[from], newBalanceFrom.Sub(held)); err != nil {
+		return 0, err
+	}
+
+	// With all accounts' locks acquired we can proceed to transfer the money.
+	err = s.updateBalance(ctx, tx, from, newBalanceFrom)
+	if err != nil {
+		return 0, NewInternalErrorFromDBError(err)
+	}
+	if value.FeeAccount == to {
+		newBalanceTo = newBalanceTo.Add(value.Fee)
+	} else if value.FeeAccount != "" {
+		err = s.updateBalance(ctx, tx, value.FeeAccount, accounts[value.FeeAccount].Balance.Add(value.Fee))
 		if err != nil {
-			return NewInternalErrorFromDBError(err)
+			return 0, NewInternalErrorFromDBError(err)
 		}
-		return nil
-	})
+	}
+	err = s.updateBalance(ctx, tx, to, newBalanceTo)
 	if err != nil {
-		return 0, err
+		return 0, NewInternalErrorFromDBError(err)
+	}
+
+	// Create new Payment
+	paymentId, err := s.createPayment(ctx, tx, value, idempotencyKey)
+	if err != nil {
+		return 0, NewInternalErrorFromDBError(err)
+	}
+	err = s.journalPayment(ctx, tx, paymentId, value)
+	if err != nil {
+		return 0, NewInternalErrorFromDBError(err)
 	}
 	return paymentId, nil
 }
 
 func (s PaymentsService) updateBalance(ctx context.Context, tx postgres.Database, id entity.AccountID, newBalance money.Numeric) error {
-	_, err := tx.ExecContext(ctx, `UPDATE account SET balance = ? WHERE id = ?`, newBalance.String(), id)
+	_, err := tx.ExecContext(ctx, `UPDATE account SET balance = ? WHERE id = ?`, newBalance, id)
 	return err
 }
 
-func (s PaymentsService) createPayment(ctx context.Context, tx postgres.Database, value entity.PaymentValue) (entity.PaymentID, error) {
+func (s PaymentsService) createPayment(ctx context.Context, tx postgres.Database, value entity.PaymentValue, idempotencyKey entity.IdempotencyKey) (entity.PaymentID, error) {
 	var result struct {
 		Id int64 `sql:"id"`
 	}
 	const sql = `--payments_insert
 		INSERT INTO payment
-			(time, from_account_id, to_account_id, amount, currency)
+			(time, from_account_id, to_account_id, amount, currency, idempotency_key,
+			 fx_quote_id, fx_rate, to_amount, to_currency, refund_of, fee, fee_account_id)
 		VALUES
-			(?time, ?from_account_id, ?to_account_id, ?amount, ?currency)
+			(?time, ?from_account_id, ?to_account_id, ?amount, ?currency, ?idempotency_key,
+			 ?fx_quote_id, ?fx_rate, ?to_amount, ?to_currency, ?refund_of, ?fee, ?fee_account_id)
 		RETURNING
 			id as id;
 	`
-	_, err := tx.QueryOneContext(ctx, &result, sql, struct {
-		Time          time.Time `sql:"time"`
-		FromAccountId string    `sql:"from_account_id"`
-		ToAccountId   string    `sql:"to_account_id"`
-		Amount        string    `sql:"amount"`
-		Currency      string    `sql:"currency"`
+	params := struct {
+		Time           time.Time      `sql:"time"`
+		FromAccountId  string         `sql:"from_account_id"`
+		ToAccountId    string         `sql:"to_account_id"`
+		Amount         money.Numeric  `sql:"amount"`
+		Currency       string         `sql:"currency"`
+		IdempotencyKey string         `sql:"idempotency_key"`
+		FxQuoteId      int64          `sql:"fx_quote_id"`
+		FxRate         *money.Numeric `sql:"fx_rate"`
+		ToAmount       *money.Numeric `sql:"to_amount"`
+		ToCurrency     string         `sql:"to_currency"`
+		RefundOf       int64          `sql:"refund_of"`
+		Fee            *money.Numeric `sql:"fee"`
+		FeeAccountId   string         `sql:"fee_account_id"`
 	}{
-		Time:          value.Time,
-		FromAccountId: string(value.From),
-		ToAccountId:   string(value.To),
-		Amount:        value.Amount.String(),
-		Currency:      string(value.Currency),
-	})
+		Time:           value.Time,
+		FromAccountId:  string(value.From),
+		ToAccountId:    string(value.To),
+		Amount:         value.Amount,
+		Currency:       string(value.Currency),
+		IdempotencyKey: string(idempotencyKey),
+		RefundOf:       int64(value.RefundOf),
+	}
+	if value.FeeAccount != "" {
+		params.Fee = &value.Fee
+		params.FeeAccountId = string(value.FeeAccount)
+	}
+	if value.Exchange != nil {
+		params.FxQuoteId = int64(value.Exchange.Quote)
+		params.FxRate = &value.Exchange.Rate
+		params.ToAmount = &value.Exchange.ToAmount
+		params.ToCurrency = string(value.Exchange.ToCurrency)
+	}
+	_, err := tx.QueryOneContext(ctx, &result, sql, params)
 	if err != nil {
 		return 0, err
 	}
-	return entity.PaymentID(result.Id), nil
+
+	id := entity.PaymentID(result.Id)
+	event, err := service.NewPaymentCreatedEvent(id, value)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.createEvent(ctx, tx, event); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// lockAccounts locks accounts for update till the end of the transaction and returns them.
+// NOTE: we're not gonna modify accounts' primary keys,
+// so FOR NO KEY UPDATE is sufficient here and improves concurrency.
+// Also it's important to lock rows in deterministic order to prevent deadlocks,
+// so every operation locks accounts in ascending order of their ids.
+func (s PaymentsService) lockAccounts(ctx context.Context, tx postgres.Database, ids ...entity.AccountID) (map[entity.AccountID]entity.Account, error) {
+	lockOrder := make([]entity.AccountID, 0, len(ids))
+	accounts := make(map[entity.AccountID]entity.Account, len(ids))
+	for _, id := range ids {
+		if _, seen := accounts[id]; !seen {
+			accounts[id] = entity.Account{}
+			lockOrder = append(lockOrder, id)
+		}
+	}
+	sort.Slice(lockOrder, func(i, j int) bool {
+		return lockOrder[i] < lockOrder[j]
+	})
+
+	for _, id := range lockOrder {
+		account, err := s.getAccountWithLock(ctx, tx, id)
+		if err != nil {
+			if strings.Contains(err.Error(), "no rows in result set") {
+				return nil, service.ErrAccountDoesNotExist
+			}
+			return nil, NewInternalErrorFromDBError(err)
+		}
+		accounts[id] = account
+	}
+	return accounts, nil
 }
 
-func (s PaymentsService) getAccountWithLock(ctx context.Context, tx postgres.Database, id entity.AccountID) (entity.Account, error) {
+// getAccountWithLock returns the account locked until the end of tx. Waiting for the lock is traced,
+// since it's where concurrent transfers of the same account spend their time.
+func (s PaymentsService) getAccountWithLock(ctx context.Context, tx postgres.Database, id entity.AccountID) (_ entity.Account, err error) {
+	ctx, span := s.tracer.Start(ctx, "getAccountWithLock", trace.WithAttributes(attribute.String("account.id", string(id))))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
+
 	var model struct {
-		Id       string `sql:"id"`
-		Currency string `sql:"currency"`
-		Balance  string `sql:"balance"`
+		Id           string        `sql:"id"`
+		Currency     string        `sql:"currency"`
+		Balance      money.Numeric `sql:"balance"`
+		Status       string        `sql:"status"`
+		StatusReason string        `sql:"status_reason"`
+		CreditLimit  money.Numeric `sql:"credit_limit"`
+		Unbounded    bool          `sql:"unbounded"`
 	}
 
-	const sql = `SELECT id, currency, balance::text as balance FROM account WHERE id = ? FOR NO KEY UPDATE`
+	const sql = `--account_lock
+		SELECT id, currency, balance, status, status_reason, credit_limit, unbounded
+		FROM account
+		WHERE id = ?
+		FOR NO KEY UPDATE
+	`
 
-	_, err := tx.QueryOneContext(ctx, &model, sql, id)
+	_, err = tx.QueryOneContext(ctx, &model, sql, id)
 	if err != nil {
 		return entity.Account{}, err
 	}
 
 	return entity.Account{
-		Id:       entity.AccountID(model.Id),
-		Currency: money.Currency(model.Currency),
-		Balance:  money.NewNumericFromStringMust(model.Balance),
+		Id:           entity.AccountID(model.Id),
+		Currency:     money.Currency(model.Currency),
+		Balance:      model.Balance,
+		Status:       entity.AccountStatus(model.Status),
+		StatusReason: model.StatusReason,
+		CreditLimit:  model.CreditLimit,
+		Unbounded:    model.Unbounded,
 	}, nil
 }
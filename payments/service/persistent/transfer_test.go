@@ -6,6 +6,7 @@ import (
 	"github.com/lightsgoout/fintech-go/payments/service"
 	"github.com/lightsgoout/fintech-go/pkg/money"
 	"github.com/lightsgoout/fintech-go/pkg/testing/isolation"
+	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
@@ -34,51 +35,108 @@ func TestPaymentsService_Transfer(t *testing.T) {
 	}
 
 	t.Run("transfer 50 from bob to alice", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
-		_, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(50), "USD")
+		_, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(50), "USD", "")
 		if err != nil {
 			t.Error(err)
 		}
 	}))
 
 	t.Run("transfer 50 from alice to bob", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
-		_, err := svc.Transfer(env.Ctx, alice, bob, money.NewNumericFromInt64(50), "USD")
+		_, err := svc.Transfer(env.Ctx, alice, bob, money.NewNumericFromInt64(50), "USD", "")
 		if err != nil {
 			t.Error(err)
 		}
 	}))
 
 	t.Run("no transferring more than balance value", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
-		paymentId, err := svc.Transfer(env.Ctx, alice, bob, money.NewNumericFromInt64(9000), "USD")
+		paymentId, err := svc.Transfer(env.Ctx, alice, bob, money.NewNumericFromInt64(9000), "USD", "")
 		if !errors.Is(err, service.ErrInsufficientFunds) {
 			t.Errorf("expected ErrInsufficientFunds, got paymentId=%v, err=%v", paymentId, err)
 		}
 	}))
 
 	t.Run("only same currency allowed bob-side", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
-		paymentId, err := svc.Transfer(env.Ctx, alice, bob, money.NewNumericFromInt64(9000), "EUR")
+		paymentId, err := svc.Transfer(env.Ctx, alice, bob, money.NewNumericFromInt64(9000), "EUR", "")
 		if !errors.Is(err, service.ErrIncompatibleCurrency) {
 			t.Errorf("expected ErrIncompatibleCurrency, got paymentId=%v, err=%v", paymentId, err)
 		}
 	}))
 
 	t.Run("only same currency allowed alice-side", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
-		paymentId, err := svc.Transfer(env.Ctx, bobEur, alice, money.NewNumericFromInt64(9000), "EUR")
+		paymentId, err := svc.Transfer(env.Ctx, bobEur, alice, money.NewNumericFromInt64(9000), "EUR", "")
 		if !errors.Is(err, service.ErrIncompatibleCurrency) {
 			t.Errorf("expected ErrIncompatibleCurrency, got paymentId=%v, err=%v", paymentId, err)
 		}
 	}))
 
 	t.Run("check account exists", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
-		paymentId, err := svc.Transfer(env.Ctx, "abc", bob, money.NewNumericFromInt64(9000), "USD")
+		paymentId, err := svc.Transfer(env.Ctx, "abc", bob, money.NewNumericFromInt64(9000), "USD", "")
 		if !errors.Is(err, service.ErrAccountDoesNotExist) {
 			t.Errorf("expected ErrAccountDoesNotExist, got paymentId=%v, err=%v", paymentId, err)
 		}
 	}))
 
 	t.Run("disallow transfer to the same account", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
-		paymentId, err := svc.Transfer(env.Ctx, bob, bob, money.NewNumericFromInt64(20), "USD")
+		paymentId, err := svc.Transfer(env.Ctx, bob, bob, money.NewNumericFromInt64(20), "USD", "")
 		if !errors.Is(err, service.ErrBadTransferTarget) {
 			t.Errorf("expected ErrBadTransferTarget, got paymentId=%v, err=%v", paymentId, err)
 		}
 	}))
+
+	t.Run("amount must be positive", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		paymentId, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(0), "USD", "")
+		if !errors.Is(err, service.ErrInvalidAmount) {
+			t.Errorf("expected ErrInvalidAmount, got paymentId=%v, err=%v", paymentId, err)
+		}
+	}))
+
+	t.Run("amount must fit currency minor units", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		paymentId, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromStringMust("0.001"), "USD", "")
+		if !errors.Is(err, service.ErrInvalidAmount) {
+			t.Errorf("expected ErrInvalidAmount, got paymentId=%v, err=%v", paymentId, err)
+		}
+	}))
+
+	t.Run("replay with the same idempotency key", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		first, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(10), "USD", "key-1")
+		if err != nil {
+			t.Error(err)
+		}
+		second, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(10), "USD", "key-1")
+		if err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, first, second)
+
+		page, err := svc.GetPayments(env.Ctx, service.PaymentsQuery{Account: bob})
+		if err != nil {
+			t.Error(err)
+		}
+		payments := page.Payments
+		assert.Equal(t, len(payments), 1)
+	}))
+
+	t.Run("idempotency key reused with different parameters", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		_, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(10), "USD", "key-2")
+		if err != nil {
+			t.Error(err)
+		}
+		paymentId, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(11), "USD", "key-2")
+		if !errors.Is(err, service.ErrIdempotencyKeyReused) {
+			t.Errorf("expected ErrIdempotencyKeyReused, got paymentId=%v, err=%v", paymentId, err)
+		}
+	}))
+
+	t.Run("expired idempotency key makes a new payment", isolation.WrapInTransaction(env.Tx, func(t *testing.T) {
+		svc := NewPaymentsService(env.Tx, WithIdempotencyRetention(0))
+		first, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(10), "USD", "key-3")
+		if err != nil {
+			t.Error(err)
+		}
+		second, err := svc.Transfer(env.Ctx, bob, alice, money.NewNumericFromInt64(10), "USD", "key-3")
+		if err != nil {
+			t.Error(err)
+		}
+		assert.NotEqual(t, first, second)
+	}))
 }
@@ -0,0 +1,353 @@
+package persistent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
+	"strings"
+	"time"
+)
+
+// webhookColumns are columns to select into webhookModel.
+const webhookColumns = `id, url, secret, coalesce(account_id, '') AS account_id, event_types, created_at`
+
+type webhookModel struct {
+	Id         int64     `sql:"id"`
+	URL        string    `sql:"url"`
+	Secret     string    `sql:"secret"`
+	Account    string    `pg:"account_id"`
+	EventTypes []string  `pg:"event_types,array"`
+	CreatedAt  time.Time `sql:"created_at"`
+}
+
+func (m webhookModel) toEntity() entity.Webhook {
+	w := entity.Webhook{
+		Id:        entity.WebhookID(m.Id),
+		URL:       m.URL,
+		Secret:    m.Secret,
+		Account:   entity.AccountID(m.Account),
+		CreatedAt: m.CreatedAt,
+	}
+	for _, typ := range m.EventTypes {
+		w.EventTypes = append(w.EventTypes, entity.EventType(typ))
+	}
+	return w
+}
+
+// deliveryColumns are columns to select into deliveryModel.
+const deliveryColumns = `
+	d.id, d.webhook_id, d.event_id, d.event_time, d.event_type, d.payload::text AS payload,
+	d.status, d.attempts, d.next_attempt_at, d.last_error, d.delivered_at, d.created_at`
+
+type deliveryModel struct {
+	Id            int64      `sql:"id"`
+	Webhook       int64      `pg:"webhook_id"`
+	EventId       int64      `sql:"event_id"`
+	EventTime     time.Time  `sql:"event_time"`
+	EventType     string     `sql:"event_type"`
+	Payload       string     `sql:"payload"`
+	Status        string     `sql:"status"`
+	Attempts      int        `sql:"attempts"`
+	NextAttemptAt time.Time  `sql:"next_attempt_at"`
+	LastError     string     `sql:"last_error"`
+	DeliveredAt   *time.Time `sql:"delivered_at"`
+	CreatedAt     time.Time  `sql:"created_at"`
+}
+
+func (m deliveryModel) toEntity() entity.WebhookDelivery {
+	d := entity.WebhookDelivery{
+		Id:      entity.DeliveryID(m.Id),
+		Webhook: entity.WebhookID(m.Webhook),
+		Event: entity.Event{
+			Id:      entity.EventID(m.EventId),
+			Time:    m.EventTime,
+			Type:    entity.EventType(m.EventType),
+			Payload: json.RawMessage(m.Payload),
+		},
+		Status:        entity.DeliveryStatus(m.Status),
+		Attempts:      m.Attempts,
+		NextAttemptAt: m.NextAttemptAt,
+		LastError:     m.LastError,
+		CreatedAt:     m.CreatedAt,
+	}
+	if m.DeliveredAt != nil {
+		d.DeliveredAt = *m.DeliveredAt
+	}
+	return d
+}
+
+func (s PaymentsService) CreateWebhook(ctx context.Context, webhook entity.Webhook) (entity.Webhook, error) {
+	if err := service.ValidateWebhook(webhook); err != nil {
+		return entity.Webhook{}, err
+	}
+	if webhook.Account != "" {
+		exists, err := s.accountExists(ctx, webhook.Account)
+		if err != nil {
+			return entity.Webhook{}, NewInternalErrorFromDBError(err)
+		}
+		if !exists {
+			return entity.Webhook{}, service.ErrAccountDoesNotExist
+		}
+	}
+
+	eventTypes := make([]string, 0, len(webhook.EventTypes))
+	for _, typ := range webhook.EventTypes {
+		eventTypes = append(eventTypes, string(typ))
+	}
+	var model webhookModel
+	const sql = `--webhook_insert
+		INSERT INTO webhook (url, secret, account_id, event_types, created_at)
+		VALUES (?, ?, nullif(?, ''), ?, ?)
+		RETURNING ` + webhookColumns
+	_, err := s.pg.QueryOneContext(ctx, &model, sql,
+		webhook.URL, webhook.Secret, string(webhook.Account), pg.Array(eventTypes), time.Now().UTC(),
+	)
+	if err != nil {
+		return entity.Webhook{}, NewInternalErrorFromDBError(err)
+	}
+	return model.toEntity(), nil
+}
+
+func (s PaymentsService) GetWebhooks(ctx context.Context) ([]entity.Webhook, error) {
+	webhooks, err := s.getWebhooks(ctx, s.pg)
+	if err != nil {
+		return nil, NewInternalErrorFromDBError(err)
+	}
+	return webhooks, nil
+}
+
+func (s PaymentsService) getWebhooks(ctx context.Context, db postgres.Database) ([]entity.Webhook, error) {
+	var rows []webhookModel
+	_, err := db.QueryContext(ctx, &rows, `SELECT `+webhookColumns+` FROM webhook ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	webhooks := make([]entity.Webhook, 0, len(rows))
+	for _, r := range rows {
+		webhooks = append(webhooks, r.toEntity())
+	}
+	return webhooks, nil
+}
+
+func (s PaymentsService) DeleteWebhook(ctx context.Context, id entity.WebhookID) error {
+	res, err := s.pg.ExecContext(ctx, `DELETE FROM webhook WHERE id = ?`, id)
+	if err != nil {
+		return NewInternalErrorFromDBError(err)
+	}
+	if res.RowsAffected() == 0 {
+		return service.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (s PaymentsService) EnqueueWebhookDeliveries(ctx context.Context, events []entity.Event) error {
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		// Webhooks are few, so it's simpler to match events against them here than in SQL.
+		webhooks, err := s.getWebhooks(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		const sql = `--webhook_delivery_insert
+			INSERT INTO webhook_delivery
+				(webhook_id, event_id, event_time, event_type, payload, status, next_attempt_at, created_at)
+			VALUES
+				(?, ?, ?, ?, ?::jsonb, ?, ?, ?)
+			ON CONFLICT (webhook_id, event_id) DO NOTHING
+		`
+		for _, event := range events {
+			for _, webhook := range webhooks {
+				if !service.WebhookMatches(webhook, event) {
+					continue
+				}
+				d := service.NewWebhookDelivery(webhook.Id, event, now)
+				_, err := tx.ExecContext(ctx, sql,
+					d.Webhook, d.Event.Id, d.Event.Time, string(d.Event.Type), string(d.Event.Payload),
+					string(d.Status), d.NextAttemptAt, d.CreatedAt,
+				)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return NewInternalErrorFromDBError(err)
+	}
+	return nil
+}
+
+// errNoDueDeliveries stops DeliverWebhooks when there is nothing left to deliver.
+var errNoDueDeliveries = errors.New("no due deliveries")
+
+func (s PaymentsService) DeliverWebhooks(ctx context.Context, now time.Time, limit int, deliver service.DeliverFunc) (int, error) {
+	for attempted := 0; attempted < limit; attempted++ {
+		err := s.deliverWebhook(ctx, now, deliver)
+		if errors.Is(err, errNoDueDeliveries) {
+			return attempted, nil
+		}
+		if err != nil {
+			return attempted, err
+		}
+	}
+	return limit, nil
+}
+
+// deliverWebhook makes an attempt to deliver a single due delivery. The delivery is claimed first by moving
+// its next attempt past a lease, so concurrent senders skip it, and the receiver is called outside of transactions,
+// so no locks are held meanwhile. The attempt is recorded unless the lease has been lost, and an interrupted
+// attempt is retried once the lease expires.
+func (s PaymentsService) deliverWebhook(ctx context.Context, now time.Time, deliver service.DeliverFunc) error {
+	delivery, webhook, lease, err := s.claimDelivery(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	deliverErr := deliver(ctx, webhook, delivery)
+	if ctx.Err() != nil {
+		// Delivery was interrupted rather than failed, it's going to be retried.
+		return ctx.Err()
+	}
+
+	d := service.RecordDeliveryAttempt(delivery, now, deliverErr)
+	var deliveredAt *time.Time
+	if !d.DeliveredAt.IsZero() {
+		deliveredAt = &d.DeliveredAt
+	}
+	const sql = `--webhook_delivery_record
+		UPDATE webhook_delivery
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, delivered_at = ?
+		WHERE id = ? AND status = 'pending' AND next_attempt_at = ?
+	`
+	_, err = s.pg.ExecContext(ctx, sql, string(d.Status), d.Attempts, d.NextAttemptAt, d.LastError, deliveredAt, d.Id, lease)
+	if err != nil {
+		return NewInternalErrorFromDBError(err)
+	}
+	return nil
+}
+
+// claimDelivery leases the most overdue delivery at now until the returned time, and returns it with its webhook.
+func (s PaymentsService) claimDelivery(ctx context.Context, now time.Time) (entity.WebhookDelivery, entity.Webhook, time.Time, error) {
+	var (
+		deliveryRow deliveryModel
+		webhookRow  webhookModel
+	)
+	// Postgres keeps microseconds, the lease is compared to the stored value later.
+	lease := now.Add(service.DeliveryLease).Truncate(time.Microsecond)
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		const sql = `--webhook_delivery_due
+			SELECT ` + deliveryColumns + `
+			FROM webhook_delivery d
+			WHERE d.status = 'pending' AND d.next_attempt_at <= ?
+			ORDER BY d.next_attempt_at ASC, d.id ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		`
+		_, err := tx.QueryOneContext(ctx, &deliveryRow, sql, now)
+		if errors.Is(err, pg.ErrNoRows) {
+			return errNoDueDeliveries
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.QueryOneContext(ctx, &webhookRow, `SELECT `+webhookColumns+` FROM webhook WHERE id = ?`, deliveryRow.Webhook)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE webhook_delivery SET next_attempt_at = ? WHERE id = ?`, lease, deliveryRow.Id)
+		return err
+	})
+	switch {
+	case errors.Is(err, errNoDueDeliveries):
+		return entity.WebhookDelivery{}, entity.Webhook{}, time.Time{}, err
+	case err != nil:
+		return entity.WebhookDelivery{}, entity.Webhook{}, time.Time{}, NewInternalErrorFromDBError(err)
+	}
+	return deliveryRow.toEntity(), webhookRow.toEntity(), lease, nil
+}
+
+func (s PaymentsService) updateDelivery(ctx context.Context, tx postgres.Database, d entity.WebhookDelivery) error {
+	var deliveredAt *time.Time
+	if !d.DeliveredAt.IsZero() {
+		deliveredAt = &d.DeliveredAt
+	}
+	const sql = `--webhook_delivery_update
+		UPDATE webhook_delivery
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, delivered_at = ?
+		WHERE id = ?
+	`
+	_, err := tx.ExecContext(ctx, sql, string(d.Status), d.Attempts, d.NextAttemptAt, d.LastError, deliveredAt, d.Id)
+	return err
+}
+
+func (s PaymentsService) GetWebhookDeliveries(ctx context.Context, query service.DeliveriesQuery) ([]entity.WebhookDelivery, error) {
+	limit, err := query.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []deliveryModel
+	// Only filters which are set make it to the query, since go-pg renders zero values of parameters as NULL.
+	conditions := []string{"true"}
+	if query.Webhook != 0 {
+		conditions = append(conditions, `d.webhook_id = ?webhook`)
+	}
+	if query.Status != "" {
+		conditions = append(conditions, `d.status = ?status`)
+	}
+	sql := `--webhook_delivery_list
+		SELECT ` + deliveryColumns + `
+		FROM webhook_delivery d
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY d.id DESC
+		LIMIT ?limit
+	`
+	_, err = s.pg.QueryContext(ctx, &rows, sql, struct {
+		Webhook int64  `sql:"webhook"`
+		Status  string `sql:"status"`
+		Limit   int    `sql:"limit"`
+	}{
+		Webhook: int64(query.Webhook),
+		Status:  string(query.Status),
+		Limit:   limit,
+	})
+	if err != nil {
+		return nil, NewInternalErrorFromDBError(err)
+	}
+	deliveries := make([]entity.WebhookDelivery, 0, len(rows))
+	for _, r := range rows {
+		deliveries = append(deliveries, r.toEntity())
+	}
+	return deliveries, nil
+}
+
+func (s PaymentsService) ReplayWebhookDelivery(ctx context.Context, id entity.DeliveryID, now time.Time) (entity.WebhookDelivery, error) {
+	var delivery entity.WebhookDelivery
+	err := postgres.NestedRunInTransaction(ctx, s.pg, func(tx postgres.Database) error {
+		var model deliveryModel
+		_, err := tx.QueryOneContext(ctx, &model,
+			`SELECT `+deliveryColumns+` FROM webhook_delivery d WHERE d.id = ? FOR UPDATE`, id,
+		)
+		if errors.Is(err, pg.ErrNoRows) {
+			return service.ErrDeliveryNotFound
+		}
+		if err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		delivery = service.ReplayDelivery(model.toEntity(), now)
+		if err := s.updateDelivery(ctx, tx, delivery); err != nil {
+			return NewInternalErrorFromDBError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
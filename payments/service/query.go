@@ -0,0 +1,156 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPaymentsLimit is a page size used when PaymentsQuery.Limit is not set.
+	DefaultPaymentsLimit = 100
+
+	// MaxPaymentsLimit is the largest page size allowed, larger limits are capped.
+	MaxPaymentsLimit = 1000
+)
+
+// PaymentDirection filters payments by whether an account sent or received money.
+type PaymentDirection string
+
+const (
+	DirectionAny      PaymentDirection = ""
+	DirectionIncoming PaymentDirection = "incoming"
+	DirectionOutgoing PaymentDirection = "outgoing"
+)
+
+// PaymentsQuery selects payments of an account for GetPayments. Zero values of optional fields mean "no filter".
+type PaymentsQuery struct {
+	Account entity.AccountID
+
+	// Cursor is PaymentsPage.NextCursor of the previous page, empty for the first page
+	Cursor string
+
+	// Limit is max number of payments on a page
+	Limit int
+
+	// Since is an inclusive lower bound of payment time
+	Since time.Time
+
+	// Until is an exclusive upper bound of payment time
+	Until time.Time
+
+	Direction PaymentDirection
+
+	// Counterparty is the other side of the payment
+	Counterparty entity.AccountID
+
+	// MinAmount and MaxAmount are inclusive bounds of amount in the account's currency
+	MinAmount *money.Numeric
+	MaxAmount *money.Numeric
+}
+
+// Validate checks the query is well-formed and returns the decoded cursor and effective page size.
+func (q PaymentsQuery) Validate() (PaymentsCursor, int, error) {
+	if q.Account == "" {
+		return PaymentsCursor{}, 0, ErrBadAccountID
+	}
+	switch q.Direction {
+	case DirectionAny, DirectionIncoming, DirectionOutgoing:
+	default:
+		return PaymentsCursor{}, 0, ErrBadQuery
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return PaymentsCursor{}, 0, ErrBadQuery
+	}
+	if q.MinAmount != nil && q.MaxAmount != nil && q.MaxAmount.LessThan(*q.MinAmount) {
+		return PaymentsCursor{}, 0, ErrBadQuery
+	}
+
+	limit := q.Limit
+	switch {
+	case limit < 0:
+		return PaymentsCursor{}, 0, ErrBadQuery
+	case limit == 0:
+		limit = DefaultPaymentsLimit
+	case limit > MaxPaymentsLimit:
+		limit = MaxPaymentsLimit
+	}
+
+	var cursor PaymentsCursor
+	if q.Cursor != "" {
+		var err error
+		cursor, err = DecodePaymentsCursor(q.Cursor)
+		if err != nil {
+			return PaymentsCursor{}, 0, ErrBadCursor
+		}
+	}
+	return cursor, limit, nil
+}
+
+// PaymentsPage is a single page of GetPayments result.
+type PaymentsPage struct {
+	Payments []entity.Payment
+
+	// NextCursor points to the next page, empty if this page is the last one
+	NextCursor string
+}
+
+// PaymentsCursor is a position in a list of payments ordered by time and id descending.
+// Payments strictly after the position are returned on the next page.
+type PaymentsCursor struct {
+	Time time.Time
+	Id   entity.PaymentID
+}
+
+// IsZero reports whether the cursor points to the very beginning of the list.
+func (c PaymentsCursor) IsZero() bool {
+	return c.Id == 0
+}
+
+// Precedes reports whether payment p is positioned after the cursor, i.e. belongs to pages following it.
+func (c PaymentsCursor) Precedes(p entity.Payment) bool {
+	if c.IsZero() {
+		return true
+	}
+	if !p.Value.Time.Equal(c.Time) {
+		return p.Value.Time.Before(c.Time)
+	}
+	return p.Id < c.Id
+}
+
+// EncodePaymentsCursor returns an opaque cursor pointing right after the given payment.
+func EncodePaymentsCursor(p entity.Payment) string {
+	raw := fmt.Sprintf("%d:%d", p.Value.Time.UnixNano(), p.Id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodePaymentsCursor parses a cursor produced by EncodePaymentsCursor.
+func DecodePaymentsCursor(cursor string) (PaymentsCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return PaymentsCursor{}, err
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 2 {
+		return PaymentsCursor{}, fmt.Errorf("malformed cursor %q", cursor)
+	}
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return PaymentsCursor{}, err
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return PaymentsCursor{}, err
+	}
+	if id <= 0 {
+		return PaymentsCursor{}, fmt.Errorf("malformed cursor %q", cursor)
+	}
+	return PaymentsCursor{
+		Time: time.Unix(0, ts).UTC(),
+		Id:   entity.PaymentID(id),
+	}, nil
+}
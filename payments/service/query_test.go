@@ -0,0 +1,116 @@
+package service
+
+import (
+	"errors"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"testing"
+	"time"
+)
+
+func TestPaymentsCursor(t *testing.T) {
+	p := entity.Payment{
+		Id: 42,
+		Value: entity.PaymentValue{
+			Time: time.Date(2020, 11, 2, 10, 18, 14, 249221000, time.UTC),
+		},
+	}
+	cursor, err := DecodePaymentsCursor(EncodePaymentsCursor(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.Id != p.Id || !cursor.Time.Equal(p.Value.Time) {
+		t.Errorf("TestPaymentsCursor got %+v, want position of %+v", cursor, p)
+	}
+
+	tests := []struct {
+		in  entity.Payment
+		out bool
+	}{
+		{
+			in:  entity.Payment{Id: 41, Value: entity.PaymentValue{Time: p.Value.Time}},
+			out: true,
+		},
+		{
+			in:  entity.Payment{Id: 42, Value: entity.PaymentValue{Time: p.Value.Time}},
+			out: false,
+		},
+		{
+			in:  entity.Payment{Id: 50, Value: entity.PaymentValue{Time: p.Value.Time.Add(-time.Second)}},
+			out: true,
+		},
+		{
+			in:  entity.Payment{Id: 10, Value: entity.PaymentValue{Time: p.Value.Time.Add(time.Second)}},
+			out: false,
+		},
+	}
+	for _, test := range tests {
+		if res := cursor.Precedes(test.in); res != test.out {
+			t.Errorf("TestPaymentsCursor Precedes(%+v) got %t, want %t", test.in, res, test.out)
+		}
+	}
+
+	for _, malformed := range []string{"!!!", "MTIz", "YWJjOjE", "MTIzOjA"} {
+		if _, err := DecodePaymentsCursor(malformed); err == nil {
+			t.Errorf("TestPaymentsCursor expected error for %q", malformed)
+		}
+	}
+}
+
+func TestPaymentsQuery_Validate(t *testing.T) {
+	now := time.Now()
+	ten := money.NewNumericFromInt64(10)
+	five := money.NewNumericFromInt64(5)
+
+	tests := []struct {
+		in    PaymentsQuery
+		limit int
+		err   error
+	}{
+		{
+			in:    PaymentsQuery{Account: "bob"},
+			limit: DefaultPaymentsLimit,
+		},
+		{
+			in:    PaymentsQuery{Account: "bob", Limit: 10, Direction: DirectionIncoming},
+			limit: 10,
+		},
+		{
+			in:    PaymentsQuery{Account: "bob", Limit: MaxPaymentsLimit + 1},
+			limit: MaxPaymentsLimit,
+		},
+		{
+			in:  PaymentsQuery{},
+			err: ErrBadAccountID,
+		},
+		{
+			in:  PaymentsQuery{Account: "bob", Limit: -1},
+			err: ErrBadQuery,
+		},
+		{
+			in:  PaymentsQuery{Account: "bob", Direction: "sideways"},
+			err: ErrBadQuery,
+		},
+		{
+			in:  PaymentsQuery{Account: "bob", Since: now, Until: now},
+			err: ErrBadQuery,
+		},
+		{
+			in:  PaymentsQuery{Account: "bob", MinAmount: &ten, MaxAmount: &five},
+			err: ErrBadQuery,
+		},
+		{
+			in:  PaymentsQuery{Account: "bob", Cursor: "garbage"},
+			err: ErrBadCursor,
+		},
+	}
+	for _, test := range tests {
+		_, limit, err := test.in.Validate()
+		if !errors.Is(err, test.err) {
+			t.Errorf("TestPaymentsQuery_Validate %+v got err=%v, want %v", test.in, err, test.err)
+		}
+		if err == nil && limit != test.limit {
+			t.Errorf("TestPaymentsQuery_Validate %+v got limit %d, want %d", test.in, limit, test.limit)
+		}
+	}
+}
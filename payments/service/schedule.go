@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"time"
+)
+
+// NewScheduledTransfer checks parameters of st which don't depend on the state of accounts,
+// and returns it active and due at its first occurrence. Zero StartAt means now, once is the default recurrence.
+// Times are truncated to seconds, which is all the precision schedules need.
+func NewScheduledTransfer(st entity.ScheduledTransfer, now time.Time) (entity.ScheduledTransfer, error) {
+	if st.Recurrence == "" {
+		st.Recurrence = entity.RecurrenceOnce
+	}
+	if !st.Recurrence.IsValid() {
+		return entity.ScheduledTransfer{}, ErrBadSchedule
+	}
+
+	if st.Recurrence == entity.RecurrenceMonthly {
+		if st.DayOfMonth < 1 || st.DayOfMonth > 31 {
+			return entity.ScheduledTransfer{}, ErrBadSchedule
+		}
+	} else if st.DayOfMonth != 0 {
+		return entity.ScheduledTransfer{}, ErrBadSchedule
+	}
+
+	if st.StartAt.IsZero() {
+		st.StartAt = now
+	} else if st.StartAt.Before(now) {
+		return entity.ScheduledTransfer{}, ErrBadSchedule
+	}
+	st.StartAt = st.StartAt.UTC().Truncate(time.Second)
+
+	if !st.EndAt.IsZero() {
+		if st.Recurrence == entity.RecurrenceOnce {
+			return entity.ScheduledTransfer{}, ErrBadSchedule
+		}
+		st.EndAt = st.EndAt.UTC().Truncate(time.Second)
+	}
+
+	first := firstOccurrence(st)
+	if !st.EndAt.IsZero() && first.After(st.EndAt) {
+		return entity.ScheduledTransfer{}, ErrBadSchedule
+	}
+
+	st.Id = 0
+	st.Status = entity.ScheduleActive
+	st.NextRunAt = first
+	st.CreatedAt = now
+	return st, nil
+}
+
+// firstOccurrence returns the earliest occurrence of st at or after st.StartAt.
+func firstOccurrence(st entity.ScheduledTransfer) time.Time {
+	if st.Recurrence != entity.RecurrenceMonthly {
+		return st.StartAt
+	}
+	first := dayOfMonth(st.StartAt.Year(), st.StartAt.Month(), st.DayOfMonth, st.StartAt)
+	if first.Before(st.StartAt) {
+		first = dayOfMonth(st.StartAt.Year(), st.StartAt.Month()+1, st.DayOfMonth, st.StartAt)
+	}
+	return first
+}
+
+// NextOccurrence returns the occurrence of st following the given one, or zero time if there is none.
+func NextOccurrence(st entity.ScheduledTransfer, occurrence time.Time) time.Time {
+	var next time.Time
+	switch st.Recurrence {
+	case entity.RecurrenceDaily:
+		next = occurrence.AddDate(0, 0, 1)
+	case entity.RecurrenceWeekly:
+		next = occurrence.AddDate(0, 0, 7)
+	case entity.RecurrenceMonthly:
+		next = dayOfMonth(occurrence.Year(), occurrence.Month()+1, st.DayOfMonth, st.StartAt)
+	default:
+		return time.Time{}
+	}
+	if !st.EndAt.IsZero() && next.After(st.EndAt) {
+		return time.Time{}
+	}
+	return next
+}
+
+// dayOfMonth returns the given day of a month at the time of day of clock, or the last day of shorter months.
+// Months past December roll over to the next year.
+func dayOfMonth(year int, month time.Month, day int, clock time.Time) time.Time {
+	if last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day(); day > last {
+		day = last
+	}
+	return time.Date(year, month, day, clock.Hour(), clock.Minute(), clock.Second(), 0, time.UTC)
+}
+
+// RecordRun returns the run of st due at st.NextRunAt made at now, which made payment unless it failed with err,
+// and st advanced to its next occurrence, or completed if there is none.
+// Failed runs aren't retried, the next occurrence is made as usual.
+func RecordRun(st entity.ScheduledTransfer, now time.Time, payment entity.PaymentID, err error) (entity.ScheduledTransfer, entity.ScheduledRun) {
+	run := entity.ScheduledRun{
+		Schedule:    st.Id,
+		ScheduledAt: st.NextRunAt,
+		RanAt:       now,
+	}
+	if err != nil {
+		run.Status = entity.RunFailed
+		run.Error = err.Error()
+	} else {
+		run.Status = entity.RunSucceeded
+		run.Payment = payment
+	}
+
+	st.NextRunAt = NextOccurrence(st, st.NextRunAt)
+	if st.NextRunAt.IsZero() {
+		st.Status = entity.ScheduleCompleted
+	}
+	return st, run
+}
+
+// IsRunFailure tells whether err returned by a transfer fails the run of a scheduled transfer.
+// Otherwise the transfer couldn't be made for reasons unrelated to it, and it's retried later.
+func IsRunFailure(err error) bool {
+	var internal ErrInternal
+	return !errors.As(err, &internal) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// CancelSchedule returns st cancelled, failing with ErrScheduleNotActive unless it's active.
+func CancelSchedule(st entity.ScheduledTransfer) (entity.ScheduledTransfer, error) {
+	if st.Status != entity.ScheduleActive {
+		return entity.ScheduledTransfer{}, ErrScheduleNotActive
+	}
+	st.Status = entity.ScheduleCancelled
+	st.NextRunAt = time.Time{}
+	return st, nil
+}
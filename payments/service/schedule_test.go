@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewScheduledTransfer(t *testing.T) {
+	now := time.Date(2021, 1, 20, 8, 30, 15, 500, time.UTC)
+
+	st, err := NewScheduledTransfer(entity.ScheduledTransfer{}, now)
+	if assert.NoError(t, err) {
+		assert.Equal(t, st.Recurrence, entity.RecurrenceOnce)
+		assert.Equal(t, st.Status, entity.ScheduleActive)
+		assert.Equal(t, st.NextRunAt, time.Date(2021, 1, 20, 8, 30, 15, 0, time.UTC), "due at once")
+	}
+
+	// The 15th of January has passed, so it's made in February first.
+	st, err = NewScheduledTransfer(entity.ScheduledTransfer{
+		Recurrence: entity.RecurrenceMonthly,
+		DayOfMonth: 15,
+		StartAt:    now.Add(time.Hour),
+	}, now)
+	if assert.NoError(t, err) {
+		assert.Equal(t, st.NextRunAt, time.Date(2021, 2, 15, 9, 30, 15, 0, time.UTC))
+	}
+
+	_, err = NewScheduledTransfer(entity.ScheduledTransfer{
+		Recurrence: entity.RecurrenceMonthly,
+		DayOfMonth: 15,
+		StartAt:    now.Add(time.Hour),
+		EndAt:      now.AddDate(0, 0, 7),
+	}, now)
+	if !errors.Is(err, ErrBadSchedule) {
+		t.Errorf("expected ErrBadSchedule for a schedule ending before the first occurrence, got err=%v", err)
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	start := time.Date(2021, 1, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		st   entity.ScheduledTransfer
+		want []time.Time
+	}{
+		{
+			entity.ScheduledTransfer{Recurrence: entity.RecurrenceOnce, StartAt: start},
+			nil,
+		},
+		{
+			entity.ScheduledTransfer{Recurrence: entity.RecurrenceDaily, StartAt: start, EndAt: start.AddDate(0, 0, 2)},
+			[]time.Time{start.AddDate(0, 0, 1), start.AddDate(0, 0, 2)},
+		},
+		{
+			entity.ScheduledTransfer{Recurrence: entity.RecurrenceWeekly, StartAt: start, EndAt: start.AddDate(0, 0, 20)},
+			[]time.Time{start.AddDate(0, 0, 7), start.AddDate(0, 0, 14)},
+		},
+		{
+			// Shorter months get the transfer on their last day, the rest on the 31st.
+			entity.ScheduledTransfer{Recurrence: entity.RecurrenceMonthly, DayOfMonth: 31, StartAt: start, EndAt: start.AddDate(0, 3, 0)},
+			[]time.Time{
+				time.Date(2021, 2, 28, 12, 0, 0, 0, time.UTC),
+				time.Date(2021, 3, 31, 12, 0, 0, 0, time.UTC),
+				time.Date(2021, 4, 30, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			entity.ScheduledTransfer{Recurrence: entity.RecurrenceMonthly, DayOfMonth: 31, StartAt: start.AddDate(0, 11, 0), EndAt: start.AddDate(1, 0, 1)},
+			[]time.Time{time.Date(2022, 1, 31, 12, 0, 0, 0, time.UTC)},
+		},
+	}
+	for _, test := range tests {
+		var got []time.Time
+		for next := NextOccurrence(test.st, test.st.StartAt); !next.IsZero() && len(got) <= len(test.want); next = NextOccurrence(test.st, next) {
+			got = append(got, next)
+		}
+		assert.Equal(t, got, test.want, "%+v", test.st)
+	}
+}
+
+func TestRecordRun(t *testing.T) {
+	start := time.Date(2021, 1, 31, 12, 0, 0, 0, time.UTC)
+	st := entity.ScheduledTransfer{
+		Id:         1,
+		Recurrence: entity.RecurrenceDaily,
+		StartAt:    start,
+		EndAt:      start.AddDate(0, 0, 1),
+		Status:     entity.ScheduleActive,
+		NextRunAt:  start,
+	}
+
+	st, run := RecordRun(st, start.Add(time.Minute), 7, nil)
+	assert.Equal(t, run, entity.ScheduledRun{
+		Schedule:    1,
+		ScheduledAt: start,
+		RanAt:       start.Add(time.Minute),
+		Status:      entity.RunSucceeded,
+		Payment:     7,
+	})
+	assert.Equal(t, st.Status, entity.ScheduleActive)
+	assert.Equal(t, st.NextRunAt, start.AddDate(0, 0, 1))
+
+	st, run = RecordRun(st, start.AddDate(0, 0, 1), 0, ErrInsufficientFunds)
+	assert.Equal(t, run.Status, entity.RunFailed)
+	assert.Equal(t, run.Error, ErrInsufficientFunds.Error())
+	assert.Equal(t, st.Status, entity.ScheduleCompleted)
+	assert.True(t, st.NextRunAt.IsZero())
+
+	_, err := CancelSchedule(st)
+	if !errors.Is(err, ErrScheduleNotActive) {
+		t.Errorf("expected ErrScheduleNotActive, got err=%v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/lightsgoout/fintech-go/payments/entity"
 	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
 )
 
 // PaymentsService is an interface containing all possible business operations for payments.
@@ -12,11 +13,137 @@ type PaymentsService interface {
 	CreateAccount(ctx context.Context, id entity.AccountID, balance money.Numeric, cur money.Currency) error
 
 	// Transfer sends money from one entity.Account to another, atomically.
-	Transfer(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency) (entity.PaymentID, error)
+	// When idempotencyKey is not empty, a replay with the same key and parameters returns the original
+	// entity.PaymentID without moving money again, and a replay with different parameters fails
+	// with ErrIdempotencyKeyReused.
+	Transfer(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency, idempotencyKey entity.IdempotencyKey) (entity.PaymentID, error)
 
-	// GetPayments returns a list of transactions for a given AccountID in descending order (recent payments first).
-	GetPayments(ctx context.Context, accountId entity.AccountID) ([]entity.Payment, error)
+	// TransferBatch makes transfers of all the legs in order, atomically: either all of them succeed or none.
+	// Returns entity.PaymentID of every leg, failures are reported as ErrBatchLeg wrapping the cause.
+	TransferBatch(ctx context.Context, legs []TransferLeg) ([]entity.PaymentID, error)
+
+	// QuoteExchange locks an exchange rate between two currencies for a short period of time.
+	QuoteExchange(ctx context.Context, from, to money.Currency) (entity.Quote, error)
+
+	// TransferWithQuote sends money from one entity.Account to another one in a different currency, atomically.
+	// amount is debited in the currency of the from account and credited to the to account converted
+	// by the rate of the given entity.Quote. Idempotency keys behave the same as in Transfer.
+	TransferWithQuote(ctx context.Context, from, to entity.AccountID, amount money.Numeric, quoteId entity.QuoteID, idempotencyKey entity.IdempotencyKey) (entity.PaymentID, error)
+
+	// Authorize reserves money on the from account for a later transfer to the to account, see Capture and Void.
+	// Reserved money can't be spent by other operations, unless the entity.Hold expires.
+	// Limits of the from account apply to the hold, and once again to its capture.
+	Authorize(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency) (entity.Hold, error)
+
+	// Capture transfers reserved money of an active entity.Hold, atomically.
+	// amount is the part of held money to transfer, or all of it when nil. The rest is released.
+	Capture(ctx context.Context, id entity.HoldID, amount *money.Numeric) (entity.PaymentID, error)
+
+	// Void releases reserved money of an active entity.Hold without transferring it.
+	Void(ctx context.Context, id entity.HoldID) error
+
+	// Refund transfers money of a payment back from its receiver to its sender, atomically.
+	// amount is the part of the payment to refund, or all of it not refunded yet when nil.
+	// A payment can be refunded several times, as long as refunds add up to no more than the payment amount.
+	// Refunds and cross-currency payments can't be refunded.
+	Refund(ctx context.Context, id entity.PaymentID, amount *money.Numeric) (entity.PaymentID, error)
+
+	// SetAccountStatus changes status of an account, recording the reason of the change, and returns the updated account.
+	// Frozen accounts can't send money and, unless only debits are frozen, receive it.
+	// Only accounts with zero balance can be closed, and closed accounts stay closed forever.
+	SetAccountStatus(ctx context.Context, id entity.AccountID, status entity.AccountStatus, reason string) (entity.Account, error)
+
+	// SetCreditLimit allows the balance of an account to go down to -limit, or to any negative value if unbounded.
+	// The limit can't be set below money the account already owes.
+	SetCreditLimit(ctx context.Context, id entity.AccountID, limit money.Numeric, unbounded bool) (entity.Account, error)
+
+	// SetAccountLimits replaces limits of outgoing payments of an account.
+	// Limits are enforced for every payment the account sends, including hold captures and refunds.
+	SetAccountLimits(ctx context.Context, id entity.AccountID, limits entity.AccountLimits) error
+
+	// GetAccountLimits returns limits of outgoing payments of an account.
+	GetAccountLimits(ctx context.Context, id entity.AccountID) (entity.AccountLimits, error)
+
+	// GetBalance returns the balance of an account after all payments made at or before the given moment.
+	GetBalance(ctx context.Context, id entity.AccountID, at time.Time) (entity.Balance, error)
+
+	// GetStatement returns balance changes of an account made since (inclusive) until (exclusive),
+	// with opening, running and closing balances. Zero until means now.
+	GetStatement(ctx context.Context, id entity.AccountID, since, until time.Time) (entity.Statement, error)
+
+	// GetPayments returns a page of transactions of an account matching the query,
+	// in descending order (recent payments first).
+	GetPayments(ctx context.Context, query PaymentsQuery) (PaymentsPage, error)
 
 	// GetAccounts returns a list of possible AccountID's to trade with (matching the given Currency), ascending order.
 	GetAccounts(ctx context.Context, cur money.Currency) ([]entity.AccountID, error)
+
+	// RelayEvents passes up to limit oldest unpublished events to publish, in order they were recorded,
+	// and marks them published if publish succeeds. Otherwise they're passed again next time,
+	// so events are delivered at least once. Returns the number of published events.
+	RelayEvents(ctx context.Context, limit int, publish PublishFunc) (int, error)
+
+	// CreateWebhook subscribes webhook.URL to events matching the webhook, see entity.Webhook.
+	// Events recorded since then are delivered to it once they're passed to EnqueueWebhookDeliveries.
+	CreateWebhook(ctx context.Context, webhook entity.Webhook) (entity.Webhook, error)
+
+	// GetWebhooks returns all webhooks in order they were created.
+	GetWebhooks(ctx context.Context) ([]entity.Webhook, error)
+
+	// DeleteWebhook unsubscribes a webhook, forgetting its deliveries.
+	DeleteWebhook(ctx context.Context, id entity.WebhookID) error
+
+	// EnqueueWebhookDeliveries schedules delivery of events to every webhook they match.
+	// An event passed again isn't delivered to the same webhook twice.
+	EnqueueWebhookDeliveries(ctx context.Context, events []entity.Event) error
+
+	// DeliverWebhooks passes up to limit pending deliveries due at now to deliver, one by one,
+	// and records the outcome of every attempt, see RecordDeliveryAttempt.
+	// Returns the number of attempts made.
+	DeliverWebhooks(ctx context.Context, now time.Time, limit int, deliver DeliverFunc) (int, error)
+
+	// GetWebhookDeliveries returns deliveries matching the query, recent ones first.
+	GetWebhookDeliveries(ctx context.Context, query DeliveriesQuery) ([]entity.WebhookDelivery, error)
+
+	// ReplayWebhookDelivery makes a delivery pending again with a fresh set of attempts, whatever its status is.
+	ReplayWebhookDelivery(ctx context.Context, id entity.DeliveryID, now time.Time) (entity.WebhookDelivery, error)
+
+	// ScheduleTransfer creates a transfer made later, once or repeatedly, see entity.ScheduledTransfer.
+	// Accounts must exist, but whether they can make the transfer is only checked by its runs.
+	ScheduleTransfer(ctx context.Context, st entity.ScheduledTransfer) (entity.ScheduledTransfer, error)
+
+	// GetScheduledTransfers returns transfers scheduled from an account, or from any account if it's empty,
+	// in order they were created. Fails with ErrAccountDoesNotExist for unknown accounts.
+	GetScheduledTransfers(ctx context.Context, from entity.AccountID) ([]entity.ScheduledTransfer, error)
+
+	// CancelScheduledTransfer stops an active scheduled transfer from being made again.
+	CancelScheduledTransfer(ctx context.Context, id entity.ScheduledTransferID) (entity.ScheduledTransfer, error)
+
+	// GetScheduledRuns returns runs of a scheduled transfer in order they were made.
+	GetScheduledRuns(ctx context.Context, id entity.ScheduledTransferID) ([]entity.ScheduledRun, error)
+
+	// RunScheduledTransfers makes up to limit scheduled transfers due at now, one by one, the same way Transfer does,
+	// and records the outcome of every run, see RecordRun. A transfer is made once per occurrence,
+	// however many instances run it concurrently. Returns the number of runs made.
+	// Occurrences missed while nothing called it, e.g. during downtime, are all made back to back once it's called,
+	// in order they were due, and none of them is skipped.
+	// A scheduled transfer failing with ErrInternal isn't recorded and is skipped for the rest of the call,
+	// the first such error is returned once the others are run.
+	RunScheduledTransfers(ctx context.Context, now time.Time, limit int) (int, error)
+
+	// AppendAuditEntry appends an entry to the audit log, its Id is assigned by the log.
+	AppendAuditEntry(ctx context.Context, entry entity.AuditEntry) error
+
+	// GetAuditLog returns entries of the audit log matching the query, recent ones first.
+	GetAuditLog(ctx context.Context, query AuditQuery) ([]entity.AuditEntry, error)
 }
+
+// Transactional is implemented by services which can make several calls atomically.
+type Transactional interface {
+	// InTransaction calls fn with a service making its calls within a single transaction,
+	// which is committed unless fn fails.
+	InTransaction(ctx context.Context, fn func(svc PaymentsService) error) error
+}
+
+// PublishFunc delivers events to downstream systems.
+type PublishFunc func(ctx context.Context, events []entity.Event) error
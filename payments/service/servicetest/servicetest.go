@@ -0,0 +1,2101 @@
+// Package servicetest contains a conformance test suite every service.PaymentsService implementation must pass.
+package servicetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/fee"
+	"github.com/lightsgoout/fintech-go/pkg/fx"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// Config describes settings of a service under test. The suite always sets every field.
+type Config struct {
+	// Rates is a source of exchange rates, nil disables cross-currency transfers
+	Rates fx.RateProvider
+
+	QuoteTTL time.Duration
+
+	IdempotencyRetention time.Duration
+
+	HoldTTL time.Duration
+
+	Fees service.Fees
+}
+
+// NewService returns a service having no accounts and payments, configured according to cfg.
+// Changes made to the service must not be visible to services returned for other tests.
+type NewService func(t *testing.T, cfg Config) service.PaymentsService
+
+func defaultConfig() Config {
+	return Config{
+		QuoteTTL:             time.Minute,
+		IdempotencyRetention: time.Hour,
+		HoldTTL:              time.Hour,
+	}
+}
+
+// Run runs the conformance suite against services created by newService.
+func Run(t *testing.T, newService NewService) {
+	t.Run("CreateAccount", func(t *testing.T) { testCreateAccount(t, newService) })
+	t.Run("Transfer", func(t *testing.T) { testTransfer(t, newService) })
+	t.Run("TransferWithQuote", func(t *testing.T) { testTransferWithQuote(t, newService) })
+	t.Run("Holds", func(t *testing.T) { testHolds(t, newService) })
+	t.Run("Refund", func(t *testing.T) { testRefund(t, newService) })
+	t.Run("TransferBatch", func(t *testing.T) { testTransferBatch(t, newService) })
+	t.Run("AccountStatus", func(t *testing.T) { testAccountStatus(t, newService) })
+	t.Run("Fees", func(t *testing.T) { testFees(t, newService) })
+	t.Run("Limits", func(t *testing.T) { testLimits(t, newService) })
+	t.Run("CreditLimit", func(t *testing.T) { testCreditLimit(t, newService) })
+	t.Run("Statement", func(t *testing.T) { testStatement(t, newService) })
+	t.Run("Events", func(t *testing.T) { testEvents(t, newService) })
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, newService) })
+	t.Run("ScheduledTransfers", func(t *testing.T) { testScheduledTransfers(t, newService) })
+	t.Run("AuditLog", func(t *testing.T) { testAuditLog(t, newService) })
+	t.Run("GetPayments", func(t *testing.T) { testGetPayments(t, newService) })
+	t.Run("GetAccounts", func(t *testing.T) { testGetAccounts(t, newService) })
+}
+
+const (
+	bob   = entity.AccountID("bob")
+	alice = entity.AccountID("alice")
+	clyde = entity.AccountID("clyde")
+)
+
+var ctx = context.Background()
+
+func createAccount(t *testing.T, svc service.PaymentsService, id entity.AccountID, balance int64, cur money.Currency) {
+	err := svc.CreateAccount(ctx, id, money.NewNumericFromInt64(balance), cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func paymentIds(payments []entity.Payment) []entity.PaymentID {
+	var ids []entity.PaymentID
+	for _, p := range payments {
+		ids = append(ids, p.Id)
+	}
+	return ids
+}
+
+func testCreateAccount(t *testing.T, newService NewService) {
+	t.Run("create account OK", func(t *testing.T) {
+		svc := newService(t, defaultConfig())
+		err := svc.CreateAccount(ctx, bob, money.NewNumericFromStringMust("100.50"), "USD")
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("duplicate account disallowed", func(t *testing.T) {
+		svc := newService(t, defaultConfig())
+		createAccount(t, svc, bob, 60, "USD")
+		err := svc.CreateAccount(ctx, bob, money.NewNumericFromInt64(60), "EUR")
+		if !errors.Is(err, service.ErrAccountAlreadyExists) {
+			t.Errorf("expected ErrAccountAlreadyExists, got err=%v", err)
+		}
+	})
+
+	for _, testcase := range []struct {
+		name    string
+		id      entity.AccountID
+		balance money.Numeric
+		cur     money.Currency
+		want    error
+	}{
+		{"disallow balance < 0", bob, money.NewNumericFromInt64(-1), "USD", service.ErrInsufficientFunds},
+		{"unknown currency", bob, money.NewNumericFromInt64(1), "UAH", service.ErrIncompatibleCurrency},
+		{"balance must fit currency minor units", bob, money.NewNumericFromStringMust("0.001"), "USD", service.ErrInvalidAmount},
+		{"empty id", "", money.NewNumericFromInt64(1), "USD", service.ErrBadAccountID},
+	} {
+		testcase := testcase
+		t.Run(testcase.name, func(t *testing.T) {
+			svc := newService(t, defaultConfig())
+			err := svc.CreateAccount(ctx, testcase.id, testcase.balance, testcase.cur)
+			if !errors.Is(err, testcase.want) {
+				t.Errorf("expected %v, got err=%v", testcase.want, err)
+			}
+		})
+	}
+}
+
+func testTransfer(t *testing.T, newService NewService) {
+	prepare := func(t *testing.T, cfg Config) service.PaymentsService {
+		svc := newService(t, cfg)
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 100, "USD")
+		createAccount(t, svc, clyde, 100, "EUR")
+		return svc
+	}
+
+	t.Run("transfer moves money", func(t *testing.T) {
+		svc := prepare(t, defaultConfig())
+		_, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(60), "USD", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// bob has 40 left, alice has 160 now
+		paymentId, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(41), "USD", "")
+		if !errors.Is(err, service.ErrInsufficientFunds) {
+			t.Errorf("expected ErrInsufficientFunds, got paymentId=%v, err=%v", paymentId, err)
+		}
+		_, err = svc.Transfer(ctx, alice, bob, money.NewNumericFromInt64(160), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	for _, testcase := range []struct {
+		name     string
+		from, to entity.AccountID
+		amount   money.Numeric
+		cur      money.Currency
+		want     error
+	}{
+		{"no transferring more than balance value", bob, alice, money.NewNumericFromInt64(9000), "USD", service.ErrInsufficientFunds},
+		{"only same currency allowed sender-side", clyde, alice, money.NewNumericFromInt64(10), "EUR", service.ErrIncompatibleCurrency},
+		{"only same currency allowed receiver-side", bob, clyde, money.NewNumericFromInt64(10), "USD", service.ErrIncompatibleCurrency},
+		{"unknown currency", bob, alice, money.NewNumericFromInt64(10), "UAH", service.ErrIncompatibleCurrency},
+		{"sender must exist", "abc", bob, money.NewNumericFromInt64(10), "USD", service.ErrAccountDoesNotExist},
+		{"receiver must exist", bob, "abc", money.NewNumericFromInt64(10), "USD", service.ErrAccountDoesNotExist},
+		{"disallow transfer to the same account", bob, bob, money.NewNumericFromInt64(10), "USD", service.ErrBadTransferTarget},
+		{"amount must be positive", bob, alice, money.NewNumericFromInt64(0), "USD", service.ErrInvalidAmount},
+		{"amount must fit currency minor units", bob, alice, money.NewNumericFromStringMust("0.001"), "USD", service.ErrInvalidAmount},
+	} {
+		testcase := testcase
+		t.Run(testcase.name, func(t *testing.T) {
+			svc := prepare(t, defaultConfig())
+			paymentId, err := svc.Transfer(ctx, testcase.from, testcase.to, testcase.amount, testcase.cur, "")
+			if !errors.Is(err, testcase.want) {
+				t.Errorf("expected %v, got paymentId=%v, err=%v", testcase.want, paymentId, err)
+			}
+		})
+	}
+
+	t.Run("replay with the same idempotency key", func(t *testing.T) {
+		svc := prepare(t, defaultConfig())
+		first, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(10), "USD", "key-1")
+		if err != nil {
+			t.Error(err)
+		}
+		second, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(10), "USD", "key-1")
+		if err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, first, second)
+
+		page, err := svc.GetPayments(ctx, service.PaymentsQuery{Account: bob})
+		if err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, paymentIds(page.Payments), []entity.PaymentID{first})
+	})
+
+	t.Run("idempotency key reused with different parameters", func(t *testing.T) {
+		svc := prepare(t, defaultConfig())
+		_, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(10), "USD", "key-2")
+		if err != nil {
+			t.Error(err)
+		}
+		paymentId, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(11), "USD", "key-2")
+		if !errors.Is(err, service.ErrIdempotencyKeyReused) {
+			t.Errorf("expected ErrIdempotencyKeyReused, got paymentId=%v, err=%v", paymentId, err)
+		}
+	})
+
+	t.Run("expired idempotency key makes a new payment", func(t *testing.T) {
+		cfg := defaultConfig()
+		cfg.IdempotencyRetention = 0
+		svc := prepare(t, cfg)
+		first, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(10), "USD", "key-3")
+		if err != nil {
+			t.Error(err)
+		}
+		second, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(10), "USD", "key-3")
+		if err != nil {
+			t.Error(err)
+		}
+		assert.NotEqual(t, first, second)
+	})
+}
+
+func testTransferWithQuote(t *testing.T, newService NewService) {
+	rates, err := fx.NewStaticRateProvider(
+		fx.Rate{From: "USD", To: "EUR", Rate: money.NewNumericFromStringMust("0.9")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prepare := func(t *testing.T, cfg Config) service.PaymentsService {
+		svc := newService(t, cfg)
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 100, "EUR")
+		return svc
+	}
+	withRates := defaultConfig()
+	withRates.Rates = rates
+
+	t.Run("transfer with quote OK", func(t *testing.T) {
+		svc := prepare(t, withRates)
+		quote, err := svc.QuoteExchange(ctx, "USD", "EUR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, quote.Rate.String(), "0.9")
+
+		paymentId, err := svc.TransferWithQuote(ctx, bob, alice, money.NewNumericFromStringMust("10.01"), quote.Id, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		page, err := svc.GetPayments(ctx, service.PaymentsQuery{Account: alice})
+		if err != nil {
+			t.Fatal(err)
+		}
+		payments := page.Payments
+		if !assert.Equal(t, paymentIds(payments), []entity.PaymentID{paymentId}) {
+			return
+		}
+		assert.Equal(t, payments[0].Value.Amount.String(), "10.01")
+		assert.Equal(t, payments[0].Value.Currency, money.Currency("USD"))
+		assert.Equal(t, payments[0].Value.Outgoing, false)
+		if assert.NotNil(t, payments[0].Value.Exchange) {
+			assert.Equal(t, payments[0].Value.Exchange.Quote, quote.Id)
+			assert.Equal(t, payments[0].Value.Exchange.Rate.String(), "0.9")
+			// 10.01 * 0.9 = 9.009, rounded to cents
+			assert.Equal(t, payments[0].Value.Exchange.ToAmount.String(), "9.01")
+			assert.Equal(t, payments[0].Value.Exchange.ToCurrency, money.Currency("EUR"))
+		}
+	})
+
+	t.Run("no rate for the pair", func(t *testing.T) {
+		svc := prepare(t, withRates)
+		quote, err := svc.QuoteExchange(ctx, "EUR", "USD")
+		if !errors.Is(err, service.ErrRateUnavailable) {
+			t.Errorf("expected ErrRateUnavailable, got quote=%v, err=%v", quote, err)
+		}
+	})
+
+	t.Run("quote currencies must match accounts", func(t *testing.T) {
+		svc := prepare(t, withRates)
+		quote, err := svc.QuoteExchange(ctx, "USD", "EUR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		paymentId, err := svc.TransferWithQuote(ctx, alice, bob, money.NewNumericFromInt64(10), quote.Id, "")
+		if !errors.Is(err, service.ErrIncompatibleCurrency) {
+			t.Errorf("expected ErrIncompatibleCurrency, got paymentId=%v, err=%v", paymentId, err)
+		}
+	})
+
+	t.Run("unknown quote", func(t *testing.T) {
+		svc := prepare(t, withRates)
+		paymentId, err := svc.TransferWithQuote(ctx, bob, alice, money.NewNumericFromInt64(10), 0, "")
+		if !errors.Is(err, service.ErrQuoteNotFound) {
+			t.Errorf("expected ErrQuoteNotFound, got paymentId=%v, err=%v", paymentId, err)
+		}
+	})
+
+	t.Run("expired quote", func(t *testing.T) {
+		cfg := withRates
+		cfg.QuoteTTL = -time.Second
+		svc := prepare(t, cfg)
+		quote, err := svc.QuoteExchange(ctx, "USD", "EUR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		paymentId, err := svc.TransferWithQuote(ctx, bob, alice, money.NewNumericFromInt64(10), quote.Id, "")
+		if !errors.Is(err, service.ErrQuoteExpired) {
+			t.Errorf("expected ErrQuoteExpired, got paymentId=%v, err=%v", paymentId, err)
+		}
+	})
+
+	t.Run("cross-currency transfers disabled without rate provider", func(t *testing.T) {
+		svc := prepare(t, defaultConfig())
+		quote, err := svc.QuoteExchange(ctx, "USD", "EUR")
+		if !errors.Is(err, service.ErrRateUnavailable) {
+			t.Errorf("expected ErrRateUnavailable, got quote=%v, err=%v", quote, err)
+		}
+	})
+}
+
+func testHolds(t *testing.T, newService NewService) {
+	prepare := func(t *testing.T, cfg Config) service.PaymentsService {
+		svc := newService(t, cfg)
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 100, "USD")
+		createAccount(t, svc, clyde, 100, "EUR")
+		return svc
+	}
+	authorize := func(t *testing.T, svc service.PaymentsService, amount int64) entity.Hold {
+		hold, err := svc.Authorize(ctx, bob, alice, money.NewNumericFromInt64(amount), "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hold
+	}
+
+	t.Run("held money can't be spent", func(t *testing.T) {
+		svc := prepare(t, defaultConfig())
+		hold := authorize(t, svc, 60)
+		assert.Equal(t, hold.Status, entity.HoldActive)
+		assert.Equal(t, hold.Amount.String(), "60")
+
+		paymentId, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(41), "USD", "")
+		if !errors.Is(err, service.ErrInsufficientFunds) {
+			t.Errorf("expected ErrInsufficientFunds, got paymentId=%v, err=%v", paymentId, err)
+		}
+		other, err := svc.Authorize(ctx, bob, alice, money.NewNumericFromInt64(41), "USD")
+		if !errors.Is(err, service.ErrInsufficientFunds) {
+			t.Errorf("expected ErrInsufficientFunds, got hold=%v, err=%v", other, err)
+		}
+		_, err = svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(40), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("full capture", func(t *testing.T) {
+		svc := prepare(t, defaultConfig())
+		hold := authorize(t, svc, 60)
+		paymentId, err := svc.Capture(ctx, hold.Id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		page, err := svc.GetPayments(ctx, service.PaymentsQuery{Account: alice})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assert.Equal(t, paymentIds(page.Payments), []entity.PaymentID{paymentId}) {
+			assert.Equal(t, page.Payments[0].Value.From, bob)
+			assert.Equal(t, page.Payments[0].Value.Amount.String(), "60")
+		}
+
+		// bob has 40 left, nothing is held anymore
+		_, err = svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(40), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+		_, err = svc.Capture(ctx, hold.Id, nil)
+		if !errors.Is(err, service.ErrHoldNotActive) {
+			t.Errorf("expected ErrHoldNotActive, got err=%v", err)
+		}
+	})
+
+	t.Run("partial capture releases the rest", func(t *testing.T) {
+		svc := prepare(t, defaultConfig())
+		hold := authorize(t, svc, 60)
+		twenty := money.NewNumericFromInt64(20)
+		_, err := svc.Capture(ctx, hold.Id, &twenty)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(80), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("capture amount", func(t *testing.T) {
+		svc := prepare(t, defaultConfig())
+		hold := authorize(t, svc, 60)
+		for _, testcase := range []struct {
+			amount money.Numeric
+			want   error
+		}{
+			{money.NewNumericFromInt64(61), service.ErrCaptureExceedsHold},
+			{money.NewNumericFromInt64(0), service.ErrInvalidAmount},
+			{money.NewNumericFromStringMust("0.001"), service.ErrInvalidAmount},
+		} {
+			amount := testcase.amount
+			paymentId, err := svc.Capture(ctx, hold.Id, &amount)
+			if !errors.Is(err, testcase.want) {
+				t.Errorf("%s: expected %v, got paymentId=%v, err=%v", amount, testcase.want, paymentId, err)
+			}
+		}
+	})
+
+	t.Run("void releases money", func(t *testing.T) {
+		svc := prepare(t, defaultConfig())
+		hold := authorize(t, svc, 60)
+		err := svc.Void(ctx, hold.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(100), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+		err = svc.Void(ctx, hold.Id)
+		if !errors.Is(err, service.ErrHoldNotActive) {
+			t.Errorf("expected ErrHoldNotActive, got err=%v", err)
+		}
+		paymentId, err := svc.Capture(ctx, hold.Id, nil)
+		if !errors.Is(err, service.ErrHoldNotActive) {
+			t.Errorf("expected ErrHoldNotActive, got paymentId=%v, err=%v", paymentId, err)
+		}
+	})
+
+	t.Run("expired hold releases money", func(t *testing.T) {
+		cfg := defaultConfig()
+		cfg.HoldTTL = -time.Second
+		svc := prepare(t, cfg)
+		hold := authorize(t, svc, 60)
+		_, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(100), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+		paymentId, err := svc.Capture(ctx, hold.Id, nil)
+		if !errors.Is(err, service.ErrHoldExpired) {
+			t.Errorf("expected ErrHoldExpired, got paymentId=%v, err=%v", paymentId, err)
+		}
+		err = svc.Void(ctx, hold.Id)
+		if !errors.Is(err, service.ErrHoldExpired) {
+			t.Errorf("expected ErrHoldExpired, got err=%v", err)
+		}
+	})
+
+	t.Run("unknown hold", func(t *testing.T) {
+		svc := prepare(t, defaultConfig())
+		paymentId, err := svc.Capture(ctx, 0, nil)
+		if !errors.Is(err, service.ErrHoldNotFound) {
+			t.Errorf("expected ErrHoldNotFound, got paymentId=%v, err=%v", paymentId, err)
+		}
+		err = svc.Void(ctx, 0)
+		if !errors.Is(err, service.ErrHoldNotFound) {
+			t.Errorf("expected ErrHoldNotFound, got err=%v", err)
+		}
+	})
+
+	for _, testcase := range []struct {
+		name     string
+		from, to entity.AccountID
+		amount   money.Numeric
+		cur      money.Currency
+		want     error
+	}{
+		{"no holding more than balance value", bob, alice, money.NewNumericFromInt64(101), "USD", service.ErrInsufficientFunds},
+		{"only same currency allowed", bob, clyde, money.NewNumericFromInt64(10), "USD", service.ErrIncompatibleCurrency},
+		{"sender must exist", "abc", alice, money.NewNumericFromInt64(10), "USD", service.ErrAccountDoesNotExist},
+		{"receiver must exist", bob, "abc", money.NewNumericFromInt64(10), "USD", service.ErrAccountDoesNotExist},
+		{"disallow hold for the same account", bob, bob, money.NewNumericFromInt64(10), "USD", service.ErrBadTransferTarget},
+		{"amount must be positive", bob, alice, money.NewNumericFromInt64(0), "USD", service.ErrInvalidAmount},
+	} {
+		testcase := testcase
+		t.Run(testcase.name, func(t *testing.T) {
+			svc := prepare(t, defaultConfig())
+			hold, err := svc.Authorize(ctx, testcase.from, testcase.to, testcase.amount, testcase.cur)
+			if !errors.Is(err, testcase.want) {
+				t.Errorf("expected %v, got hold=%v, err=%v", testcase.want, hold, err)
+			}
+		})
+	}
+}
+
+func testRefund(t *testing.T, newService NewService) {
+	prepare := func(t *testing.T) (service.PaymentsService, entity.PaymentID) {
+		svc := newService(t, defaultConfig())
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 0, "USD")
+		paymentId, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(60), "USD", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return svc, paymentId
+	}
+	numeric := func(s string) *money.Numeric {
+		n := money.NewNumericFromStringMust(s)
+		return &n
+	}
+
+	t.Run("partial refunds up to the payment amount", func(t *testing.T) {
+		svc, paymentId := prepare(t)
+		first, err := svc.Refund(ctx, paymentId, numeric("20"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := svc.Refund(ctx, paymentId, numeric("30.5"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		refundId, err := svc.Refund(ctx, paymentId, numeric("9.51"))
+		if !errors.Is(err, service.ErrRefundExceedsPayment) {
+			t.Errorf("expected ErrRefundExceedsPayment, got refundId=%v, err=%v", refundId, err)
+		}
+		// The rest of the payment
+		third, err := svc.Refund(ctx, paymentId, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refundId, err = svc.Refund(ctx, paymentId, nil)
+		if !errors.Is(err, service.ErrRefundExceedsPayment) {
+			t.Errorf("expected ErrRefundExceedsPayment, got refundId=%v, err=%v", refundId, err)
+		}
+
+		page, err := svc.GetPayments(ctx, service.PaymentsQuery{Account: bob})
+		if err != nil {
+			t.Fatal(err)
+		}
+		payments := page.Payments
+		if !assert.Equal(t, paymentIds(payments), []entity.PaymentID{third, second, first, paymentId}) {
+			return
+		}
+		for i, amount := range []string{"9.5", "30.5", "20"} {
+			assert.Equal(t, payments[i].Value.From, alice)
+			assert.Equal(t, payments[i].Value.To, bob)
+			assert.Equal(t, payments[i].Value.Amount.String(), amount)
+			assert.Equal(t, payments[i].Value.RefundOf, paymentId)
+			assert.Equal(t, payments[i].Value.Outgoing, false)
+		}
+		assert.Equal(t, payments[3].Value.RefundOf, entity.PaymentID(0))
+		assert.Equal(t, payments[3].RefundedBy, []entity.PaymentID{first, second, third})
+	})
+
+	t.Run("receiver must have funds", func(t *testing.T) {
+		svc, paymentId := prepare(t)
+		_, err := svc.Transfer(ctx, alice, bob, money.NewNumericFromInt64(50), "USD", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		refundId, err := svc.Refund(ctx, paymentId, nil)
+		if !errors.Is(err, service.ErrInsufficientFunds) {
+			t.Errorf("expected ErrInsufficientFunds, got refundId=%v, err=%v", refundId, err)
+		}
+		_, err = svc.Refund(ctx, paymentId, numeric("10"))
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("refunds can't be refunded", func(t *testing.T) {
+		svc, paymentId := prepare(t)
+		refundId, err := svc.Refund(ctx, paymentId, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := svc.Refund(ctx, refundId, nil)
+		if !errors.Is(err, service.ErrNotRefundable) {
+			t.Errorf("expected ErrNotRefundable, got refundId=%v, err=%v", id, err)
+		}
+	})
+
+	t.Run("refund amount", func(t *testing.T) {
+		svc, paymentId := prepare(t)
+		for _, testcase := range []struct {
+			amount string
+			want   error
+		}{
+			{"0", service.ErrInvalidAmount},
+			{"-1", service.ErrInvalidAmount},
+			{"0.001", service.ErrInvalidAmount},
+			{"60.01", service.ErrRefundExceedsPayment},
+		} {
+			refundId, err := svc.Refund(ctx, paymentId, numeric(testcase.amount))
+			if !errors.Is(err, testcase.want) {
+				t.Errorf("%s: expected %v, got refundId=%v, err=%v", testcase.amount, testcase.want, refundId, err)
+			}
+		}
+	})
+
+	t.Run("unknown payment", func(t *testing.T) {
+		svc, _ := prepare(t)
+		refundId, err := svc.Refund(ctx, 999999, nil)
+		if !errors.Is(err, service.ErrPaymentNotFound) {
+			t.Errorf("expected ErrPaymentNotFound, got refundId=%v, err=%v", refundId, err)
+		}
+	})
+}
+
+func testTransferBatch(t *testing.T, newService NewService) {
+	prepare := func(t *testing.T) service.PaymentsService {
+		svc := newService(t, defaultConfig())
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 0, "USD")
+		createAccount(t, svc, clyde, 0, "USD")
+		return svc
+	}
+	leg := func(from, to entity.AccountID, amount int64) service.TransferLeg {
+		return service.TransferLeg{From: from, To: to, Amount: money.NewNumericFromInt64(amount), Currency: "USD"}
+	}
+
+	t.Run("legs may spend money received in earlier legs", func(t *testing.T) {
+		svc := prepare(t)
+		ids, err := svc.TransferBatch(ctx, []service.TransferLeg{
+			leg(bob, alice, 70),
+			leg(alice, clyde, 50),
+			leg(clyde, bob, 10),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !assert.Len(t, ids, 3) {
+			return
+		}
+
+		page, err := svc.GetPayments(ctx, service.PaymentsQuery{Account: alice})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, paymentIds(page.Payments), []entity.PaymentID{ids[1], ids[0]})
+
+		// bob has 40 left, alice has 20, clyde has 40
+		_, err = svc.Transfer(ctx, alice, bob, money.NewNumericFromInt64(21), "USD", "")
+		if !errors.Is(err, service.ErrInsufficientFunds) {
+			t.Errorf("expected ErrInsufficientFunds, got err=%v", err)
+		}
+		_, err = svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(40), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("failed leg rolls back the whole batch", func(t *testing.T) {
+		svc := prepare(t)
+		ids, err := svc.TransferBatch(ctx, []service.TransferLeg{
+			leg(bob, alice, 70),
+			leg(alice, clyde, 71),
+		})
+		var legErr service.ErrBatchLeg
+		if !errors.As(err, &legErr) || !errors.Is(err, service.ErrInsufficientFunds) {
+			t.Fatalf("expected ErrBatchLeg with ErrInsufficientFunds, got ids=%v, err=%v", ids, err)
+		}
+		assert.Equal(t, legErr.Index, 1)
+
+		for _, account := range []entity.AccountID{bob, alice, clyde} {
+			page, err := svc.GetPayments(ctx, service.PaymentsQuery{Account: account})
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Empty(t, page.Payments)
+		}
+		// bob still has all of his money
+		_, err = svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(100), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("invalid leg is reported with its index", func(t *testing.T) {
+		svc := prepare(t)
+		_, err := svc.TransferBatch(ctx, []service.TransferLeg{
+			leg(bob, alice, 10),
+			leg(alice, alice, 10),
+		})
+		var legErr service.ErrBatchLeg
+		if !errors.As(err, &legErr) || !errors.Is(err, service.ErrBadTransferTarget) {
+			t.Fatalf("expected ErrBatchLeg with ErrBadTransferTarget, got err=%v", err)
+		}
+		assert.Equal(t, legErr.Index, 1)
+	})
+
+	t.Run("unknown account", func(t *testing.T) {
+		svc := prepare(t)
+		_, err := svc.TransferBatch(ctx, []service.TransferLeg{
+			leg(bob, alice, 10),
+			leg(alice, "nobody", 10),
+		})
+		var legErr service.ErrBatchLeg
+		if !errors.As(err, &legErr) || !errors.Is(err, service.ErrAccountDoesNotExist) {
+			t.Fatalf("expected ErrBatchLeg with ErrAccountDoesNotExist, got err=%v", err)
+		}
+		assert.Equal(t, legErr.Index, 1)
+	})
+
+	t.Run("empty batch", func(t *testing.T) {
+		svc := prepare(t)
+		_, err := svc.TransferBatch(ctx, nil)
+		if !errors.Is(err, service.ErrBadBatch) {
+			t.Errorf("expected ErrBadBatch, got err=%v", err)
+		}
+	})
+}
+
+func testAccountStatus(t *testing.T, newService NewService) {
+	prepare := func(t *testing.T) service.PaymentsService {
+		svc := newService(t, defaultConfig())
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 100, "USD")
+		return svc
+	}
+	setStatus := func(t *testing.T, svc service.PaymentsService, id entity.AccountID, status entity.AccountStatus) {
+		_, err := svc.SetAccountStatus(ctx, id, status, "testing")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	transfer := func(svc service.PaymentsService, from, to entity.AccountID) error {
+		_, err := svc.Transfer(ctx, from, to, money.NewNumericFromInt64(10), "USD", "")
+		return err
+	}
+
+	t.Run("status is changed with a reason", func(t *testing.T) {
+		svc := prepare(t)
+		account, err := svc.SetAccountStatus(ctx, bob, entity.AccountFrozenAll, "suspicious activity")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, account.Id, bob)
+		assert.Equal(t, account.Status, entity.AccountFrozenAll)
+		assert.Equal(t, account.StatusReason, "suspicious activity")
+	})
+
+	t.Run("frozen debit account can only receive money", func(t *testing.T) {
+		svc := prepare(t)
+		setStatus(t, svc, bob, entity.AccountFrozenDebit)
+		if err := transfer(svc, bob, alice); !errors.Is(err, service.ErrAccountFrozen) {
+			t.Errorf("expected ErrAccountFrozen, got err=%v", err)
+		}
+		if err := transfer(svc, alice, bob); err != nil {
+			t.Error(err)
+		}
+		_, err := svc.Authorize(ctx, bob, alice, money.NewNumericFromInt64(10), "USD")
+		if !errors.Is(err, service.ErrAccountFrozen) {
+			t.Errorf("expected ErrAccountFrozen, got err=%v", err)
+		}
+	})
+
+	t.Run("frozen account can neither send nor receive money", func(t *testing.T) {
+		svc := prepare(t)
+		setStatus(t, svc, bob, entity.AccountFrozenAll)
+		if err := transfer(svc, bob, alice); !errors.Is(err, service.ErrAccountFrozen) {
+			t.Errorf("expected ErrAccountFrozen, got err=%v", err)
+		}
+		if err := transfer(svc, alice, bob); !errors.Is(err, service.ErrAccountFrozen) {
+			t.Errorf("expected ErrAccountFrozen, got err=%v", err)
+		}
+
+		setStatus(t, svc, bob, entity.AccountActive)
+		if err := transfer(svc, bob, alice); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("held money can't be captured from frozen account", func(t *testing.T) {
+		svc := prepare(t)
+		hold, err := svc.Authorize(ctx, bob, alice, money.NewNumericFromInt64(10), "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		setStatus(t, svc, bob, entity.AccountFrozenDebit)
+		_, err = svc.Capture(ctx, hold.Id, nil)
+		if !errors.Is(err, service.ErrAccountFrozen) {
+			t.Errorf("expected ErrAccountFrozen, got err=%v", err)
+		}
+	})
+
+	t.Run("only empty account can be closed", func(t *testing.T) {
+		svc := prepare(t)
+		_, err := svc.SetAccountStatus(ctx, bob, entity.AccountClosed, "customer request")
+		if !errors.Is(err, service.ErrAccountNotEmpty) {
+			t.Fatalf("expected ErrAccountNotEmpty, got err=%v", err)
+		}
+
+		_, err = svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(100), "USD", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		setStatus(t, svc, bob, entity.AccountClosed)
+
+		if err := transfer(svc, alice, bob); !errors.Is(err, service.ErrAccountClosed) {
+			t.Errorf("expected ErrAccountClosed, got err=%v", err)
+		}
+		_, err = svc.SetAccountStatus(ctx, bob, entity.AccountActive, "reopen")
+		if !errors.Is(err, service.ErrAccountClosed) {
+			t.Errorf("expected ErrAccountClosed, got err=%v", err)
+		}
+	})
+
+	for _, testcase := range []struct {
+		name   string
+		id     entity.AccountID
+		status entity.AccountStatus
+		reason string
+		want   error
+	}{
+		{"unknown status", bob, "sleeping", "testing", service.ErrBadAccountStatus},
+		{"empty reason", bob, entity.AccountFrozenAll, " ", service.ErrReasonRequired},
+		{"unknown account", "nobody", entity.AccountFrozenAll, "testing", service.ErrAccountDoesNotExist},
+	} {
+		testcase := testcase
+		t.Run(testcase.name, func(t *testing.T) {
+			svc := prepare(t)
+			_, err := svc.SetAccountStatus(ctx, testcase.id, testcase.status, testcase.reason)
+			if !errors.Is(err, testcase.want) {
+				t.Errorf("expected %v, got err=%v", testcase.want, err)
+			}
+		})
+	}
+}
+
+func testFees(t *testing.T, newService NewService) {
+	const revenue = entity.AccountID("revenue")
+	prepare := func(t *testing.T) service.PaymentsService {
+		schedule, err := fee.NewStaticSchedule(fee.Rule{
+			Currency: "USD",
+			Flat:     money.NewNumericFromInt64(1),
+			Percent:  money.NewNumericFromInt64(10),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rates, err := fx.NewStaticRateProvider(
+			fx.Rate{From: "USD", To: "EUR", Rate: money.NewNumericFromStringMust("0.9")},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := defaultConfig()
+		cfg.Fees = service.Fees{
+			Schedule:        schedule,
+			RevenueAccounts: map[money.Currency]entity.AccountID{"USD": revenue},
+		}
+		cfg.Rates = rates
+		svc := newService(t, cfg)
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 0, "USD")
+		createAccount(t, svc, revenue, 0, "USD")
+		return svc
+	}
+	// spendAll makes sure from has exactly amount of money by spending it and checking nothing is left.
+	spendAll := func(t *testing.T, svc service.PaymentsService, from entity.AccountID, amount string) {
+		_, err := svc.Transfer(ctx, from, clyde, money.NewNumericFromStringMust(amount), "USD", "")
+		if err != nil {
+			t.Fatalf("%s can't spend %s: %v", from, amount, err)
+		}
+		_, err = svc.Transfer(ctx, from, clyde, money.NewNumericFromStringMust("0.01"), "USD", "")
+		if !errors.Is(err, service.ErrInsufficientFunds) {
+			t.Errorf("expected %s to have %s exactly, got err=%v", from, amount, err)
+		}
+	}
+
+	t.Run("sender pays fee to revenue account", func(t *testing.T) {
+		svc := prepare(t)
+		paymentId, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(50), "USD", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		page, err := svc.GetPayments(ctx, service.PaymentsQuery{Account: bob})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !assert.Equal(t, paymentIds(page.Payments), []entity.PaymentID{paymentId}) {
+			return
+		}
+		assert.Equal(t, page.Payments[0].Value.Amount.String(), "50")
+		assert.Equal(t, page.Payments[0].Value.Fee.String(), "6")
+		assert.Equal(t, page.Payments[0].Value.FeeAccount, revenue)
+
+		// The fee is a part of the payment line of the statement
+		statement, err := svc.GetStatement(ctx, bob, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assert.Len(t, statement.Lines, 2) {
+			assert.Equal(t, statement.Lines[1].Amount.String(), "-56")
+		}
+		assert.Equal(t, statement.ClosingBalance.String(), "44")
+
+		// Revenue accounts don't pay fees
+		createAccount(t, svc, clyde, 0, "USD")
+		spendAll(t, svc, revenue, "6")
+	})
+
+	t.Run("sender must afford amount and fee", func(t *testing.T) {
+		svc := prepare(t)
+		_, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(91), "USD", "")
+		if !errors.Is(err, service.ErrInsufficientFunds) {
+			t.Errorf("expected ErrInsufficientFunds, got err=%v", err)
+		}
+		// 90 + 1 + 9 = 100
+		_, err = svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(90), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("fee is rounded to currency minor units", func(t *testing.T) {
+		svc := prepare(t)
+		_, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromStringMust("0.05"), "USD", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, err := svc.GetPayments(ctx, service.PaymentsQuery{Account: bob})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, page.Payments[0].Value.Fee.String(), "1.01")
+	})
+
+	t.Run("fee paid to revenue account itself", func(t *testing.T) {
+		svc := prepare(t)
+		_, err := svc.Transfer(ctx, bob, revenue, money.NewNumericFromInt64(10), "USD", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		createAccount(t, svc, clyde, 0, "USD")
+		spendAll(t, svc, revenue, "12")
+	})
+
+	t.Run("batch legs pay fees", func(t *testing.T) {
+		svc := prepare(t)
+		_, err := svc.TransferBatch(ctx, []service.TransferLeg{
+			{From: bob, To: alice, Amount: money.NewNumericFromInt64(10), Currency: "USD"},
+			{From: bob, To: alice, Amount: money.NewNumericFromInt64(20), Currency: "USD"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		createAccount(t, svc, clyde, 0, "USD")
+		spendAll(t, svc, revenue, "5")
+	})
+
+	t.Run("missing revenue account fails its batch leg", func(t *testing.T) {
+		// The EUR revenue account is configured, but not created
+		schedule, err := fee.NewStaticSchedule(fee.Rule{Currency: "EUR", Flat: money.NewNumericFromInt64(1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := defaultConfig()
+		cfg.Fees = service.Fees{
+			Schedule:        schedule,
+			RevenueAccounts: map[money.Currency]entity.AccountID{"EUR": revenue},
+		}
+		svc := newService(t, cfg)
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 0, "USD")
+		createAccount(t, svc, clyde, 100, "EUR")
+		createAccount(t, svc, "dave", 0, "EUR")
+
+		_, err = svc.TransferBatch(ctx, []service.TransferLeg{
+			{From: bob, To: alice, Amount: money.NewNumericFromInt64(10), Currency: "USD"},
+			{From: clyde, To: "dave", Amount: money.NewNumericFromInt64(10), Currency: "EUR"},
+		})
+		var legErr service.ErrBatchLeg
+		if assert.True(t, errors.As(err, &legErr), "got err=%v", err) {
+			assert.Equal(t, legErr.Index, 1)
+			assert.True(t, errors.Is(err, service.ErrAccountDoesNotExist))
+		}
+	})
+
+	t.Run("captures and refunds are free", func(t *testing.T) {
+		svc := prepare(t)
+		hold, err := svc.Authorize(ctx, bob, alice, money.NewNumericFromInt64(100), "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		paymentId, err := svc.Capture(ctx, hold.Id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = svc.Refund(ctx, paymentId, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = svc.Transfer(ctx, revenue, alice, money.NewNumericFromStringMust("0.01"), "USD", "")
+		if !errors.Is(err, service.ErrInsufficientFunds) {
+			t.Errorf("expected ErrInsufficientFunds, got err=%v", err)
+		}
+	})
+
+	t.Run("exchanges are free", func(t *testing.T) {
+		svc := prepare(t)
+		createAccount(t, svc, clyde, 0, "EUR")
+		quote, err := svc.QuoteExchange(ctx, "USD", "EUR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// bob can't afford any fee on top of it
+		_, err = svc.TransferWithQuote(ctx, bob, clyde, money.NewNumericFromInt64(100), quote.Id, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, err := svc.GetPayments(ctx, service.PaymentsQuery{Account: bob})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assert.Len(t, page.Payments, 1) {
+			assert.False(t, page.Payments[0].Value.Fee.IsPositive())
+			assert.Empty(t, page.Payments[0].Value.FeeAccount)
+		}
+	})
+}
+
+func testLimits(t *testing.T, newService NewService) {
+	prepare := func(t *testing.T, limits entity.AccountLimits) service.PaymentsService {
+		svc := newService(t, defaultConfig())
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 100, "USD")
+		if err := svc.SetAccountLimits(ctx, bob, limits); err != nil {
+			t.Fatal(err)
+		}
+		return svc
+	}
+	numeric := func(s string) *money.Numeric {
+		n := money.NewNumericFromStringMust(s)
+		return &n
+	}
+	count := func(n int) *int {
+		return &n
+	}
+	transfer := func(svc service.PaymentsService, from, to entity.AccountID, amount string) error {
+		_, err := svc.Transfer(ctx, from, to, money.NewNumericFromStringMust(amount), "USD", "")
+		return err
+	}
+	expectLimit := func(t *testing.T, err error, limit entity.Limit) {
+		var limitErr service.ErrAccountLimit
+		if !errors.As(err, &limitErr) || !errors.Is(err, service.ErrLimitExceeded) {
+			t.Errorf("expected ErrAccountLimit, got err=%v", err)
+			return
+		}
+		assert.Equal(t, limitErr.Account, bob)
+		assert.Equal(t, limitErr.Limit, limit)
+	}
+
+	t.Run("limits are stored", func(t *testing.T) {
+		limits := entity.AccountLimits{MaxAmount: numeric("10.5"), MonthlyCount: count(0)}
+		svc := prepare(t, limits)
+		got, err := svc.GetAccountLimits(ctx, bob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, got.MaxAmount.String(), "10.5")
+		assert.Nil(t, got.DailyAmount)
+		assert.Nil(t, got.MonthlyAmount)
+		assert.Nil(t, got.DailyCount)
+		assert.Equal(t, got.MonthlyCount, count(0))
+
+		got, err = svc.GetAccountLimits(ctx, alice)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, got, entity.AccountLimits{})
+	})
+
+	t.Run("max amount", func(t *testing.T) {
+		svc := prepare(t, entity.AccountLimits{MaxAmount: numeric("50")})
+		expectLimit(t, transfer(svc, bob, alice, "50.01"), entity.LimitMaxAmount)
+		if err := transfer(svc, bob, alice, "50"); err != nil {
+			t.Error(err)
+		}
+		// Incoming payments are not limited
+		if err := transfer(svc, alice, bob, "150"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("daily and monthly amounts", func(t *testing.T) {
+		svc := prepare(t, entity.AccountLimits{DailyAmount: numeric("60"), MonthlyAmount: numeric("50")})
+		if err := transfer(svc, bob, alice, "30"); err != nil {
+			t.Fatal(err)
+		}
+		expectLimit(t, transfer(svc, bob, alice, "20.01"), entity.LimitMonthlyAmount)
+		if err := transfer(svc, bob, alice, "20"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("daily count", func(t *testing.T) {
+		svc := prepare(t, entity.AccountLimits{DailyCount: count(2), MonthlyCount: count(5)})
+		for i := 0; i < 2; i++ {
+			if err := transfer(svc, bob, alice, "1"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expectLimit(t, transfer(svc, bob, alice, "1"), entity.LimitDailyCount)
+	})
+
+	t.Run("holds are limited", func(t *testing.T) {
+		svc := prepare(t, entity.AccountLimits{DailyAmount: numeric("10")})
+		_, err := svc.Authorize(ctx, bob, alice, money.NewNumericFromInt64(20), "USD")
+		expectLimit(t, err, entity.LimitDailyAmount)
+	})
+
+	t.Run("captures are limited", func(t *testing.T) {
+		svc := prepare(t, entity.AccountLimits{DailyAmount: numeric("10")})
+		hold, err := svc.Authorize(ctx, bob, alice, money.NewNumericFromInt64(10), "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Limits are checked at capture once again, since payments may be made meanwhile
+		if err := transfer(svc, bob, alice, "5"); err != nil {
+			t.Fatal(err)
+		}
+		_, err = svc.Capture(ctx, hold.Id, nil)
+		expectLimit(t, err, entity.LimitDailyAmount)
+		_, err = svc.Capture(ctx, hold.Id, numeric("5"))
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("negative limits", func(t *testing.T) {
+		svc := prepare(t, entity.AccountLimits{})
+		err := svc.SetAccountLimits(ctx, bob, entity.AccountLimits{DailyCount: count(-1)})
+		if !errors.Is(err, service.ErrBadLimits) {
+			t.Errorf("expected ErrBadLimits, got err=%v", err)
+		}
+		err = svc.SetAccountLimits(ctx, bob, entity.AccountLimits{MaxAmount: numeric("-1")})
+		if !errors.Is(err, service.ErrBadLimits) {
+			t.Errorf("expected ErrBadLimits, got err=%v", err)
+		}
+	})
+
+	t.Run("unknown account", func(t *testing.T) {
+		svc := prepare(t, entity.AccountLimits{})
+		err := svc.SetAccountLimits(ctx, "nobody", entity.AccountLimits{})
+		if !errors.Is(err, service.ErrAccountDoesNotExist) {
+			t.Errorf("expected ErrAccountDoesNotExist, got err=%v", err)
+		}
+		_, err = svc.GetAccountLimits(ctx, "nobody")
+		if !errors.Is(err, service.ErrAccountDoesNotExist) {
+			t.Errorf("expected ErrAccountDoesNotExist, got err=%v", err)
+		}
+	})
+}
+
+func testCreditLimit(t *testing.T, newService NewService) {
+	prepare := func(t *testing.T) service.PaymentsService {
+		svc := newService(t, defaultConfig())
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 0, "USD")
+		return svc
+	}
+	transfer := func(svc service.PaymentsService, from, to entity.AccountID, amount int64) error {
+		_, err := svc.Transfer(ctx, from, to, money.NewNumericFromInt64(amount), "USD", "")
+		return err
+	}
+
+	t.Run("balance may go down to credit limit", func(t *testing.T) {
+		svc := prepare(t)
+		account, err := svc.SetCreditLimit(ctx, bob, money.NewNumericFromInt64(50), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, account.CreditLimit.String(), "50")
+		assert.Equal(t, account.Unbounded, false)
+
+		if err := transfer(svc, bob, alice, 151); !errors.Is(err, service.ErrInsufficientFunds) {
+			t.Errorf("expected ErrInsufficientFunds, got err=%v", err)
+		}
+		if err := transfer(svc, bob, alice, 150); err != nil {
+			t.Fatal(err)
+		}
+
+		// bob owes 50 now, so the limit can't be lowered below that
+		_, err = svc.SetCreditLimit(ctx, bob, money.NewNumericFromInt64(49), false)
+		if !errors.Is(err, service.ErrCreditLimitTooLow) {
+			t.Errorf("expected ErrCreditLimitTooLow, got err=%v", err)
+		}
+		if err := transfer(svc, alice, bob, 50); err != nil {
+			t.Fatal(err)
+		}
+		_, err = svc.SetCreditLimit(ctx, bob, money.NewNumericFromInt64(0), false)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("holds may use credit", func(t *testing.T) {
+		svc := prepare(t)
+		_, err := svc.SetCreditLimit(ctx, bob, money.NewNumericFromInt64(50), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = svc.Authorize(ctx, bob, alice, money.NewNumericFromInt64(140), "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := transfer(svc, bob, alice, 11); !errors.Is(err, service.ErrInsufficientFunds) {
+			t.Errorf("expected ErrInsufficientFunds, got err=%v", err)
+		}
+		// Held money counts as debt
+		_, err = svc.SetCreditLimit(ctx, bob, money.NewNumericFromInt64(39), false)
+		if !errors.Is(err, service.ErrCreditLimitTooLow) {
+			t.Errorf("expected ErrCreditLimitTooLow, got err=%v", err)
+		}
+	})
+
+	t.Run("unbounded account", func(t *testing.T) {
+		svc := prepare(t)
+		_, err := svc.SetCreditLimit(ctx, alice, money.NewNumericFromInt64(0), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := transfer(svc, alice, bob, 1000000); err != nil {
+			t.Fatal(err)
+		}
+		_, err = svc.SetCreditLimit(ctx, alice, money.NewNumericFromInt64(0), false)
+		if !errors.Is(err, service.ErrCreditLimitTooLow) {
+			t.Errorf("expected ErrCreditLimitTooLow, got err=%v", err)
+		}
+	})
+
+	for _, testcase := range []struct {
+		name  string
+		id    entity.AccountID
+		limit money.Numeric
+		want  error
+	}{
+		{"negative limit", bob, money.NewNumericFromInt64(-1), service.ErrBadCreditLimit},
+		{"limit must fit currency minor units", bob, money.NewNumericFromStringMust("0.001"), service.ErrBadCreditLimit},
+		{"unknown account", "nobody", money.NewNumericFromInt64(1), service.ErrAccountDoesNotExist},
+	} {
+		testcase := testcase
+		t.Run(testcase.name, func(t *testing.T) {
+			svc := prepare(t)
+			_, err := svc.SetCreditLimit(ctx, testcase.id, testcase.limit, false)
+			if !errors.Is(err, testcase.want) {
+				t.Errorf("expected %v, got err=%v", testcase.want, err)
+			}
+		})
+	}
+}
+
+func testStatement(t *testing.T, newService NewService) {
+	// moment returns current time, making sure it's different from the time of anything done before or after.
+	moment := func() time.Time {
+		time.Sleep(time.Millisecond)
+		defer time.Sleep(time.Millisecond)
+		return time.Now().UTC()
+	}
+
+	svc := newService(t, defaultConfig())
+	beforeAll := moment()
+	createAccount(t, svc, bob, 100, "USD")
+	createAccount(t, svc, alice, 100, "USD")
+	afterCreate := moment()
+	first, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(30), "USD", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	afterFirst := moment()
+	second, err := svc.Transfer(ctx, alice, bob, money.NewNumericFromStringMust("10.5"), "USD", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("balance at a moment", func(t *testing.T) {
+		for _, testcase := range []struct {
+			at   time.Time
+			want string
+		}{
+			{beforeAll, "0"},
+			{afterCreate, "100"},
+			{afterFirst, "70"},
+			{time.Now().UTC(), "80.5"},
+		} {
+			balance, err := svc.GetBalance(ctx, bob, testcase.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, balance.Account, bob)
+			assert.Equal(t, balance.Currency, money.Currency("USD"))
+			assert.Equal(t, balance.Amount.String(), testcase.want)
+		}
+	})
+
+	t.Run("statement of the whole history", func(t *testing.T) {
+		statement, err := svc.GetStatement(ctx, bob, beforeAll, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, statement.Account, bob)
+		assert.Equal(t, statement.Currency, money.Currency("USD"))
+		assert.Equal(t, statement.OpeningBalance.String(), "0")
+		assert.Equal(t, statement.ClosingBalance.String(), "80.5")
+		if !assert.Len(t, statement.Lines, 3) {
+			return
+		}
+		for i, want := range []struct {
+			payment      entity.PaymentID
+			counterparty entity.AccountID
+			amount       string
+			balance      string
+		}{
+			{0, "", "100", "100"},
+			{first, alice, "-30", "70"},
+			{second, alice, "10.5", "80.5"},
+		} {
+			line := statement.Lines[i]
+			assert.Equal(t, line.Payment, want.payment)
+			assert.Equal(t, line.Counterparty, want.counterparty)
+			assert.Equal(t, line.Amount.String(), want.amount)
+			assert.Equal(t, line.Balance.String(), want.balance)
+		}
+	})
+
+	t.Run("statement of a period", func(t *testing.T) {
+		statement, err := svc.GetStatement(ctx, bob, afterCreate, afterFirst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, statement.OpeningBalance.String(), "100")
+		assert.Equal(t, statement.ClosingBalance.String(), "70")
+		if assert.Len(t, statement.Lines, 1) {
+			assert.Equal(t, statement.Lines[0].Payment, first)
+		}
+	})
+
+	t.Run("bad period", func(t *testing.T) {
+		_, err := svc.GetStatement(ctx, bob, afterFirst, afterCreate)
+		if !errors.Is(err, service.ErrBadPeriod) {
+			t.Errorf("expected ErrBadPeriod, got err=%v", err)
+		}
+	})
+
+	t.Run("unknown account", func(t *testing.T) {
+		_, err := svc.GetBalance(ctx, "nobody", time.Now())
+		if !errors.Is(err, service.ErrAccountDoesNotExist) {
+			t.Errorf("expected ErrAccountDoesNotExist, got err=%v", err)
+		}
+		_, err = svc.GetStatement(ctx, "nobody", afterCreate, time.Time{})
+		if !errors.Is(err, service.ErrAccountDoesNotExist) {
+			t.Errorf("expected ErrAccountDoesNotExist, got err=%v", err)
+		}
+	})
+}
+
+func testEvents(t *testing.T, newService NewService) {
+	svc := newService(t, defaultConfig())
+	createAccount(t, svc, bob, 100, "USD")
+	createAccount(t, svc, alice, 0, "USD")
+	paymentId, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromStringMust("10.5"), "USD", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Failed operations emit nothing.
+	_, err = svc.Transfer(ctx, alice, bob, money.NewNumericFromInt64(100), "USD", "")
+	if !errors.Is(err, service.ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got err=%v", err)
+	}
+
+	var received []entity.Event
+	collect := func(ctx context.Context, events []entity.Event) error {
+		received = append(received, events...)
+		return nil
+	}
+	fail := func(ctx context.Context, events []entity.Event) error {
+		return errors.New("broker is down")
+	}
+
+	t.Run("failed publish is retried", func(t *testing.T) {
+		n, err := svc.RelayEvents(ctx, 2, fail)
+		assert.Error(t, err)
+		assert.Zero(t, n)
+
+		n, err = svc.RelayEvents(ctx, 2, collect)
+		assert.NoError(t, err)
+		assert.Equal(t, n, 2)
+		if assert.Len(t, received, 2) {
+			assert.Equal(t, received[0].Type, entity.EventAccountCreated)
+			assert.JSONEq(t, string(received[0].Payload), `{"account_id":"bob","balance":"100","currency":"USD"}`)
+			assert.Equal(t, received[1].Type, entity.EventAccountCreated)
+			assert.True(t, received[0].Id < received[1].Id)
+		}
+	})
+
+	t.Run("published events are not passed again", func(t *testing.T) {
+		n, err := svc.RelayEvents(ctx, 10, collect)
+		assert.NoError(t, err)
+		assert.Equal(t, n, 1)
+		if assert.Len(t, received, 3) {
+			event := received[2]
+			assert.Equal(t, event.Type, entity.EventPaymentCreated)
+			assert.True(t, received[1].Id < event.Id)
+
+			var payload service.PaymentCreated
+			if assert.NoError(t, json.Unmarshal(event.Payload, &payload)) {
+				assert.Equal(t, payload.Payment, paymentId)
+				assert.Equal(t, payload.From, bob)
+				assert.Equal(t, payload.To, alice)
+				assert.Equal(t, payload.Amount.String(), "10.5")
+				assert.Equal(t, payload.Currency, "USD")
+				assert.Nil(t, payload.Fee)
+			}
+		}
+
+		n, err = svc.RelayEvents(ctx, 10, collect)
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+		assert.Len(t, received, 3)
+	})
+}
+
+func testWebhooks(t *testing.T, newService NewService) {
+	svc := newService(t, defaultConfig())
+	createAccount(t, svc, bob, 100, "USD")
+	createAccount(t, svc, alice, 0, "USD")
+
+	t.Run("bad webhooks", func(t *testing.T) {
+		_, err := svc.CreateWebhook(ctx, entity.Webhook{URL: "not a url", Secret: "s3cret"})
+		if !errors.Is(err, service.ErrBadWebhook) {
+			t.Errorf("expected ErrBadWebhook, got err=%v", err)
+		}
+		_, err = svc.CreateWebhook(ctx, entity.Webhook{URL: "https://partner.example", Secret: "s3cret", Account: "nobody"})
+		if !errors.Is(err, service.ErrAccountDoesNotExist) {
+			t.Errorf("expected ErrAccountDoesNotExist, got err=%v", err)
+		}
+		err = svc.DeleteWebhook(ctx, 1000)
+		if !errors.Is(err, service.ErrWebhookNotFound) {
+			t.Errorf("expected ErrWebhookNotFound, got err=%v", err)
+		}
+		_, err = svc.ReplayWebhookDelivery(ctx, 1000, time.Now())
+		if !errors.Is(err, service.ErrDeliveryNotFound) {
+			t.Errorf("expected ErrDeliveryNotFound, got err=%v", err)
+		}
+	})
+
+	alicePayments, err := svc.CreateWebhook(ctx, entity.Webhook{
+		URL:        "https://alice.example/hooks",
+		Secret:     "s3cret",
+		Account:    alice,
+		EventTypes: []entity.EventType{entity.EventPaymentCreated},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	everything, err := svc.CreateWebhook(ctx, entity.Webhook{URL: "https://audit.example/hooks", Secret: "0ther"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhooks, err := svc.GetWebhooks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, webhooks, []entity.Webhook{alicePayments, everything})
+
+	paymentId, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(10), "USD", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []entity.Event
+	_, err = svc.RelayEvents(ctx, 10, func(ctx context.Context, published []entity.Event) error {
+		events = published
+		return svc.EnqueueWebhookDeliveries(ctx, published)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Events delivered again don't make duplicate deliveries.
+	if err := svc.EnqueueWebhookDeliveries(ctx, events); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, err := svc.GetWebhookDeliveries(ctx, service.DeliveriesQuery{Webhook: alicePayments.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(t, deliveries, 1) {
+		return
+	}
+	aliceDelivery := deliveries[0]
+	assert.Equal(t, aliceDelivery.Event.Type, entity.EventPaymentCreated)
+	assert.Equal(t, aliceDelivery.Status, entity.DeliveryPending)
+	var payload service.PaymentCreated
+	if assert.NoError(t, json.Unmarshal(aliceDelivery.Event.Payload, &payload)) {
+		assert.Equal(t, payload.Payment, paymentId)
+	}
+
+	deliveries, err = svc.GetWebhookDeliveries(ctx, service.DeliveriesQuery{Webhook: everything.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, deliveries, 3, "both accounts created and the payment")
+
+	now := time.Now().UTC()
+	attempts := make(map[entity.WebhookID]int)
+	receiverDown := func(ctx context.Context, webhook entity.Webhook, delivery entity.WebhookDelivery) error {
+		attempts[webhook.Id]++
+		if webhook.Id == alicePayments.Id {
+			return errors.New("unexpected status 503")
+		}
+		return nil
+	}
+
+	t.Run("deliveries are retried until dead", func(t *testing.T) {
+		n, err := svc.DeliverWebhooks(ctx, now, 10, receiverDown)
+		assert.NoError(t, err)
+		assert.Equal(t, n, 4)
+		assert.Equal(t, attempts, map[entity.WebhookID]int{alicePayments.Id: 1, everything.Id: 3})
+
+		delivered, err := svc.GetWebhookDeliveries(ctx, service.DeliveriesQuery{Status: entity.DeliveryDelivered})
+		assert.NoError(t, err)
+		assert.Len(t, delivered, 3)
+
+		// Not due yet.
+		n, err = svc.DeliverWebhooks(ctx, now, 10, receiverDown)
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+
+		for i := 1; i < service.MaxDeliveryAttempts; i++ {
+			now = now.Add(service.RetryDelay(i))
+			n, err = svc.DeliverWebhooks(ctx, now, 10, receiverDown)
+			assert.NoError(t, err)
+			assert.Equal(t, n, 1)
+		}
+		assert.Equal(t, attempts[alicePayments.Id], service.MaxDeliveryAttempts)
+
+		dead, err := svc.GetWebhookDeliveries(ctx, service.DeliveriesQuery{Status: entity.DeliveryDead})
+		assert.NoError(t, err)
+		if assert.Len(t, dead, 1) {
+			assert.Equal(t, dead[0].Id, aliceDelivery.Id)
+			assert.Equal(t, dead[0].Attempts, service.MaxDeliveryAttempts)
+			assert.Equal(t, dead[0].LastError, "unexpected status 503")
+		}
+
+		n, err = svc.DeliverWebhooks(ctx, now.Add(24*time.Hour), 10, receiverDown)
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("dead deliveries can be replayed", func(t *testing.T) {
+		replayed, err := svc.ReplayWebhookDelivery(ctx, aliceDelivery.Id, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, replayed.Status, entity.DeliveryPending)
+		assert.Zero(t, replayed.Attempts)
+
+		n, err := svc.DeliverWebhooks(ctx, now, 10, func(context.Context, entity.Webhook, entity.WebhookDelivery) error {
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, n, 1)
+
+		deliveries, err := svc.GetWebhookDeliveries(ctx, service.DeliveriesQuery{Webhook: alicePayments.Id})
+		assert.NoError(t, err)
+		if assert.Len(t, deliveries, 1) {
+			assert.Equal(t, deliveries[0].Status, entity.DeliveryDelivered)
+			assert.Equal(t, deliveries[0].Attempts, 1)
+			assert.False(t, deliveries[0].DeliveredAt.IsZero())
+		}
+	})
+
+	t.Run("deleted webhooks forget deliveries", func(t *testing.T) {
+		if err := svc.DeleteWebhook(ctx, alicePayments.Id); err != nil {
+			t.Fatal(err)
+		}
+		webhooks, err := svc.GetWebhooks(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, webhooks, []entity.Webhook{everything})
+
+		deliveries, err := svc.GetWebhookDeliveries(ctx, service.DeliveriesQuery{})
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 3)
+
+		_, err = svc.ReplayWebhookDelivery(ctx, aliceDelivery.Id, now)
+		if !errors.Is(err, service.ErrDeliveryNotFound) {
+			t.Errorf("expected ErrDeliveryNotFound, got err=%v", err)
+		}
+	})
+}
+
+func testAuditLog(t *testing.T, newService NewService) {
+	ctx := context.Background()
+	svc := newService(t, defaultConfig())
+
+	begin := time.Now().UTC().Truncate(time.Second)
+	for i, entry := range []entity.AuditEntry{
+		{Actor: "admin", RequestId: "r1", Method: "CreateAccount", Params: json.RawMessage(`{"id": "bob"}`)},
+		{Actor: "gateway", RequestId: "r2", Method: "Transfer", Params: json.RawMessage(`{"from": "bob", "to": "alice"}`)},
+		{Actor: "admin", RequestId: "r3", Method: "Transfer", Params: json.RawMessage(`{}`), Error: "insufficient funds"},
+	} {
+		entry.Time = begin.Add(time.Duration(i) * time.Minute)
+		if err := svc.AppendAuditEntry(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	requestIds := func(entries []entity.AuditEntry) []string {
+		var result []string
+		for _, e := range entries {
+			result = append(result, e.RequestId)
+		}
+		return result
+	}
+
+	t.Run("recent entries first", func(t *testing.T) {
+		entries, err := svc.GetAuditLog(ctx, service.AuditQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []string{"r3", "r2", "r1"}, requestIds(entries))
+		assert.Greater(t, int64(entries[0].Id), int64(entries[1].Id))
+
+		e := entries[1]
+		assert.Equal(t, "gateway", e.Actor)
+		assert.Equal(t, "Transfer", e.Method)
+		assert.True(t, e.Time.Equal(begin.Add(time.Minute)))
+		assert.JSONEq(t, `{"from": "bob", "to": "alice"}`, string(e.Params))
+		assert.Empty(t, e.Error)
+		assert.Equal(t, "insufficient funds", entries[0].Error)
+	})
+
+	for _, testcase := range []struct {
+		name  string
+		query service.AuditQuery
+		want  []string
+	}{
+		{"by actor", service.AuditQuery{Actor: "admin"}, []string{"r3", "r1"}},
+		{"by method", service.AuditQuery{Method: "Transfer"}, []string{"r3", "r2"}},
+		{"since is inclusive", service.AuditQuery{Since: begin.Add(time.Minute)}, []string{"r3", "r2"}},
+		{"until is exclusive", service.AuditQuery{Until: begin.Add(time.Minute)}, []string{"r1"}},
+		{"limit", service.AuditQuery{Limit: 1}, []string{"r3"}},
+		{"nothing matches", service.AuditQuery{Actor: "nobody"}, nil},
+	} {
+		testcase := testcase
+		t.Run(testcase.name, func(t *testing.T) {
+			entries, err := svc.GetAuditLog(ctx, testcase.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, testcase.want, requestIds(entries))
+		})
+	}
+
+	t.Run("bad query", func(t *testing.T) {
+		_, err := svc.GetAuditLog(ctx, service.AuditQuery{Limit: -1})
+		assert.True(t, errors.Is(err, service.ErrBadQuery))
+		_, err = svc.GetAuditLog(ctx, service.AuditQuery{Since: begin, Until: begin})
+		assert.True(t, errors.Is(err, service.ErrBadQuery))
+	})
+}
+
+func testGetPayments(t *testing.T, newService NewService) {
+	t.Run("check account exists", func(t *testing.T) {
+		svc := newService(t, defaultConfig())
+		page, err := svc.GetPayments(ctx, service.PaymentsQuery{Account: "zzz"})
+		if !errors.Is(err, service.ErrAccountDoesNotExist) {
+			t.Errorf("expected ErrAccountDoesNotExist, got result=%v, err=%v", page, err)
+		}
+	})
+
+	t.Run("bad query", func(t *testing.T) {
+		svc := newService(t, defaultConfig())
+		createAccount(t, svc, bob, 100, "USD")
+		for _, testcase := range []struct {
+			query service.PaymentsQuery
+			want  error
+		}{
+			{service.PaymentsQuery{}, service.ErrBadAccountID},
+			{service.PaymentsQuery{Account: bob, Direction: "sideways"}, service.ErrBadQuery},
+			{service.PaymentsQuery{Account: bob, Limit: -1}, service.ErrBadQuery},
+			{service.PaymentsQuery{Account: bob, Cursor: "garbage"}, service.ErrBadCursor},
+		} {
+			page, err := svc.GetPayments(ctx, testcase.query)
+			if !errors.Is(err, testcase.want) {
+				t.Errorf("%+v: expected %v, got result=%v, err=%v", testcase.query, testcase.want, page, err)
+			}
+		}
+	})
+
+	t.Run("get empty payments", func(t *testing.T) {
+		svc := newService(t, defaultConfig())
+		createAccount(t, svc, bob, 100, "USD")
+		page, err := svc.GetPayments(ctx, service.PaymentsQuery{Account: bob})
+		if err != nil {
+			t.Error(err)
+		}
+		assert.Equal(t, len(page.Payments), 0)
+		assert.Equal(t, page.NextCursor, "")
+	})
+
+	t.Run("get payments OK", func(t *testing.T) {
+		svc := newService(t, defaultConfig())
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 100, "USD")
+
+		toAlice, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(50), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+		toBob, err := svc.Transfer(ctx, alice, bob, money.NewNumericFromInt64(35), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+
+		for _, testcase := range []struct {
+			account  entity.AccountID
+			outgoing []bool
+		}{
+			{bob, []bool{false, true}},
+			{alice, []bool{true, false}},
+		} {
+			page, err := svc.GetPayments(ctx, service.PaymentsQuery{Account: testcase.account})
+			if err != nil {
+				t.Fatal(err)
+			}
+			payments := page.Payments
+			if !assert.Equal(t, paymentIds(payments), []entity.PaymentID{toBob, toAlice}) {
+				continue
+			}
+			assert.Equal(t, payments[0].Value.From, alice)
+			assert.Equal(t, payments[0].Value.To, bob)
+			assert.Equal(t, payments[0].Value.Amount, money.NewNumericFromInt64(35))
+			assert.Equal(t, payments[0].Value.Currency, money.Currency("USD"))
+			assert.Equal(t, payments[0].Value.Outgoing, testcase.outgoing[0])
+			assert.Equal(t, payments[1].Value.From, bob)
+			assert.Equal(t, payments[1].Value.To, alice)
+			assert.Equal(t, payments[1].Value.Amount, money.NewNumericFromInt64(50))
+			assert.Equal(t, payments[1].Value.Currency, money.Currency("USD"))
+			assert.Equal(t, payments[1].Value.Outgoing, testcase.outgoing[1])
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		svc := newService(t, defaultConfig())
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 100, "USD")
+		var want []entity.PaymentID
+		for i := 1; i <= 5; i++ {
+			paymentId, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(int64(i)), "USD", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want = append([]entity.PaymentID{paymentId}, want...)
+		}
+
+		var (
+			seen   []entity.PaymentID
+			cursor string
+		)
+		for pages := 0; pages < 3; pages++ {
+			page, err := svc.GetPayments(ctx, service.PaymentsQuery{Account: bob, Limit: 2, Cursor: cursor})
+			if err != nil {
+				t.Fatal(err)
+			}
+			seen = append(seen, paymentIds(page.Payments)...)
+			cursor = page.NextCursor
+			if cursor == "" {
+				break
+			}
+		}
+		assert.Equal(t, seen, want)
+		assert.Equal(t, cursor, "")
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		svc := newService(t, defaultConfig())
+		for _, id := range [...]entity.AccountID{bob, alice, clyde} {
+			createAccount(t, svc, id, 100, "USD")
+		}
+		toAlice, err := svc.Transfer(ctx, bob, alice, money.NewNumericFromInt64(10), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+		monthly, err := svc.Transfer(ctx, bob, clyde, money.NewNumericFromInt64(20), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+		fromAlice, err := svc.Transfer(ctx, alice, bob, money.NewNumericFromInt64(30), "USD", "")
+		if err != nil {
+			t.Error(err)
+		}
+
+		fifteen := money.NewNumericFromInt64(15)
+		twenty := money.NewNumericFromInt64(20)
+		for _, testcase := range []struct {
+			query service.PaymentsQuery
+			want  []entity.PaymentID
+		}{
+			{
+				query: service.PaymentsQuery{Account: bob, Direction: service.DirectionOutgoing},
+				want:  []entity.PaymentID{monthly, toAlice},
+			},
+			{
+				query: service.PaymentsQuery{Account: bob, Direction: service.DirectionIncoming},
+				want:  []entity.PaymentID{fromAlice},
+			},
+			{
+				query: service.PaymentsQuery{Account: bob, Counterparty: alice},
+				want:  []entity.PaymentID{fromAlice, toAlice},
+			},
+			{
+				query: service.PaymentsQuery{Account: bob, MinAmount: &fifteen},
+				want:  []entity.PaymentID{fromAlice, monthly},
+			},
+			{
+				query: service.PaymentsQuery{Account: bob, MaxAmount: &twenty},
+				want:  []entity.PaymentID{monthly, toAlice},
+			},
+			{
+				query: service.PaymentsQuery{Account: bob, Until: time.Now().Add(-time.Hour)},
+				want:  nil,
+			},
+			{
+				query: service.PaymentsQuery{Account: bob, Since: time.Now().Add(-time.Hour), Direction: service.DirectionIncoming},
+				want:  []entity.PaymentID{fromAlice},
+			},
+		} {
+			page, err := svc.GetPayments(ctx, testcase.query)
+			if err != nil {
+				t.Error(err)
+			}
+			assert.Equal(t, testcase.want, paymentIds(page.Payments), "%+v", testcase.query)
+		}
+	})
+}
+
+func testGetAccounts(t *testing.T, newService NewService) {
+	t.Run("check currency", func(t *testing.T) {
+		svc := newService(t, defaultConfig())
+		res, err := svc.GetAccounts(ctx, money.NewCurrency("UAH"))
+		if !errors.Is(err, service.ErrIncompatibleCurrency) {
+			t.Errorf("expected ErrIncompatibleCurrency, got result=%v, err=%v", res, err)
+		}
+	})
+
+	t.Run("get accounts OK", func(t *testing.T) {
+		svc := newService(t, defaultConfig())
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 100, "USD")
+		createAccount(t, svc, clyde, 100, "EUR")
+
+		for _, testcase := range []struct {
+			cur  money.Currency
+			want []entity.AccountID
+		}{
+			{"USD", []entity.AccountID{alice, bob}},
+			{"EUR", []entity.AccountID{clyde}},
+			{"RUB", nil},
+		} {
+			result, err := svc.GetAccounts(ctx, testcase.cur)
+			if err != nil {
+				t.Error(err)
+			}
+			assert.Equal(t, len(testcase.want), len(result), testcase.cur)
+			if len(testcase.want) > 0 {
+				assert.Equal(t, testcase.want, result, testcase.cur)
+			}
+		}
+	})
+}
+
+func testScheduledTransfers(t *testing.T, newService NewService) {
+	svc := newService(t, defaultConfig())
+	createAccount(t, svc, bob, 100, "USD")
+	createAccount(t, svc, alice, 0, "USD")
+
+	startAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+	for _, testcase := range []struct {
+		name string
+		st   entity.ScheduledTransfer
+		want error
+	}{
+		{"unknown recurrence", entity.ScheduledTransfer{Recurrence: "yearly"}, service.ErrBadSchedule},
+		{"monthly needs a day", entity.ScheduledTransfer{Recurrence: entity.RecurrenceMonthly}, service.ErrBadSchedule},
+		{"day is only for monthly", entity.ScheduledTransfer{Recurrence: entity.RecurrenceDaily, DayOfMonth: 1}, service.ErrBadSchedule},
+		{"start in the past", entity.ScheduledTransfer{StartAt: startAt.Add(-24 * time.Hour)}, service.ErrBadSchedule},
+		{"end before start", entity.ScheduledTransfer{Recurrence: entity.RecurrenceDaily, StartAt: startAt, EndAt: startAt.Add(-time.Minute)}, service.ErrBadSchedule},
+		{"end of one-off", entity.ScheduledTransfer{StartAt: startAt, EndAt: startAt.Add(time.Hour)}, service.ErrBadSchedule},
+		{"receiver must exist", entity.ScheduledTransfer{To: "nobody"}, service.ErrAccountDoesNotExist},
+		{"disallow transfer to the same account", entity.ScheduledTransfer{To: bob}, service.ErrBadTransferTarget},
+		{"amount must be positive", entity.ScheduledTransfer{Amount: money.NewNumericFromInt64(-1)}, service.ErrInvalidAmount},
+	} {
+		testcase := testcase
+		t.Run(testcase.name, func(t *testing.T) {
+			st := testcase.st
+			st.From = bob
+			if st.To == "" {
+				st.To = alice
+			}
+			if st.Amount.Equal(money.Numeric{}) {
+				st.Amount = money.NewNumericFromInt64(10)
+			}
+			st.Currency = "USD"
+			_, err := svc.ScheduleTransfer(ctx, st)
+			if !errors.Is(err, testcase.want) {
+				t.Errorf("expected %v, got err=%v", testcase.want, err)
+			}
+		})
+	}
+
+	daily, err := svc.ScheduleTransfer(ctx, entity.ScheduledTransfer{
+		From:       bob,
+		To:         alice,
+		Amount:     money.NewNumericFromInt64(40),
+		Currency:   "USD",
+		Recurrence: entity.RecurrenceDaily,
+		StartAt:    startAt,
+		EndAt:      startAt.Add(48 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, daily.Status, entity.ScheduleActive)
+	assert.True(t, daily.NextRunAt.Equal(startAt))
+
+	monthly, err := svc.ScheduleTransfer(ctx, entity.ScheduledTransfer{
+		From:       bob,
+		To:         alice,
+		Amount:     money.NewNumericFromInt64(1),
+		Currency:   "USD",
+		Recurrence: entity.RecurrenceMonthly,
+		DayOfMonth: 31,
+		StartAt:    startAt.AddDate(0, 1, 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("nothing is due before the start", func(t *testing.T) {
+		n, err := svc.RunScheduledTransfers(ctx, startAt.Add(-time.Second), 10)
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("missed occurrences are caught up", func(t *testing.T) {
+		// Every missed occurrence is made by the same pass, none of them is skipped
+		now := startAt.Add(72 * time.Hour)
+		n, err := svc.RunScheduledTransfers(ctx, now, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, n, 3)
+
+		runs, err := svc.GetScheduledRuns(ctx, daily.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !assert.Len(t, runs, 3) {
+			return
+		}
+		for i, run := range runs {
+			assert.True(t, run.ScheduledAt.Equal(startAt.AddDate(0, 0, i)), "run %d is scheduled at %s", i, run.ScheduledAt)
+			assert.True(t, run.RanAt.Equal(now), "run %d ran at %s", i, run.RanAt)
+		}
+		assert.Equal(t, runs[0].Status, entity.RunSucceeded)
+		assert.Equal(t, runs[1].Status, entity.RunSucceeded)
+		assert.NotZero(t, runs[0].Payment)
+		assert.Equal(t, runs[2].Status, entity.RunFailed, "bob has 20 left")
+		assert.Zero(t, runs[2].Payment)
+		assert.Equal(t, runs[2].Error, service.ErrInsufficientFunds.Error())
+
+		balance, err := svc.GetBalance(ctx, alice, time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.True(t, balance.Amount.Equal(money.NewNumericFromInt64(80)), "alice has %s", balance.Amount)
+
+		runs, err = svc.GetScheduledRuns(ctx, monthly.Id)
+		assert.NoError(t, err)
+		assert.Len(t, runs, 0)
+	})
+
+	t.Run("last occurrence completes the schedule", func(t *testing.T) {
+		schedules, err := svc.GetScheduledTransfers(ctx, bob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !assert.Len(t, schedules, 2) {
+			return
+		}
+		assert.Equal(t, schedules[0].Id, daily.Id)
+		assert.Equal(t, schedules[0].Status, entity.ScheduleCompleted)
+		assert.True(t, schedules[0].NextRunAt.IsZero())
+		assert.Equal(t, schedules[1].Status, entity.ScheduleActive)
+
+		schedules, err = svc.GetScheduledTransfers(ctx, alice)
+		assert.NoError(t, err)
+		assert.Len(t, schedules, 0)
+
+		_, err = svc.GetScheduledTransfers(ctx, "nobody")
+		if !errors.Is(err, service.ErrAccountDoesNotExist) {
+			t.Errorf("expected ErrAccountDoesNotExist, got err=%v", err)
+		}
+	})
+
+	t.Run("cancelled transfers are never made", func(t *testing.T) {
+		cancelled, err := svc.CancelScheduledTransfer(ctx, monthly.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, cancelled.Status, entity.ScheduleCancelled)
+
+		_, err = svc.CancelScheduledTransfer(ctx, monthly.Id)
+		if !errors.Is(err, service.ErrScheduleNotActive) {
+			t.Errorf("expected ErrScheduleNotActive, got err=%v", err)
+		}
+		_, err = svc.CancelScheduledTransfer(ctx, daily.Id)
+		if !errors.Is(err, service.ErrScheduleNotActive) {
+			t.Errorf("expected ErrScheduleNotActive, got err=%v", err)
+		}
+
+		n, err := svc.RunScheduledTransfers(ctx, startAt.AddDate(1, 0, 0), 10)
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("internal failure doesn't block other schedules", func(t *testing.T) {
+		// EUR fees have no revenue account, so EUR transfers fail internally.
+		schedule, err := fee.NewStaticSchedule(fee.Rule{Currency: "EUR", Flat: money.NewNumericFromInt64(1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := defaultConfig()
+		cfg.Fees = service.Fees{Schedule: schedule}
+		svc := newService(t, cfg)
+		createAccount(t, svc, bob, 100, "USD")
+		createAccount(t, svc, alice, 0, "USD")
+		createAccount(t, svc, clyde, 100, "EUR")
+		createAccount(t, svc, "dave", 0, "EUR")
+
+		broken, err := svc.ScheduleTransfer(ctx, entity.ScheduledTransfer{
+			From:     clyde,
+			To:       "dave",
+			Amount:   money.NewNumericFromInt64(10),
+			Currency: "EUR",
+			StartAt:  startAt,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		later, err := svc.ScheduleTransfer(ctx, entity.ScheduledTransfer{
+			From:     bob,
+			To:       alice,
+			Amount:   money.NewNumericFromInt64(10),
+			Currency: "USD",
+			StartAt:  startAt.Add(time.Minute),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := svc.RunScheduledTransfers(ctx, startAt.Add(time.Hour), 10)
+		var internal service.ErrInternal
+		if !errors.As(err, &internal) {
+			t.Errorf("expected ErrInternal, got err=%v", err)
+		}
+		assert.Equal(t, n, 1)
+
+		runs, err := svc.GetScheduledRuns(ctx, later.Id)
+		if assert.NoError(t, err) && assert.Len(t, runs, 1) {
+			assert.Equal(t, runs[0].Status, entity.RunSucceeded)
+		}
+		// The broken one isn't recorded, it's retried on the next pass.
+		runs, err = svc.GetScheduledRuns(ctx, broken.Id)
+		assert.NoError(t, err)
+		assert.Len(t, runs, 0)
+		schedules, err := svc.GetScheduledTransfers(ctx, clyde)
+		if assert.NoError(t, err) && assert.Len(t, schedules, 1) {
+			assert.Equal(t, schedules[0].Status, entity.ScheduleActive)
+		}
+	})
+
+	t.Run("unknown scheduled transfer", func(t *testing.T) {
+		_, err := svc.CancelScheduledTransfer(ctx, 1000)
+		if !errors.Is(err, service.ErrScheduleNotFound) {
+			t.Errorf("expected ErrScheduleNotFound, got err=%v", err)
+		}
+		_, err = svc.GetScheduledRuns(ctx, 1000)
+		if !errors.Is(err, service.ErrScheduleNotFound) {
+			t.Errorf("expected ErrScheduleNotFound, got err=%v", err)
+		}
+	})
+}
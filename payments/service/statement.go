@@ -0,0 +1,37 @@
+package service
+
+import (
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"time"
+)
+
+// StatementPeriod validates a statement period and returns its effective end, which is now if until is zero.
+func StatementPeriod(since, until time.Time) (time.Time, error) {
+	if until.IsZero() {
+		until = time.Now().UTC()
+	}
+	if !since.Before(until) {
+		return time.Time{}, ErrBadPeriod
+	}
+	return until, nil
+}
+
+// NewStatement returns a statement of the given lines, filling in running and closing balances.
+// Lines must be in chronological order and have Amount set.
+func NewStatement(account entity.Account, since, until time.Time, opening money.Numeric, lines []entity.StatementLine) entity.Statement {
+	balance := opening
+	for i := range lines {
+		balance = balance.Add(lines[i].Amount)
+		lines[i].Balance = balance
+	}
+	return entity.Statement{
+		Account:        account.Id,
+		Currency:       account.Currency,
+		Since:          since,
+		Until:          until,
+		OpeningBalance: opening,
+		Lines:          lines,
+		ClosingBalance: balance,
+	}
+}
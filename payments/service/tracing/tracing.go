@@ -0,0 +1,305 @@
+// Package tracing provides service.PaymentsService middleware starting a span for every operation.
+package tracing
+
+import (
+	"context"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// InstrumentationName identifies tracers of the payments service.
+const InstrumentationName = "github.com/lightsgoout/fintech-go/payments"
+
+// PaymentsService is service.PaymentsService tracing calls to the next service.
+// Spans are children of the span in the context of a call, e.g. the one started for an HTTP request,
+// and the context passed to the next service carries them down to database queries.
+type PaymentsService struct {
+	next   service.PaymentsService
+	tracer trace.Tracer
+}
+
+// NewPaymentsService returns next traced by a tracer of tp.
+func NewPaymentsService(next service.PaymentsService, tp trace.TracerProvider) *PaymentsService {
+	return &PaymentsService{
+		next:   next,
+		tracer: tp.Tracer(InstrumentationName),
+	}
+}
+
+func (s PaymentsService) start(ctx context.Context, method string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "PaymentsService."+method, opts...)
+}
+
+// end ends span of a call which failed with err, unless it's nil.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s PaymentsService) CreateAccount(ctx context.Context, id entity.AccountID, balance money.Numeric, cur money.Currency) (err error) {
+	ctx, span := s.start(ctx, "CreateAccount")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.CreateAccount(ctx, id, balance, cur)
+}
+
+func (s PaymentsService) Transfer(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency, idempotencyKey entity.IdempotencyKey) (_ entity.PaymentID, err error) {
+	ctx, span := s.start(ctx, "Transfer", trace.WithAttributes(
+		attribute.String("payment.from", string(from)),
+		attribute.String("payment.to", string(to)),
+		attribute.String("payment.currency", string(cur)),
+	))
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.Transfer(ctx, from, to, amount, cur, idempotencyKey)
+}
+
+func (s PaymentsService) TransferBatch(ctx context.Context, legs []service.TransferLeg) (_ []entity.PaymentID, err error) {
+	ctx, span := s.start(ctx, "TransferBatch", trace.WithAttributes(attribute.Int("payment.legs", len(legs))))
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.TransferBatch(ctx, legs)
+}
+
+func (s PaymentsService) QuoteExchange(ctx context.Context, from, to money.Currency) (_ entity.Quote, err error) {
+	ctx, span := s.start(ctx, "QuoteExchange")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.QuoteExchange(ctx, from, to)
+}
+
+func (s PaymentsService) TransferWithQuote(ctx context.Context, from, to entity.AccountID, amount money.Numeric, quoteId entity.QuoteID, idempotencyKey entity.IdempotencyKey) (_ entity.PaymentID, err error) {
+	ctx, span := s.start(ctx, "TransferWithQuote")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.TransferWithQuote(ctx, from, to, amount, quoteId, idempotencyKey)
+}
+
+func (s PaymentsService) Authorize(ctx context.Context, from, to entity.AccountID, amount money.Numeric, cur money.Currency) (_ entity.Hold, err error) {
+	ctx, span := s.start(ctx, "Authorize")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.Authorize(ctx, from, to, amount, cur)
+}
+
+func (s PaymentsService) Capture(ctx context.Context, id entity.HoldID, amount *money.Numeric) (_ entity.PaymentID, err error) {
+	ctx, span := s.start(ctx, "Capture")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.Capture(ctx, id, amount)
+}
+
+func (s PaymentsService) Void(ctx context.Context, id entity.HoldID) (err error) {
+	ctx, span := s.start(ctx, "Void")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.Void(ctx, id)
+}
+
+func (s PaymentsService) Refund(ctx context.Context, id entity.PaymentID, amount *money.Numeric) (_ entity.PaymentID, err error) {
+	ctx, span := s.start(ctx, "Refund")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.Refund(ctx, id, amount)
+}
+
+func (s PaymentsService) SetAccountStatus(ctx context.Context, id entity.AccountID, status entity.AccountStatus, reason string) (_ entity.Account, err error) {
+	ctx, span := s.start(ctx, "SetAccountStatus")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.SetAccountStatus(ctx, id, status, reason)
+}
+
+func (s PaymentsService) SetCreditLimit(ctx context.Context, id entity.AccountID, limit money.Numeric, unbounded bool) (_ entity.Account, err error) {
+	ctx, span := s.start(ctx, "SetCreditLimit")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.SetCreditLimit(ctx, id, limit, unbounded)
+}
+
+func (s PaymentsService) SetAccountLimits(ctx context.Context, id entity.AccountID, limits entity.AccountLimits) (err error) {
+	ctx, span := s.start(ctx, "SetAccountLimits")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.SetAccountLimits(ctx, id, limits)
+}
+
+func (s PaymentsService) GetAccountLimits(ctx context.Context, id entity.AccountID) (_ entity.AccountLimits, err error) {
+	ctx, span := s.start(ctx, "GetAccountLimits")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.GetAccountLimits(ctx, id)
+}
+
+func (s PaymentsService) GetBalance(ctx context.Context, id entity.AccountID, at time.Time) (_ entity.Balance, err error) {
+	ctx, span := s.start(ctx, "GetBalance")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.GetBalance(ctx, id, at)
+}
+
+func (s PaymentsService) GetStatement(ctx context.Context, id entity.AccountID, since, until time.Time) (_ entity.Statement, err error) {
+	ctx, span := s.start(ctx, "GetStatement")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.GetStatement(ctx, id, since, until)
+}
+
+func (s PaymentsService) GetPayments(ctx context.Context, query service.PaymentsQuery) (_ service.PaymentsPage, err error) {
+	ctx, span := s.start(ctx, "GetPayments")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.GetPayments(ctx, query)
+}
+
+func (s PaymentsService) GetAccounts(ctx context.Context, cur money.Currency) (_ []entity.AccountID, err error) {
+	ctx, span := s.start(ctx, "GetAccounts")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.GetAccounts(ctx, cur)
+}
+
+func (s PaymentsService) RelayEvents(ctx context.Context, limit int, publish service.PublishFunc) (_ int, err error) {
+	ctx, span := s.start(ctx, "RelayEvents")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.RelayEvents(ctx, limit, publish)
+}
+
+func (s PaymentsService) CreateWebhook(ctx context.Context, webhook entity.Webhook) (_ entity.Webhook, err error) {
+	ctx, span := s.start(ctx, "CreateWebhook")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.CreateWebhook(ctx, webhook)
+}
+
+func (s PaymentsService) GetWebhooks(ctx context.Context) (_ []entity.Webhook, err error) {
+	ctx, span := s.start(ctx, "GetWebhooks")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.GetWebhooks(ctx)
+}
+
+func (s PaymentsService) DeleteWebhook(ctx context.Context, id entity.WebhookID) (err error) {
+	ctx, span := s.start(ctx, "DeleteWebhook")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.DeleteWebhook(ctx, id)
+}
+
+func (s PaymentsService) EnqueueWebhookDeliveries(ctx context.Context, events []entity.Event) (err error) {
+	ctx, span := s.start(ctx, "EnqueueWebhookDeliveries")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.EnqueueWebhookDeliveries(ctx, events)
+}
+
+func (s PaymentsService) DeliverWebhooks(ctx context.Context, now time.Time, limit int, deliver service.DeliverFunc) (_ int, err error) {
+	ctx, span := s.start(ctx, "DeliverWebhooks")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.DeliverWebhooks(ctx, now, limit, deliver)
+}
+
+func (s PaymentsService) GetWebhookDeliveries(ctx context.Context, query service.DeliveriesQuery) (_ []entity.WebhookDelivery, err error) {
+	ctx, span := s.start(ctx, "GetWebhookDeliveries")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.GetWebhookDeliveries(ctx, query)
+}
+
+func (s PaymentsService) ReplayWebhookDelivery(ctx context.Context, id entity.DeliveryID, now time.Time) (_ entity.WebhookDelivery, err error) {
+	ctx, span := s.start(ctx, "ReplayWebhookDelivery")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.ReplayWebhookDelivery(ctx, id, now)
+}
+
+func (s PaymentsService) ScheduleTransfer(ctx context.Context, st entity.ScheduledTransfer) (_ entity.ScheduledTransfer, err error) {
+	ctx, span := s.start(ctx, "ScheduleTransfer")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.ScheduleTransfer(ctx, st)
+}
+
+func (s PaymentsService) GetScheduledTransfers(ctx context.Context, from entity.AccountID) (_ []entity.ScheduledTransfer, err error) {
+	ctx, span := s.start(ctx, "GetScheduledTransfers")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.GetScheduledTransfers(ctx, from)
+}
+
+func (s PaymentsService) CancelScheduledTransfer(ctx context.Context, id entity.ScheduledTransferID) (_ entity.ScheduledTransfer, err error) {
+	ctx, span := s.start(ctx, "CancelScheduledTransfer")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.CancelScheduledTransfer(ctx, id)
+}
+
+func (s PaymentsService) GetScheduledRuns(ctx context.Context, id entity.ScheduledTransferID) (_ []entity.ScheduledRun, err error) {
+	ctx, span := s.start(ctx, "GetScheduledRuns")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.GetScheduledRuns(ctx, id)
+}
+
+func (s PaymentsService) RunScheduledTransfers(ctx context.Context, now time.Time, limit int) (_ int, err error) {
+	ctx, span := s.start(ctx, "RunScheduledTransfers")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.RunScheduledTransfers(ctx, now, limit)
+}
+
+func (s PaymentsService) AppendAuditEntry(ctx context.Context, entry entity.AuditEntry) (err error) {
+	ctx, span := s.start(ctx, "AppendAuditEntry")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.AppendAuditEntry(ctx, entry)
+}
+
+func (s PaymentsService) GetAuditLog(ctx context.Context, query service.AuditQuery) (_ []entity.AuditEntry, err error) {
+	ctx, span := s.start(ctx, "GetAuditLog")
+	defer func() {
+		end(span, err)
+	}()
+	return s.next.GetAuditLog(ctx, query)
+}
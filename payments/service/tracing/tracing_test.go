@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/payments/service/memory"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestPaymentsService(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	var svc service.PaymentsService = NewPaymentsService(memory.NewPaymentsService(), tp)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	if err := svc.CreateAccount(ctx, "bob", money.NewNumericFromInt64(100), "USD"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CreateAccount(ctx, "alice", money.NewNumericFromInt64(0), "USD"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := svc.Transfer(ctx, "bob", "alice", money.NewNumericFromInt64(1000), "USD", "")
+	assert.True(t, errors.Is(err, service.ErrInsufficientFunds))
+	parent.End()
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 4) {
+		return
+	}
+	for _, span := range spans[:3] {
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID(), "calls are children of the span in the context")
+	}
+	assert.Equal(t, "PaymentsService.CreateAccount", spans[0].Name())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	transfer := spans[2]
+	assert.Equal(t, "PaymentsService.Transfer", transfer.Name())
+	assert.Equal(t, codes.Error, transfer.Status().Code)
+	assert.Equal(t, service.ErrInsufficientFunds.Error(), transfer.Status().Description)
+	assert.Contains(t, transfer.Attributes(), attribute.String("payment.from", "bob"))
+	assert.Len(t, transfer.Events(), 1, "the error is recorded")
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"net/url"
+	"time"
+)
+
+const (
+	// MaxDeliveryAttempts is how many times a webhook delivery is attempted before it's dead.
+	MaxDeliveryAttempts = 8
+
+	// firstRetryDelay is the delay after the first failed attempt, doubled after each next one.
+	firstRetryDelay = 30 * time.Second
+
+	// maxRetryDelay caps delays between attempts.
+	maxRetryDelay = time.Hour
+
+	// DeliveryLease is how long a delivery being attempted is hidden from other senders.
+	// It has to outlast the attempt, otherwise the delivery may be sent twice.
+	DeliveryLease = time.Minute
+
+	// DefaultDeliveriesLimit is how many deliveries are returned when DeliveriesQuery.Limit is not set.
+	DefaultDeliveriesLimit = 100
+
+	// MaxDeliveriesLimit is the largest number of deliveries returned at once, larger limits are capped.
+	MaxDeliveriesLimit = 1000
+)
+
+// DeliverFunc sends an event of delivery to the webhook, returning an error unless the receiver acknowledged it.
+type DeliverFunc func(ctx context.Context, webhook entity.Webhook, delivery entity.WebhookDelivery) error
+
+// DeliveriesQuery selects webhook deliveries for GetWebhookDeliveries. Zero values mean "no filter".
+type DeliveriesQuery struct {
+	Webhook entity.WebhookID
+
+	Status entity.DeliveryStatus
+
+	// Limit is the largest number of deliveries to return, DefaultDeliveriesLimit if zero
+	Limit int
+}
+
+// Validate returns ErrBadQuery if the query is invalid, and the effective limit otherwise.
+func (q DeliveriesQuery) Validate() (int, error) {
+	if q.Status != "" && !q.Status.IsValid() {
+		return 0, ErrBadQuery
+	}
+	switch {
+	case q.Limit < 0:
+		return 0, ErrBadQuery
+	case q.Limit == 0:
+		return DefaultDeliveriesLimit, nil
+	case q.Limit > MaxDeliveriesLimit:
+		return MaxDeliveriesLimit, nil
+	}
+	return q.Limit, nil
+}
+
+// ValidateWebhook returns ErrBadWebhook unless the webhook has an absolute http(s) URL, a secret and known event types.
+func ValidateWebhook(webhook entity.Webhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrBadWebhook
+	}
+	if webhook.Secret == "" {
+		return ErrBadWebhook
+	}
+	for _, typ := range webhook.EventTypes {
+		if typ != entity.EventAccountCreated && typ != entity.EventPaymentCreated {
+			return ErrBadWebhook
+		}
+	}
+	return nil
+}
+
+// WebhookMatches tells whether event has to be delivered to webhook.
+func WebhookMatches(webhook entity.Webhook, event entity.Event) bool {
+	if len(webhook.EventTypes) > 0 {
+		found := false
+		for _, typ := range webhook.EventTypes {
+			if typ == event.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if webhook.Account == "" {
+		return true
+	}
+	for _, id := range eventAccounts(event) {
+		if id == webhook.Account {
+			return true
+		}
+	}
+	return false
+}
+
+// eventAccounts returns accounts an event concerns.
+func eventAccounts(event entity.Event) []entity.AccountID {
+	switch event.Type {
+	case entity.EventAccountCreated:
+		var payload AccountCreated
+		if json.Unmarshal(event.Payload, &payload) == nil {
+			return []entity.AccountID{payload.Account}
+		}
+	case entity.EventPaymentCreated:
+		var payload PaymentCreated
+		if json.Unmarshal(event.Payload, &payload) == nil {
+			return []entity.AccountID{payload.From, payload.To, payload.FeeAccount}
+		}
+	}
+	return nil
+}
+
+// NewWebhookDelivery returns a pending delivery of event to webhook, due at once.
+func NewWebhookDelivery(webhook entity.WebhookID, event entity.Event, now time.Time) entity.WebhookDelivery {
+	return entity.WebhookDelivery{
+		Webhook:       webhook,
+		Event:         event,
+		Status:        entity.DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
+
+// RecordDeliveryAttempt returns delivery updated with the outcome of an attempt made at now, err being its failure.
+// Failed deliveries are retried with exponential backoff, until MaxDeliveryAttempts is reached and they're dead.
+func RecordDeliveryAttempt(delivery entity.WebhookDelivery, now time.Time, err error) entity.WebhookDelivery {
+	delivery.Attempts++
+	if err == nil {
+		delivery.Status = entity.DeliveryDelivered
+		delivery.DeliveredAt = now
+		delivery.LastError = ""
+		return delivery
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= MaxDeliveryAttempts {
+		delivery.Status = entity.DeliveryDead
+		return delivery
+	}
+	delivery.NextAttemptAt = now.Add(RetryDelay(delivery.Attempts))
+	return delivery
+}
+
+// RetryDelay returns how long to wait after the given number of failed attempts.
+func RetryDelay(attempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// ReplayDelivery returns delivery made pending again with a fresh set of attempts, due at now.
+func ReplayDelivery(delivery entity.WebhookDelivery, now time.Time) entity.WebhookDelivery {
+	delivery.Status = entity.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	delivery.LastError = ""
+	delivery.DeliveredAt = time.Time{}
+	return delivery
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWebhookMatches(t *testing.T) {
+	payment, err := NewPaymentCreatedEvent(1, entity.PaymentValue{
+		From:     "bob",
+		To:       "alice",
+		Amount:   money.NewNumericFromInt64(10),
+		Currency: "USD",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := NewAccountCreatedEvent("clyde", money.NewNumericFromInt64(0), "USD", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		webhook entity.Webhook
+		event   entity.Event
+		want    bool
+	}{
+		{entity.Webhook{}, payment, true},
+		{entity.Webhook{Account: "alice"}, payment, true},
+		{entity.Webhook{Account: "bob"}, payment, true},
+		{entity.Webhook{Account: "clyde"}, payment, false},
+		{entity.Webhook{Account: "clyde"}, account, true},
+		{entity.Webhook{EventTypes: []entity.EventType{entity.EventAccountCreated}}, payment, false},
+		{entity.Webhook{Account: "alice", EventTypes: []entity.EventType{entity.EventPaymentCreated}}, payment, true},
+	}
+	for _, test := range tests {
+		assert.Equal(t, WebhookMatches(test.webhook, test.event), test.want, "%+v", test.webhook)
+	}
+}
+
+func TestRecordDeliveryAttempt(t *testing.T) {
+	now := time.Date(2020, 11, 2, 10, 0, 0, 0, time.UTC)
+	delivery := NewWebhookDelivery(1, entity.Event{Id: 1}, now)
+
+	delivery = RecordDeliveryAttempt(delivery, now, errors.New("unexpected status 500"))
+	assert.Equal(t, delivery.Status, entity.DeliveryPending)
+	assert.Equal(t, delivery.Attempts, 1)
+	assert.Equal(t, delivery.NextAttemptAt, now.Add(30*time.Second))
+	assert.Equal(t, delivery.LastError, "unexpected status 500")
+
+	delivery = RecordDeliveryAttempt(delivery, now, errors.New("unexpected status 500"))
+	assert.Equal(t, delivery.NextAttemptAt, now.Add(time.Minute))
+
+	for delivery.Status == entity.DeliveryPending {
+		delivery = RecordDeliveryAttempt(delivery, now, errors.New("timeout"))
+	}
+	assert.Equal(t, delivery.Status, entity.DeliveryDead)
+	assert.Equal(t, delivery.Attempts, MaxDeliveryAttempts)
+
+	delivery = ReplayDelivery(delivery, now)
+	assert.Equal(t, delivery.Status, entity.DeliveryPending)
+	assert.Zero(t, delivery.Attempts)
+
+	delivery = RecordDeliveryAttempt(delivery, now, nil)
+	assert.Equal(t, delivery.Status, entity.DeliveryDelivered)
+	assert.Equal(t, delivery.DeliveredAt, now)
+	assert.Empty(t, delivery.LastError)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, RetryDelay(1), 30*time.Second)
+	assert.Equal(t, RetryDelay(3), 2*time.Minute)
+	assert.Equal(t, RetryDelay(20), time.Hour)
+}
+
+func TestValidateWebhook(t *testing.T) {
+	valid := entity.Webhook{URL: "https://partner.example/hooks", Secret: "s3cret"}
+	assert.NoError(t, ValidateWebhook(valid))
+
+	for _, webhook := range []entity.Webhook{
+		{URL: "ftp://partner.example/hooks", Secret: "s3cret"},
+		{URL: "/hooks", Secret: "s3cret"},
+		{URL: "https://partner.example/hooks"},
+		{URL: "https://partner.example/hooks", Secret: "s3cret", EventTypes: []entity.EventType{"AccountDeleted"}},
+	} {
+		if err := ValidateWebhook(webhook); !errors.Is(err, ErrBadWebhook) {
+			t.Errorf("expected ErrBadWebhook for %+v, got err=%v", webhook, err)
+		}
+	}
+}
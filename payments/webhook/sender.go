@@ -0,0 +1,166 @@
+// Package webhook delivers events to HTTP endpoints subscribed to them, see entity.Webhook.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/pkg/signature"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultInterval is how often Sender checks for due deliveries unless configured otherwise.
+	DefaultInterval = time.Second
+
+	// DefaultBatchSize is how many deliveries Sender attempts at once unless configured otherwise.
+	DefaultBatchSize = 100
+
+	// DefaultTimeout is how long a receiver may take to acknowledge an event unless configured otherwise.
+	DefaultTimeout = 10 * time.Second
+)
+
+// Headers sent along with every event, in addition to signature.Header.
+const (
+	DeliveryIdHeader = "X-Webhook-Delivery"
+	EventIdHeader    = "X-Event-Id"
+	EventTypeHeader  = "X-Event-Type"
+)
+
+// Publisher is an outbox.Publisher scheduling deliveries of events to webhooks.
+type Publisher struct {
+	svc service.PaymentsService
+}
+
+func NewPublisher(svc service.PaymentsService) *Publisher {
+	return &Publisher{svc: svc}
+}
+
+func (p *Publisher) Publish(ctx context.Context, events []entity.Event) error {
+	return p.svc.EnqueueWebhookDeliveries(ctx, events)
+}
+
+// Sender is a worker sending due deliveries to webhooks.
+type Sender struct {
+	svc    service.PaymentsService
+	client *http.Client
+
+	// interval is how long Sender waits after there's nothing to deliver.
+	interval time.Duration
+
+	// batchSize is how many deliveries are attempted at once.
+	batchSize int
+
+	// now returns current time, replaced in tests.
+	now func() time.Time
+}
+
+// Option configures optional settings of Sender.
+type Option func(s *Sender)
+
+// WithInterval sets how often Sender checks for due deliveries.
+func WithInterval(interval time.Duration) Option {
+	return func(s *Sender) {
+		s.interval = interval
+	}
+}
+
+// WithClient sets HTTP client sending events, which must have a timeout.
+func WithClient(client *http.Client) Option {
+	return func(s *Sender) {
+		s.client = client
+	}
+}
+
+// NewSender returns Sender delivering events of svc.
+func NewSender(svc service.PaymentsService, opts ...Option) *Sender {
+	s := &Sender{
+		svc:       svc,
+		client:    &http.Client{Timeout: DefaultTimeout},
+		interval:  DefaultInterval,
+		batchSize: DefaultBatchSize,
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run sends deliveries until ctx is done. Failures are logged and retried after the interval.
+func (s *Sender) Run(ctx context.Context) {
+	for {
+		n, err := s.DeliverDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Print(fmt.Errorf("failed to deliver webhooks: %w", err))
+		}
+		if n == s.batchSize {
+			// There may be more due deliveries.
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+// DeliverDue makes an attempt of every delivery due now, up to the batch size, and returns the number of attempts.
+func (s *Sender) DeliverDue(ctx context.Context) (int, error) {
+	return s.svc.DeliverWebhooks(ctx, s.now(), s.batchSize, s.send)
+}
+
+// sentEvent is the body of a request, the same for every webhook and attempt.
+type sentEvent struct {
+	Id      entity.EventID   `json:"id"`
+	Time    time.Time        `json:"time"`
+	Type    entity.EventType `json:"type"`
+	Payload json.RawMessage  `json:"payload"`
+}
+
+// send POSTs the event to the webhook, any 2xx response acknowledges it.
+func (s *Sender) send(ctx context.Context, webhook entity.Webhook, delivery entity.WebhookDelivery) error {
+	body, err := json.Marshal(sentEvent{
+		Id:      delivery.Event.Id,
+		Time:    delivery.Event.Time,
+		Type:    delivery.Event.Type,
+		Payload: delivery.Event.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set(signature.Header, signature.Sign(webhook.Secret, s.now(), body))
+	req.Header.Set(DeliveryIdHeader, strconv.FormatInt(int64(delivery.Id), 10))
+	req.Header.Set(EventIdHeader, strconv.FormatInt(int64(delivery.Event.Id), 10))
+	req.Header.Set(EventTypeHeader, string(delivery.Event.Type))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain a bit of the body, so the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
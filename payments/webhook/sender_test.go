@@ -0,0 +1,162 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/lightsgoout/fintech-go/payments/entity"
+	"github.com/lightsgoout/fintech-go/payments/outbox"
+	"github.com/lightsgoout/fintech-go/payments/service"
+	"github.com/lightsgoout/fintech-go/payments/service/memory"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"github.com/lightsgoout/fintech-go/pkg/signature"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver is a webhook endpoint verifying signatures of events it receives.
+type receiver struct {
+	t      *testing.T
+	secret string
+
+	mu     sync.Mutex
+	status int
+	events []sentEvent
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	err := signature.Verify(r.secret, req.Header.Get(signature.Header), body, time.Now(), 5*time.Minute)
+	if err != nil {
+		r.t.Errorf("bad signature: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status != http.StatusOK {
+		w.WriteHeader(r.status)
+		return
+	}
+	var event sentEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		r.t.Errorf("bad body: %v", err)
+	}
+	assert.Equal(r.t, req.Header.Get(EventTypeHeader), string(event.Type))
+	r.events = append(r.events, event)
+}
+
+func (r *receiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *receiver) received() []sentEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]sentEvent(nil), r.events...)
+}
+
+func TestSender(t *testing.T) {
+	ctx := context.Background()
+	svc := memory.NewPaymentsService()
+	for _, id := range [...]entity.AccountID{"bob", "alice"} {
+		if err := svc.CreateAccount(ctx, id, money.NewNumericFromInt64(100), "USD"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rcv := &receiver{t: t, secret: "s3cret", status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	webhook, err := svc.CreateWebhook(ctx, entity.Webhook{
+		URL:        srv.URL,
+		Secret:     rcv.secret,
+		Account:    "alice",
+		EventTypes: []entity.EventType{entity.EventPaymentCreated},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Transfer(ctx, "bob", "alice", money.NewNumericFromInt64(10), "USD", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	relay := outbox.NewRelay(svc, NewPublisher(svc))
+	if _, err := relay.RelayPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	sender := NewSender(svc)
+	sender.now = func() time.Time { return now }
+
+	t.Run("failed delivery is retried with backoff", func(t *testing.T) {
+		n, err := sender.DeliverDue(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, n, 1)
+		assert.Empty(t, rcv.received())
+
+		deliveries, err := svc.GetWebhookDeliveries(ctx, service.DeliveriesQuery{Webhook: webhook.Id})
+		assert.NoError(t, err)
+		if assert.Len(t, deliveries, 1) {
+			assert.Equal(t, deliveries[0].Status, entity.DeliveryPending)
+			assert.Equal(t, deliveries[0].LastError, "unexpected status 503")
+			assert.Equal(t, deliveries[0].NextAttemptAt, now.Add(service.RetryDelay(1)))
+		}
+
+		n, err = sender.DeliverDue(ctx)
+		assert.NoError(t, err)
+		assert.Zero(t, n, "the next attempt isn't due yet")
+	})
+
+	t.Run("signed event is delivered", func(t *testing.T) {
+		rcv.setStatus(http.StatusOK)
+		now = now.Add(service.RetryDelay(1))
+
+		n, err := sender.DeliverDue(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, n, 1)
+
+		events := rcv.received()
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, events[0].Type, entity.EventPaymentCreated)
+			var payload service.PaymentCreated
+			if assert.NoError(t, json.Unmarshal(events[0].Payload, &payload)) {
+				assert.Equal(t, payload.To, entity.AccountID("alice"))
+				assert.Equal(t, payload.Amount.String(), "10")
+			}
+		}
+
+		deliveries, err := svc.GetWebhookDeliveries(ctx, service.DeliveriesQuery{Status: entity.DeliveryDelivered})
+		assert.NoError(t, err)
+		if assert.Len(t, deliveries, 1) {
+			assert.Equal(t, deliveries[0].Attempts, 2)
+		}
+	})
+
+	t.Run("replayed event is delivered again", func(t *testing.T) {
+		deliveries, err := svc.GetWebhookDeliveries(ctx, service.DeliveriesQuery{Webhook: webhook.Id})
+		if err != nil || len(deliveries) != 1 {
+			t.Fatalf("expected a single delivery, got %v, err=%v", deliveries, err)
+		}
+		if _, err := svc.ReplayWebhookDelivery(ctx, deliveries[0].Id, now); err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := sender.DeliverDue(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, n, 1)
+		events := rcv.received()
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, events[0].Id, events[1].Id, "receivers deduplicate events by id")
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package fee
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"io/ioutil"
+)
+
+// Schedule decides how much senders pay for transfers.
+type Schedule interface {
+	// Fee returns a fee for transferring amount of the given currency, zero if such transfers are free.
+	// The fee is not rounded to currency minor units.
+	Fee(amount money.Numeric, cur money.Currency) money.Numeric
+}
+
+// Tier overrides fees of a Rule for amounts of at least From.
+type Tier struct {
+	From    money.Numeric `json:"from"`
+	Flat    money.Numeric `json:"flat"`
+	Percent money.Numeric `json:"percent"`
+}
+
+// Rule describes fees of transfers in a single currency: Flat plus Percent of the amount,
+// limited by Min and Max if set. Tiers, if any, replace Flat and Percent for large enough amounts.
+type Rule struct {
+	Currency money.Currency `json:"currency"`
+	Flat     money.Numeric  `json:"flat"`
+	Percent  money.Numeric  `json:"percent"`
+	Min      *money.Numeric `json:"min"`
+	Max      *money.Numeric `json:"max"`
+
+	// Tiers must be in ascending order of From, the last one not exceeding the amount applies
+	Tiers []Tier `json:"tiers"`
+}
+
+func (r Rule) validate() error {
+	zero, hundred := money.NewNumericFromInt64(0), money.NewNumericFromInt64(100)
+	if r.Currency == "" {
+		return fmt.Errorf("missing currency")
+	}
+	if r.Flat.LessThan(zero) || r.Percent.LessThan(zero) || hundred.LessThan(r.Percent) {
+		return fmt.Errorf("bad fee %s + %s%%", r.Flat, r.Percent)
+	}
+	if r.Min != nil && r.Min.LessThan(zero) {
+		return fmt.Errorf("bad min %s", r.Min)
+	}
+	if r.Max != nil && r.Max.LessThan(zero) {
+		return fmt.Errorf("bad max %s", r.Max)
+	}
+	if r.Min != nil && r.Max != nil && r.Max.LessThan(*r.Min) {
+		return fmt.Errorf("min %s exceeds max %s", r.Min, r.Max)
+	}
+	for i, tier := range r.Tiers {
+		if tier.From.LessThan(zero) || tier.Flat.LessThan(zero) || tier.Percent.LessThan(zero) || hundred.LessThan(tier.Percent) {
+			return fmt.Errorf("bad tier from %s: %s + %s%%", tier.From, tier.Flat, tier.Percent)
+		}
+		if i > 0 && !r.Tiers[i-1].From.LessThan(tier.From) {
+			return fmt.Errorf("tiers are not in ascending order")
+		}
+	}
+	return nil
+}
+
+func (r Rule) fee(amount money.Numeric) money.Numeric {
+	flat, percent := r.Flat, r.Percent
+	for _, tier := range r.Tiers {
+		if amount.LessThan(tier.From) {
+			break
+		}
+		flat, percent = tier.Flat, tier.Percent
+	}
+
+	fee := flat.Add(amount.Mul(percent).Mul(money.NewNumericFromStringMust("0.01")))
+	if r.Min != nil && fee.LessThan(*r.Min) {
+		fee = *r.Min
+	}
+	if r.Max != nil && r.Max.LessThan(fee) {
+		fee = *r.Max
+	}
+	return fee
+}
+
+// StaticSchedule charges fees according to a fixed set of rules, transfers in other currencies are free.
+type StaticSchedule struct {
+	rules map[money.Currency]Rule
+}
+
+// NewStaticSchedule returns schedule charging fees by given rules, at most one per currency.
+func NewStaticSchedule(rules ...Rule) (*StaticSchedule, error) {
+	s := &StaticSchedule{
+		rules: make(map[money.Currency]Rule, len(rules)),
+	}
+	for _, r := range rules {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("bad fee rule for %q: %w", r.Currency, err)
+		}
+		if _, exists := s.rules[r.Currency]; exists {
+			return nil, fmt.Errorf("duplicate fee rule for %s", r.Currency)
+		}
+		s.rules[r.Currency] = r
+	}
+	return s, nil
+}
+
+// LoadStaticSchedule reads rules from a JSON file containing an array of Rule.
+func LoadStaticSchedule(path string) (*StaticSchedule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse fee rules from %s: %w", path, err)
+	}
+	return NewStaticSchedule(rules...)
+}
+
+func (s *StaticSchedule) Fee(amount money.Numeric, cur money.Currency) money.Numeric {
+	rule, ok := s.rules[cur]
+	if !ok {
+		return money.NewNumericFromInt64(0)
+	}
+	return rule.fee(amount)
+}
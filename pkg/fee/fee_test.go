@@ -0,0 +1,90 @@
+package fee
+
+import (
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func numeric(s string) *money.Numeric {
+	n := money.NewNumericFromStringMust(s)
+	return &n
+}
+
+func TestStaticSchedule_Fee(t *testing.T) {
+	s, err := NewStaticSchedule(
+		Rule{Currency: "USD", Flat: *numeric("0.30"), Percent: *numeric("2.9")},
+		Rule{Currency: "EUR", Percent: *numeric("1"), Min: numeric("0.5"), Max: numeric("10")},
+		Rule{
+			Currency: "RUB",
+			Percent:  *numeric("2"),
+			Tiers: []Tier{
+				{From: *numeric("1000"), Percent: *numeric("1")},
+				{From: *numeric("10000"), Flat: *numeric("50")},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		amount string
+		cur    money.Currency
+		want   string
+	}{
+		{"100", "USD", "3.2"},
+		{"10", "EUR", "0.5"},
+		{"100", "EUR", "1"},
+		{"5000", "EUR", "10"},
+		{"999", "RUB", "19.98"},
+		{"1000", "RUB", "10"},
+		{"20000", "RUB", "50"},
+		{"100", "GBP", "0"},
+	}
+	for _, test := range tests {
+		got := s.Fee(*numeric(test.amount), test.cur)
+		if !got.Equal(*numeric(test.want)) {
+			t.Errorf("TestStaticSchedule_Fee(%s %s) got %s, want %s", test.amount, test.cur, got, test.want)
+		}
+	}
+}
+
+func TestNewStaticSchedule(t *testing.T) {
+	tests := [][]Rule{
+		{{Flat: *numeric("1")}},
+		{{Currency: "USD", Flat: *numeric("-1")}},
+		{{Currency: "USD", Percent: *numeric("101")}},
+		{{Currency: "USD", Min: numeric("2"), Max: numeric("1")}},
+		{{Currency: "USD", Tiers: []Tier{{From: *numeric("10")}, {From: *numeric("5")}}}},
+		{{Currency: "USD"}, {Currency: "USD"}},
+	}
+	for _, test := range tests {
+		if _, err := NewStaticSchedule(test...); err == nil {
+			t.Errorf("TestNewStaticSchedule expected error for %v", test)
+		}
+	}
+}
+
+func TestLoadStaticSchedule(t *testing.T) {
+	f, err := ioutil.TempFile("", "fees*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`[{"currency":"USD","flat":"0.3","percent":"2.9","max":"5"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	s, err := LoadStaticSchedule(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee := s.Fee(*numeric("1000"), "USD")
+	if fee.String() != "5" {
+		t.Errorf("TestLoadStaticSchedule got %s, want 5", fee)
+	}
+}
@@ -0,0 +1,75 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"io/ioutil"
+)
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// RateProvider is a source of currency exchange rates.
+type RateProvider interface {
+	// Rate returns how many units of currency to one unit of currency from buys.
+	// Returns ErrRateNotFound if currencies can't be exchanged.
+	Rate(ctx context.Context, from, to money.Currency) (money.Numeric, error)
+}
+
+// Rate is an exchange rate of a currency pair.
+type Rate struct {
+	From money.Currency `json:"from"`
+	To   money.Currency `json:"to"`
+	Rate money.Numeric  `json:"rate"`
+}
+
+type pair struct {
+	from, to money.Currency
+}
+
+// StaticRateProvider serves a fixed set of rates, useful in tests and local development.
+//
+// NOTE: inverse rates are not derived automatically, each direction must be listed explicitly.
+type StaticRateProvider struct {
+	rates map[pair]money.Numeric
+}
+
+// NewStaticRateProvider returns provider serving given rates, which must be positive.
+func NewStaticRateProvider(rates ...Rate) (*StaticRateProvider, error) {
+	p := &StaticRateProvider{
+		rates: make(map[pair]money.Numeric, len(rates)),
+	}
+	for _, r := range rates {
+		if !r.Rate.IsPositive() {
+			return nil, fmt.Errorf("bad rate %s for %s/%s", r.Rate, r.From, r.To)
+		}
+		if r.From == r.To {
+			return nil, fmt.Errorf("bad currency pair %s/%s", r.From, r.To)
+		}
+		p.rates[pair{r.From, r.To}] = r.Rate
+	}
+	return p, nil
+}
+
+// LoadStaticRateProvider reads rates from a JSON file containing an array of Rate.
+func LoadStaticRateProvider(path string) (*StaticRateProvider, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rates []Rate
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("failed to parse rates from %s: %w", path, err)
+	}
+	return NewStaticRateProvider(rates...)
+}
+
+func (p *StaticRateProvider) Rate(_ context.Context, from, to money.Currency) (money.Numeric, error) {
+	rate, ok := p.rates[pair{from, to}]
+	if !ok {
+		return money.Numeric{}, ErrRateNotFound
+	}
+	return rate, nil
+}
@@ -0,0 +1,68 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"github.com/lightsgoout/fintech-go/pkg/money"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestStaticRateProvider_Rate(t *testing.T) {
+	p, err := NewStaticRateProvider(Rate{From: "USD", To: "EUR", Rate: money.NewNumericFromStringMust("0.92")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rate, err := p.Rate(context.Background(), "USD", "EUR")
+	if err != nil {
+		t.Error(err)
+	}
+	if !rate.Equal(money.NewNumericFromStringMust("0.92")) {
+		t.Errorf("TestStaticRateProvider_Rate got %s, want 0.92", rate)
+	}
+
+	_, err = p.Rate(context.Background(), "EUR", "USD")
+	if !errors.Is(err, ErrRateNotFound) {
+		t.Errorf("expected ErrRateNotFound, got err=%v", err)
+	}
+}
+
+func TestNewStaticRateProvider(t *testing.T) {
+	tests := []Rate{
+		{From: "USD", To: "EUR", Rate: money.NewNumericFromInt64(0)},
+		{From: "USD", To: "EUR", Rate: money.NewNumericFromInt64(-1)},
+		{From: "USD", To: "USD", Rate: money.NewNumericFromInt64(1)},
+	}
+	for _, test := range tests {
+		if _, err := NewStaticRateProvider(test); err == nil {
+			t.Errorf("TestNewStaticRateProvider expected error for %v", test)
+		}
+	}
+}
+
+func TestLoadStaticRateProvider(t *testing.T) {
+	f, err := ioutil.TempFile("", "rates*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`[{"from":"USD","to":"RUB","rate":"76.4321"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	p, err := LoadStaticRateProvider(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rate, err := p.Rate(context.Background(), "USD", "RUB")
+	if err != nil {
+		t.Error(err)
+	}
+	if rate.String() != "76.4321" {
+		t.Errorf("TestLoadStaticRateProvider got %s, want 76.4321", rate)
+	}
+}
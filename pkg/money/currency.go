@@ -1,6 +1,13 @@
 package money
 
-import "strings"
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+)
 
 type Currency string
 
@@ -8,14 +15,111 @@ func NewCurrency(raw string) Currency {
 	return Currency(strings.ToUpper(raw))
 }
 
+// IsKnownCurrency reports whether cur is present in DefaultCurrencyRegistry.
 func IsKnownCurrency(cur Currency) bool {
-	switch cur {
-	case "USD":
-		return true
-	case "EUR":
-		return true
-	case "RUB":
-		return true
+	return DefaultCurrencyRegistry.IsKnown(cur)
+}
+
+// CurrencyInfo describes a currency as defined by ISO 4217.
+type CurrencyInfo struct {
+	// Code is an alphabetic code, e.g. USD
+	Code Currency `json:"code"`
+
+	// NumericCode is a numeric code, e.g. 840 for USD
+	NumericCode int `json:"numeric_code"`
+
+	// MinorUnits is how many fractional digits amounts may have, e.g. 2 for cents
+	MinorUnits int32 `json:"minor_units"`
+
+	// Symbol is used for display purposes only, e.g. $
+	Symbol string `json:"symbol"`
+}
+
+var currencyCodeRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+
+func (c CurrencyInfo) validate() error {
+	if !currencyCodeRegexp.MatchString(string(c.Code)) {
+		return fmt.Errorf("bad currency code %q", c.Code)
+	}
+	if c.NumericCode <= 0 || c.NumericCode > 999 {
+		return fmt.Errorf("bad numeric code %d of currency %s", c.NumericCode, c.Code)
+	}
+	if c.MinorUnits < 0 {
+		return fmt.Errorf("bad minor units %d of currency %s", c.MinorUnits, c.Code)
+	}
+	return nil
+}
+
+// CurrencyRegistry is a set of currencies the system is allowed to operate with.
+type CurrencyRegistry struct {
+	currencies map[Currency]CurrencyInfo
+}
+
+// NewCurrencyRegistry returns a registry of given currencies, checking they're well-formed and unique.
+func NewCurrencyRegistry(currencies ...CurrencyInfo) (*CurrencyRegistry, error) {
+	r := &CurrencyRegistry{
+		currencies: make(map[Currency]CurrencyInfo, len(currencies)),
+	}
+	for _, c := range currencies {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := r.currencies[c.Code]; ok {
+			return nil, fmt.Errorf("duplicate currency %s", c.Code)
+		}
+		r.currencies[c.Code] = c
 	}
-	return false
+	return r, nil
 }
+
+// MustNewCurrencyRegistry is like NewCurrencyRegistry but panics on malformed currencies.
+func MustNewCurrencyRegistry(currencies ...CurrencyInfo) *CurrencyRegistry {
+	r, err := NewCurrencyRegistry(currencies...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// LoadCurrencyRegistry reads a registry from a JSON file containing an array of CurrencyInfo.
+func LoadCurrencyRegistry(path string) (*CurrencyRegistry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var currencies []CurrencyInfo
+	if err := json.Unmarshal(data, &currencies); err != nil {
+		return nil, fmt.Errorf("failed to parse currencies from %s: %w", path, err)
+	}
+	return NewCurrencyRegistry(currencies...)
+}
+
+// Lookup returns description of a currency if it's known.
+func (r *CurrencyRegistry) Lookup(cur Currency) (CurrencyInfo, bool) {
+	c, ok := r.currencies[cur]
+	return c, ok
+}
+
+func (r *CurrencyRegistry) IsKnown(cur Currency) bool {
+	_, ok := r.currencies[cur]
+	return ok
+}
+
+// Currencies returns all known currencies ordered by code.
+func (r *CurrencyRegistry) Currencies() []CurrencyInfo {
+	result := make([]CurrencyInfo, 0, len(r.currencies))
+	for _, c := range r.currencies {
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Code < result[j].Code
+	})
+	return result
+}
+
+// DefaultCurrencyRegistry contains currencies supported out of the box.
+var DefaultCurrencyRegistry = MustNewCurrencyRegistry(
+	CurrencyInfo{Code: "USD", NumericCode: 840, MinorUnits: 2, Symbol: "$"},
+	CurrencyInfo{Code: "EUR", NumericCode: 978, MinorUnits: 2, Symbol: "€"},
+	CurrencyInfo{Code: "RUB", NumericCode: 643, MinorUnits: 2, Symbol: "₽"},
+)
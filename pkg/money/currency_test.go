@@ -1,6 +1,10 @@
 package money
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
 
 func TestIsKnownCurrency(t *testing.T) {
 	tests := []struct {
@@ -19,6 +23,10 @@ func TestIsKnownCurrency(t *testing.T) {
 			in:  "RUB",
 			out: true,
 		},
+		{
+			in:  "UAH",
+			out: false,
+		},
 	}
 	for _, test := range tests {
 		res := IsKnownCurrency(test.in)
@@ -27,3 +35,75 @@ func TestIsKnownCurrency(t *testing.T) {
 		}
 	}
 }
+
+func TestNewCurrencyRegistry(t *testing.T) {
+	tests := []struct {
+		in []CurrencyInfo
+		ok bool
+	}{
+		{
+			in: []CurrencyInfo{{Code: "GBP", NumericCode: 826, MinorUnits: 2, Symbol: "£"}},
+			ok: true,
+		},
+		{
+			in: []CurrencyInfo{{Code: "JPY", NumericCode: 392, MinorUnits: 0, Symbol: "¥"}},
+			ok: true,
+		},
+		{
+			in: []CurrencyInfo{{Code: "usd", NumericCode: 840, MinorUnits: 2}},
+			ok: false,
+		},
+		{
+			in: []CurrencyInfo{{Code: "USD", NumericCode: 0, MinorUnits: 2}},
+			ok: false,
+		},
+		{
+			in: []CurrencyInfo{{Code: "USD", NumericCode: 840, MinorUnits: -1}},
+			ok: false,
+		},
+		{
+			in: []CurrencyInfo{
+				{Code: "USD", NumericCode: 840, MinorUnits: 2},
+				{Code: "USD", NumericCode: 840, MinorUnits: 2},
+			},
+			ok: false,
+		},
+	}
+	for _, test := range tests {
+		_, err := NewCurrencyRegistry(test.in...)
+		if (err == nil) != test.ok {
+			t.Errorf("TestNewCurrencyRegistry %v got err=%v, want ok=%t", test.in, err, test.ok)
+		}
+	}
+}
+
+func TestLoadCurrencyRegistry(t *testing.T) {
+	f, err := ioutil.TempFile("", "currencies*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`[{"code":"JPY","numeric_code":392,"minor_units":0,"symbol":"¥"},{"code":"USD","numeric_code":840,"minor_units":2,"symbol":"$"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	r, err := LoadCurrencyRegistry(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	jpy, ok := r.Lookup("JPY")
+	if !ok {
+		t.Fatal("TestLoadCurrencyRegistry JPY not found")
+	}
+	if jpy.NumericCode != 392 || jpy.MinorUnits != 0 || jpy.Symbol != "¥" {
+		t.Errorf("TestLoadCurrencyRegistry got %+v", jpy)
+	}
+	if r.IsKnown("EUR") {
+		t.Error("TestLoadCurrencyRegistry EUR must not be known")
+	}
+	if codes := r.Currencies(); len(codes) != 2 || codes[0].Code != "JPY" || codes[1].Code != "USD" {
+		t.Errorf("TestLoadCurrencyRegistry got currencies %v", codes)
+	}
+}
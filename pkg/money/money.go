@@ -1,6 +1,11 @@
 package money
 
-import "github.com/shopspring/decimal"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+)
 
 type Numeric struct {
 	value decimal.Decimal
@@ -18,24 +23,92 @@ func (n Numeric) Add(a Numeric) Numeric {
 	}
 }
 
+func (n Numeric) Neg() Numeric {
+	return Numeric{
+		value: n.value.Neg(),
+	}
+}
+
+func (n Numeric) Mul(a Numeric) Numeric {
+	return Numeric{
+		value: n.value.Mul(a.value),
+	}
+}
+
+// Round rounds n to the given number of decimal places, half away from zero.
+func (n Numeric) Round(places int32) Numeric {
+	return Numeric{
+		value: n.value.Round(places),
+	}
+}
+
 func (n Numeric) LessThan(a Numeric) bool {
 	return n.value.LessThan(a.value)
 }
 
+func (n Numeric) Equal(a Numeric) bool {
+	return n.value.Equal(a.value)
+}
+
+func (n Numeric) IsPositive() bool {
+	return n.value.IsPositive()
+}
+
+// FitsDecimalPlaces reports whether n has no more significant fractional digits than places,
+// e.g. 1.50 fits 2 decimal places, while 1.505 does not.
+func (n Numeric) FitsDecimalPlaces(places int32) bool {
+	return n.value.Equal(n.value.Truncate(places))
+}
+
+// Float64 returns the nearest float64 value of n, which is only good for metrics and never for calculations.
+func (n Numeric) Float64() float64 {
+	f, _ := n.value.Float64()
+	return f
+}
+
 func (n Numeric) String() string {
 	return n.value.String()
 }
 
+// MarshalJSON implements json.Marshaler. Numeric is always encoded as a string to not lose precision.
+func (n Numeric) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Both strings ("10.05") and numbers (10.05) are accepted,
+// numbers are parsed from their textual representation, so no float rounding is involved.
+func (n *Numeric) UnmarshalJSON(data []byte) error {
+	return n.value.UnmarshalJSON(data)
+}
+
+// Scan implements sql.Scanner.
+func (n *Numeric) Scan(value interface{}) error {
+	if value == nil {
+		return fmt.Errorf("money: cannot scan NULL into Numeric")
+	}
+	return n.value.Scan(value)
+}
+
+// Value implements driver.Valuer.
+func (n Numeric) Value() (driver.Value, error) {
+	return n.String(), nil
+}
+
 func NewNumericFromInt64(value int64) Numeric {
 	return Numeric{
 		value: decimal.NewFromInt(value),
 	}
 }
 
-func NewNumericFromFloat32(value float32) Numeric {
-	return Numeric{
-		value: decimal.NewFromFloat32(value),
+// NewNumericFromString parses decimal representation of a number, e.g. "100.05".
+func NewNumericFromString(value string) (Numeric, error) {
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return Numeric{}, err
 	}
+	return Numeric{
+		value: d,
+	}, nil
 }
 
 func NewNumericFromStringMust(value string) Numeric {
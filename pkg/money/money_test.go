@@ -1 +1,149 @@
 package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNumeric_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{
+			in:  `"0.1"`,
+			out: "0.1",
+		},
+		{
+			in:  `0.1`,
+			out: "0.1",
+		},
+		{
+			in:  `"123456789012345678901234567890.12"`,
+			out: "123456789012345678901234567890.12",
+		},
+		{
+			in:  `123456789012345678901234567890.12`,
+			out: "123456789012345678901234567890.12",
+		},
+	}
+	for _, test := range tests {
+		var n Numeric
+		if err := json.Unmarshal([]byte(test.in), &n); err != nil {
+			t.Errorf("TestNumeric_UnmarshalJSON %s: unexpected err %v", test.in, err)
+			continue
+		}
+		if n.String() != test.out {
+			t.Errorf("TestNumeric_UnmarshalJSON got %s, want %s", n.String(), test.out)
+		}
+	}
+
+	var n Numeric
+	if err := json.Unmarshal([]byte(`"abc"`), &n); err == nil {
+		t.Error("TestNumeric_UnmarshalJSON expected error for malformed number")
+	}
+}
+
+func TestNumeric_MarshalJSON(t *testing.T) {
+	res, err := json.Marshal(NewNumericFromStringMust("100.05"))
+	if err != nil {
+		t.Error(err)
+	}
+	if string(res) != `"100.05"` {
+		t.Errorf("TestNumeric_MarshalJSON got %s, want %s", res, `"100.05"`)
+	}
+}
+
+func TestNumeric_FitsDecimalPlaces(t *testing.T) {
+	tests := []struct {
+		in     string
+		places int32
+		out    bool
+	}{
+		{
+			in:     "10",
+			places: 0,
+			out:    true,
+		},
+		{
+			in:     "10.5",
+			places: 0,
+			out:    false,
+		},
+		{
+			in:     "10.50",
+			places: 2,
+			out:    true,
+		},
+		{
+			in:     "10.5000",
+			places: 2,
+			out:    true,
+		},
+		{
+			in:     "10.505",
+			places: 2,
+			out:    false,
+		},
+	}
+	for _, test := range tests {
+		res := NewNumericFromStringMust(test.in).FitsDecimalPlaces(test.places)
+		if res != test.out {
+			t.Errorf("TestNumeric_FitsDecimalPlaces %s/%d got %t, want %t", test.in, test.places, res, test.out)
+		}
+	}
+}
+
+func TestNumeric_Scan(t *testing.T) {
+	var n Numeric
+	if err := n.Scan([]byte("42.42")); err != nil {
+		t.Error(err)
+	}
+	if !n.Equal(NewNumericFromStringMust("42.42")) {
+		t.Errorf("TestNumeric_Scan got %s, want 42.42", n)
+	}
+	if err := n.Scan(nil); err == nil {
+		t.Error("TestNumeric_Scan expected error for NULL")
+	}
+
+	v, err := n.Value()
+	if err != nil {
+		t.Error(err)
+	}
+	if v != "42.42" {
+		t.Errorf("TestNumeric_Value got %v, want 42.42", v)
+	}
+}
+
+func TestNumeric_MulRound(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		places int32
+		out    string
+	}{
+		{
+			a:      "10.01",
+			b:      "0.9",
+			places: 2,
+			out:    "9.01",
+		},
+		{
+			a:      "100",
+			b:      "76.4321",
+			places: 2,
+			out:    "7643.21",
+		},
+		{
+			a:      "1.5",
+			b:      "1",
+			places: 0,
+			out:    "2",
+		},
+	}
+	for _, test := range tests {
+		res := NewNumericFromStringMust(test.a).Mul(NewNumericFromStringMust(test.b)).Round(test.places)
+		if res.String() != test.out {
+			t.Errorf("TestNumeric_MulRound %s*%s got %s, want %s", test.a, test.b, res, test.out)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Package migrate applies versioned SQL migrations to Postgres.
+//
+// Migrations are files named NNNN_name.up.sql and NNNN_name.down.sql, where NNNN is a version number.
+// Applied versions are recorded in schema_migrations table. Every migration runs in its own transaction
+// holding an advisory lock, so several instances starting at once don't apply the same migration twice.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"github.com/lightsgoout/fintech-go/pkg/postgres"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migration is a single versioned change of the schema.
+type Migration struct {
+	Version int64
+	Name    string
+
+	// Up applies the migration
+	Up string
+
+	// Down reverts the migration, empty if the migration is irreversible
+	Down string
+}
+
+// AppliedMigration is a migration recorded in schema_migrations table.
+type AppliedMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+var fileNameRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Load reads migrations from the root of fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		parts := fileNameRegexp.FindStringSubmatch(f.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("unexpected migration file name %q", f.Name())
+		}
+		version, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("bad version of migration %q", f.Name())
+		}
+		sql, err := fs.ReadFile(fsys, f.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = m
+		}
+		if m.Name != parts[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, parts[2])
+		}
+		if parts[3] == "up" {
+			m.Up = string(sql)
+		} else {
+			m.Down = string(sql)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrator applies and reverts migrations.
+type Migrator struct {
+	db         postgres.Database
+	migrations []Migration
+}
+
+// NewMigrator returns Migrator of migrations found in fsys, see Load.
+func NewMigrator(db postgres.Database, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Migrations returns all known migrations, ordered by version.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up applies all pending migrations in order and returns the ones it has applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	for _, migration := range m.migrations {
+		migration := migration
+		done := false
+		err := m.locked(ctx, func(tx postgres.Database, versions map[int64]bool) error {
+			if versions[migration.Version] {
+				return nil
+			}
+			if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+				return err
+			}
+			const sql = `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, now())`
+			if _, err := tx.ExecContext(ctx, sql, migration.Version, migration.Name); err != nil {
+				return err
+			}
+			done = true
+			return nil
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if done {
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+// Baseline records migrations up to version as applied without running them, and returns the ones it has recorded.
+// It adopts a database which schema was created otherwise, e.g. by a script the migrations replaced,
+// so Up applies only migrations following version.
+func (m *Migrator) Baseline(ctx context.Context, version int64) ([]Migration, error) {
+	known := false
+	for _, migration := range m.migrations {
+		known = known || migration.Version == version
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown migration version %d", version)
+	}
+
+	var recorded []Migration
+	err := m.locked(ctx, func(tx postgres.Database, versions map[int64]bool) error {
+		for _, migration := range m.migrations {
+			if migration.Version > version || versions[migration.Version] {
+				continue
+			}
+			const sql = `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, now())`
+			if _, err := tx.ExecContext(ctx, sql, migration.Version, migration.Name); err != nil {
+				return err
+			}
+			recorded = append(recorded, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recorded, nil
+}
+
+// Down reverts the latest applied migration and returns it, or returns false if nothing is applied.
+func (m *Migrator) Down(ctx context.Context) (Migration, bool, error) {
+	var (
+		reverted Migration
+		found    bool
+	)
+	err := m.locked(ctx, func(tx postgres.Database, versions map[int64]bool) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if versions[m.migrations[i].Version] {
+				reverted, found = m.migrations[i], true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+		if reverted.Down == "" {
+			return fmt.Errorf("migration %d_%s is irreversible", reverted.Version, reverted.Name)
+		}
+		if _, err := tx.ExecContext(ctx, reverted.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, reverted.Version)
+		return err
+	})
+	if err != nil {
+		return Migration{}, false, err
+	}
+	return reverted, found, nil
+}
+
+// Applied returns migrations recorded as applied, ordered by version.
+func (m *Migrator) Applied(ctx context.Context) ([]AppliedMigration, error) {
+	var exists struct {
+		Exists bool `sql:"exists"`
+	}
+	_, err := m.db.QueryOneContext(ctx, &exists, `SELECT to_regclass('schema_migrations') IS NOT NULL as exists`)
+	if err != nil || !exists.Exists {
+		return nil, err
+	}
+
+	var rows []struct {
+		Version   int64     `sql:"version"`
+		Name      string    `sql:"name"`
+		AppliedAt time.Time `sql:"applied_at"`
+	}
+	_, err = m.db.QueryContext(ctx, &rows, `SELECT version, name, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	applied := make([]AppliedMigration, 0, len(rows))
+	for _, r := range rows {
+		applied = append(applied, AppliedMigration(r))
+	}
+	return applied, nil
+}
+
+// Pending returns migrations which are not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[int64]bool, len(applied))
+	for _, a := range applied {
+		versions[a.Version] = true
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if !versions[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// locked runs f in a transaction holding the migrations lock, passing versions applied so far.
+func (m *Migrator) locked(ctx context.Context, f func(tx postgres.Database, versions map[int64]bool) error) error {
+	return postgres.NestedRunInTransaction(ctx, m.db, func(tx postgres.Database) error {
+		// The lock is released automatically when the transaction ends.
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('schema_migrations'))`); err != nil {
+			return err
+		}
+		const sql = `CREATE TABLE IF NOT EXISTS schema_migrations
+			(
+				version    bigint PRIMARY KEY,
+				name       text                     not null,
+				applied_at timestamp with time zone not null
+			)`
+		if _, err := tx.ExecContext(ctx, sql); err != nil {
+			return err
+		}
+
+		var rows []int64
+		if _, err := tx.QueryContext(ctx, &rows, `SELECT version FROM schema_migrations`); err != nil {
+			return err
+		}
+		versions := make(map[int64]bool, len(rows))
+		for _, v := range rows {
+			versions[v] = true
+		}
+		return f(tx, versions)
+	})
+}
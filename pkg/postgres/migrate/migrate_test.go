@@ -0,0 +1,46 @@
+package migrate
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"0002_add_column.up.sql":   {Data: []byte("alter table a add column b text;")},
+		"0001_init.up.sql":         {Data: []byte("create table a (id int);")},
+		"0001_init.down.sql":       {Data: []byte("drop table a;")},
+		"0010_irreversible.up.sql": {Data: []byte("delete from a;")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, migrations, []Migration{
+		{Version: 1, Name: "init", Up: "create table a (id int);", Down: "drop table a;"},
+		{Version: 2, Name: "add_column", Up: "alter table a add column b text;"},
+		{Version: 10, Name: "irreversible", Up: "delete from a;"},
+	})
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"bad file name":     {"init.sql": {Data: []byte("select 1;")}},
+		"zero version":      {"0000_init.up.sql": {Data: []byte("select 1;")}},
+		"no up file":        {"0001_init.down.sql": {Data: []byte("select 1;")}},
+		"conflicting names": {"0001_init.up.sql": {Data: []byte("select 1;")}, "0001_other.down.sql": {Data: []byte("select 1;")}},
+	} {
+		_, err := Load(fsys)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestMigrator_BaselineUnknownVersion(t *testing.T) {
+	migrator, err := NewMigrator(nil, fstest.MapFS{
+		"0001_init.up.sql": {Data: []byte("create table a (id int);")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Baseline(context.Background(), 2)
+	assert.EqualError(t, err, "unknown migration version 2")
+}
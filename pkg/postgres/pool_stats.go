@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"github.com/go-pg/pg/v10"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolStater is implemented by pg.DB.
+type PoolStater interface {
+	PoolStats() *pg.PoolStats
+}
+
+// PoolStatsCollector exports connection pool stats of a database as Prometheus metrics.
+type PoolStatsCollector struct {
+	db PoolStater
+
+	hits, misses, timeouts, total, idle, stale *prometheus.Desc
+}
+
+// NewPoolStatsCollector returns PoolStatsCollector of db, its metrics are prefixed with namespace.
+func NewPoolStatsCollector(db PoolStater, namespace string) *PoolStatsCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &PoolStatsCollector{
+		db:       db,
+		hits:     desc("hits_total", "Number of times a free connection was found in the pool."),
+		misses:   desc("misses_total", "Number of times a free connection was not found in the pool."),
+		timeouts: desc("timeouts_total", "Number of times waiting for a connection timed out."),
+		total:    desc("connections", "Number of connections in the pool."),
+		idle:     desc("idle_connections", "Number of idle connections in the pool."),
+		stale:    desc("stale_connections_total", "Number of stale connections removed from the pool."),
+	}
+}
+
+func (c *PoolStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.total
+	ch <- c.idle
+	ch <- c.stale
+}
+
+func (c *PoolStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.stale, prometheus.CounterValue, float64(stats.StaleConns))
+}
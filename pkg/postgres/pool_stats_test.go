@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"github.com/go-pg/pg/v10"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type staticPoolStats pg.PoolStats
+
+func (s staticPoolStats) PoolStats() *pg.PoolStats {
+	stats := pg.PoolStats(s)
+	return &stats
+}
+
+func TestPoolStatsCollector(t *testing.T) {
+	c := NewPoolStatsCollector(staticPoolStats{Hits: 10, Misses: 2, TotalConns: 3, IdleConns: 1}, "payments")
+
+	expected := `
+# HELP payments_db_pool_connections Number of connections in the pool.
+# TYPE payments_db_pool_connections gauge
+payments_db_pool_connections 3
+# HELP payments_db_pool_hits_total Number of times a free connection was found in the pool.
+# TYPE payments_db_pool_hits_total counter
+payments_db_pool_hits_total 10
+# HELP payments_db_pool_idle_connections Number of idle connections in the pool.
+# TYPE payments_db_pool_idle_connections gauge
+payments_db_pool_idle_connections 1
+# HELP payments_db_pool_misses_total Number of times a free connection was not found in the pool.
+# TYPE payments_db_pool_misses_total counter
+payments_db_pool_misses_total 2
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"payments_db_pool_connections", "payments_db_pool_hits_total", "payments_db_pool_idle_connections", "payments_db_pool_misses_total",
+	)
+	assert.NoError(t, err)
+}
@@ -45,15 +45,22 @@ func NestedRunInTransaction(ctx context.Context, db Database, f func(tx Database
 		if err != nil {
 			return err
 		}
-		return f(db)
+		if err := f(db); err != nil {
+			// Leave the outer transaction usable, as RunInTransaction would do
+			if _, rbErr := db.ExecContext(ctx, `ROLLBACK TO SAVEPOINT tx_002`); rbErr != nil {
+				return rbErr
+			}
+			return err
+		}
+		_, err = db.ExecContext(ctx, `RELEASE SAVEPOINT tx_002`)
+		return err
 	case *pg.DB:
 		// No transaction yet
 		return (db.(*pg.DB)).RunInTransaction(ctx, func(tx *pg.Tx) error {
-			return f(db)
+			return f(tx)
 		})
 	default:
 		// Must never get here
 		panic("NestedRunInTransaction unexpected db type")
 	}
-	return nil
 }
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingHook is pg.QueryHook starting a span for every query, as a child of the span in the context of the query.
+// Add it with pg.DB.AddQueryHook, transactions of the database inherit it.
+type TracingHook struct {
+	tracer trace.Tracer
+}
+
+// NewTracingHook returns TracingHook using a tracer of tp.
+func NewTracingHook(tp trace.TracerProvider) TracingHook {
+	return TracingHook{tracer: tp.Tracer("github.com/lightsgoout/fintech-go/pkg/postgres")}
+}
+
+// BeforeQuery starts a span named after the query, see QueryName.
+// The statement is recorded without parameters, so no account data ends up in traces.
+func (h TracingHook) BeforeQuery(ctx context.Context, evt *pg.QueryEvent) (context.Context, error) {
+	query, err := evt.UnformattedQuery()
+	if err != nil {
+		// Not worth failing the query for
+		query = nil
+	}
+	ctx, _ = h.tracer.Start(ctx, QueryName(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", string(query)),
+		),
+	)
+	return ctx, nil
+}
+
+// AfterQuery ends the span started by BeforeQuery. pg.ErrNoRows isn't an error of the query.
+func (h TracingHook) AfterQuery(ctx context.Context, evt *pg.QueryEvent) error {
+	span := trace.SpanFromContext(ctx)
+	if evt.Err != nil && !errors.Is(evt.Err, pg.ErrNoRows) {
+		span.RecordError(evt.Err)
+		span.SetStatus(codes.Error, evt.Err.Error())
+	}
+	if evt.Result != nil {
+		span.SetAttributes(attribute.Int("db.rows_affected", evt.Result.RowsAffected()))
+	}
+	span.End()
+	return nil
+}
+
+// QueryName returns the label of a query if it starts with one, e.g. --payments_get,
+// or its first keyword otherwise, e.g. SAVEPOINT.
+func QueryName(query []byte) string {
+	query = bytes.TrimSpace(query)
+	if bytes.HasPrefix(query, []byte("--")) {
+		query = query[2:]
+	}
+	if i := bytes.IndexAny(query, " \t\r\n"); i >= 0 {
+		query = query[:i]
+	}
+	if len(query) == 0 {
+		return "query"
+	}
+	return string(query)
+}
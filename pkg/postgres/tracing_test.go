@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestTracingHook(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	hook := NewTracingHook(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	for _, evt := range []*pg.QueryEvent{
+		{Query: "--account_lock\n\t\tSELECT * FROM account WHERE id = ? FOR NO KEY UPDATE", Params: []interface{}{"bob"}},
+		{Query: "SAVEPOINT tx_002"},
+		{Query: "SELECT 1 FROM account WHERE id = ?", Params: []interface{}{"alice"}, Err: pg.ErrNoRows},
+		{Query: "SELECT 1/0"},
+	} {
+		ctx, err := hook.BeforeQuery(context.Background(), evt)
+		assert.NoError(t, err)
+		if evt.Query == "SELECT 1/0" {
+			evt.Err = errors.New("division by zero")
+		}
+		assert.NoError(t, hook.AfterQuery(ctx, evt))
+	}
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 4) {
+		return
+	}
+	assert.Equal(t, "account_lock", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("db.statement",
+		"--account_lock\n\t\tSELECT * FROM account WHERE id = ? FOR NO KEY UPDATE"), "parameters aren't recorded")
+	assert.Equal(t, "SAVEPOINT", spans[1].Name())
+	assert.Equal(t, codes.Unset, spans[2].Status().Code, "no rows isn't an error")
+	assert.Equal(t, codes.Error, spans[3].Status().Code)
+}
+
+func TestQueryName(t *testing.T) {
+	assert.Equal(t, "payments_get", QueryName([]byte("--payments_get\nSELECT 1")))
+	assert.Equal(t, "BEGIN", QueryName([]byte("BEGIN")))
+	assert.Equal(t, "SELECT", QueryName([]byte("\n\t\tSELECT id FROM account")))
+	assert.Equal(t, "query", QueryName(nil))
+}
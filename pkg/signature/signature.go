@@ -0,0 +1,71 @@
+// Package signature signs HTTP payloads with HMAC-SHA256, so receivers can make sure
+// a payload comes from the holder of a shared secret and wasn't replayed long after it was sent.
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header is the HTTP header carrying the signature.
+const Header = "X-Signature"
+
+var (
+	ErrMalformed = errors.New("malformed signature")
+	ErrMismatch  = errors.New("signature mismatch")
+	ErrExpired   = errors.New("signature expired")
+)
+
+// Sign returns a signature of body made at the given moment, in form of t=<unix time>,v1=<hex HMAC-SHA256>.
+// The timestamp is signed along with the body as "<unix time>.<body>".
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(mac(secret, timestamp, body)))
+}
+
+// Verify checks signature of body made by Sign, which must be made no more than tolerance before now.
+// Zero tolerance disables the check of the age.
+func Verify(secret string, signature string, body []byte, now time.Time, tolerance time.Duration) error {
+	var timestamp, v1 string
+	for _, part := range strings.Split(signature, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return ErrMalformed
+		}
+		switch kv[0] {
+		case "t":
+			timestamp = kv[1]
+		case "v1":
+			v1 = kv[1]
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrMalformed
+	}
+	sum, err := hex.DecodeString(v1)
+	if err != nil || len(sum) == 0 {
+		return ErrMalformed
+	}
+	if !hmac.Equal(sum, mac(secret, timestamp, body)) {
+		return ErrMismatch
+	}
+	if tolerance > 0 && now.Sub(time.Unix(unix, 0)) > tolerance {
+		return ErrExpired
+	}
+	return nil
+}
+
+func mac(secret string, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
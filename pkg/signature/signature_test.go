@@ -0,0 +1,42 @@
+package signature
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	at := time.Unix(1604312520, 0)
+	body := []byte(`{"id":1}`)
+	sig := Sign("secret", at, body)
+	if want := "t=1604312520,v1="; sig[:len(want)] != want {
+		t.Errorf("TestSignVerify got %s, want prefix %s", sig, want)
+	}
+
+	if err := Verify("secret", sig, body, at.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Errorf("expected valid signature, got err=%v", err)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		body      string
+		now       time.Time
+		want      error
+	}{
+		{"other secret", "other", sig, `{"id":1}`, at, ErrMismatch},
+		{"other body", "secret", sig, `{"id":2}`, at, ErrMismatch},
+		{"too old", "secret", sig, `{"id":1}`, at.Add(time.Hour), ErrExpired},
+		{"no timestamp", "secret", "v1=abcd", `{"id":1}`, at, ErrMalformed},
+		{"no mac", "secret", "t=1604312520", `{"id":1}`, at, ErrMalformed},
+		{"garbage", "secret", "garbage", `{"id":1}`, at, ErrMalformed},
+	}
+	for _, test := range tests {
+		err := Verify(test.secret, test.signature, []byte(test.body), test.now, 5*time.Minute)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: expected %v, got err=%v", test.name, test.want, err)
+		}
+	}
+}
@@ -25,6 +25,21 @@ func WrapInTransaction(tx *pg.Tx, f func(*testing.T)) func(t *testing.T) {
 	}
 }
 
+// Isolate is like WrapInTransaction, but for the rest of an already running test:
+// changes made by the test are rolled back when it completes.
+func Isolate(t *testing.T, tx *pg.Tx) {
+	_, err := tx.Exec(`SAVEPOINT tx_001`)
+	if err != nil {
+		panic(err)
+	}
+	t.Cleanup(func() {
+		_, err := tx.Exec(`ROLLBACK TO tx_001`)
+		if err != nil {
+			panic(err)
+		}
+	})
+}
+
 // TestEnv contains isolated environment for a test suite
 type TestEnv struct {
 	// Tx is a transaction wrapping entire test suite